@@ -0,0 +1,129 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// apsisHoverRadius is how close, in pixels, the cursor must be to an apsis
+// mark to show its distance.
+const apsisHoverRadius = 6
+
+// apsisEvent is a body passing the nearest or farthest point of its orbit.
+type apsisEvent struct {
+	id       int
+	pos      nbody.Vector2D // where, in world coordinates
+	distance float64        // from the primary
+	apo      bool           // apoapsis rather than periapsis
+}
+
+// apsides watches each body's distance from the heavier body it is bound
+// to, step by step, and reports where the distance stops falling or
+// rising. These are the extremes the body actually reaches, perturbations
+// and all, rather than those of its osculating orbit.
+type apsides struct {
+	byID      map[int]*apsisState
+	primaries []int
+	events    []apsisEvent
+}
+
+type apsisState struct {
+	primary  int            // ID of the primary, or nbody.NoBody
+	distance float64        // at the previous step
+	pos      nbody.Vector2D // at the previous step
+	closing  bool           // distance was falling
+	moving   bool           // distance has changed since the primary was found
+	seen     bool
+}
+
+// update takes the current state of bodies and returns the apsides passed
+// since the previous call, which are at the previous step's positions.
+func (a *apsides) update(bodies []nbody.Body) []apsisEvent {
+	if a.byID == nil {
+		a.byID = make(map[int]*apsisState)
+	}
+	a.events = a.events[:0]
+	a.primaries = findPrimaries(a.primaries, bodies)
+	for i, p := range a.primaries {
+		b := bodies[i]
+		st := a.byID[b.ID]
+		if st == nil {
+			st = &apsisState{primary: nbody.NoBody}
+			a.byID[b.ID] = st
+		}
+		st.seen = true
+		if p < 0 {
+			st.primary = nbody.NoBody
+			continue
+		}
+		d := math.Hypot(b.Position.X-bodies[p].Position.X, b.Position.Y-bodies[p].Position.Y)
+		switch {
+		case st.primary != bodies[p].ID:
+			st.primary, st.moving = bodies[p].ID, false
+		case d == st.distance:
+		case st.moving && st.closing != (d < st.distance):
+			a.events = append(a.events, apsisEvent{id: b.ID, pos: st.pos, distance: st.distance, apo: !st.closing})
+			fallthrough
+		default:
+			st.closing, st.moving = d < st.distance, true
+		}
+		st.distance, st.pos = d, b.Position
+	}
+	for id, st := range a.byID {
+		if !st.seen {
+			delete(a.byID, id)
+		}
+		st.seen = false
+	}
+	return a.events
+}
+
+// apsisMark is an apsis on a trail.
+type apsisMark struct {
+	apsisEvent
+	at int // trail.count when it was passed
+}
+
+// mark adds e to the trail of its body, in frame.
+func (t *trails) mark(e apsisEvent, frame *Frame) {
+	tr := t.byID[e.id]
+	if tr == nil || tr.count == 0 {
+		return
+	}
+	e.pos = frame.apply(e.pos)
+	tr.marks = append(tr.marks, apsisMark{e, tr.count - 1})
+}
+
+// drawMarks draws the apsis marks on every trail and returns the mark under
+// cursor, if any.
+func (t *trails) drawMarks(dst *ebiten.Image, toScreen func(nbody.Vector2D) nbody.Vector2D, cursor image.Point) (hover *apsisMark, at nbody.Vector2D) {
+	for _, tr := range t.byID {
+		for k := range tr.marks {
+			m := &tr.marks[k]
+			s := toScreen(m.pos)
+			c := theme.Periapsis
+			if m.apo {
+				c = theme.Apoapsis
+			}
+			vector.StrokeCircle(dst, float32(s.X), float32(s.Y), 3, 1, c, true)
+			if math.Hypot(s.X-float64(cursor.X), s.Y-float64(cursor.Y)) <= apsisHoverRadius*uiScale {
+				hover, at = m, s
+			}
+		}
+	}
+	return hover, at
+}
+
+// drawApsisLabel shows the distance of the apsis mark m drawn at s.
+func drawApsisLabel(dst *ebiten.Image, m *apsisMark, s nbody.Vector2D) {
+	label := "periapsis "
+	if m.apo {
+		label = "apoapsis "
+	}
+	drawText(dst, label+formatDistance(m.distance), int(s.X)+6, int(s.Y)-debugLineHeight)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// subsystemMaxBodies bounds the bodies searched for subsystems each frame,
+// since finding each body's primary compares it with every other.
+const subsystemMaxBodies = 500
+
+// subsystems finds the bodies that have satellites of their own while
+// themselves orbiting a heavier body, such as the Earth with the Moon, so
+// the barycenter of each group can be marked alongside the system's.
+type subsystems struct {
+	primaries []int   // index of the heavier body each body is bound to, or -1
+	members   [][]int // indices of each subsystem's bodies, primary last
+}
+
+// find groups bodies into subsystems.
+func (s *subsystems) find(bodies []nbody.Body) {
+	s.members = s.members[:0]
+	s.primaries = findPrimaries(s.primaries, bodies)
+	for p := range s.primaries {
+		if s.primaries[p] < 0 {
+			continue
+		}
+		var members []int
+		for i, q := range s.primaries {
+			if q == p {
+				members = append(members, i)
+			}
+		}
+		if members != nil {
+			s.members = append(s.members, append(members, p))
+		}
+	}
+}
+
+// findPrimaries sets dst to the index of the heavier body each of bodies is
+// bound to, or -1, and returns it. It finds none above subsystemMaxBodies.
+func findPrimaries(dst []int, bodies []nbody.Body) []int {
+	dst = dst[:0]
+	if len(bodies) > subsystemMaxBodies {
+		return dst
+	}
+	for i := range bodies {
+		p, ok := nbody.DominantBody(bodies, i)
+		if !ok || bodies[p].Mass <= bodies[i].Mass || !nbody.OsculatingElements(bodies[i], bodies[p]).Bound {
+			p = -1
+		}
+		dst = append(dst, p)
+	}
+	return dst
+}
+
+// drawBarycenters marks the barycenter of all bodies, and of each
+// subsystem, in vp. center is the camera center.
+func (g *Game) drawBarycenters(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	g.markBarycenter(dst, vp, center, nil, theme.Barycenter)
+	for _, members := range g.subsystems.members {
+		g.markBarycenter(dst, vp, center, members, theme.Subsystem)
+	}
+}
+
+// markBarycenter draws a crossed circle in vp at the barycenter of the
+// bodies at indices, or of all bodies if indices is nil.
+func (g *Game) markBarycenter(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D, indices []int, c color.Color) {
+	var sum nbody.Vector2D
+	var mass float64
+	add := func(i int) {
+		m := g.drawBodies[i].Mass
+		sum.X += g.framePositions[i].X * m
+		sum.Y += g.framePositions[i].Y * m
+		mass += m
+	}
+	if indices == nil {
+		for i := range g.drawBodies {
+			add(i)
+		}
+	}
+	for _, i := range indices {
+		add(i)
+	}
+	if mass == 0 {
+		return
+	}
+	p := vp.toScreen(g.view(nbody.Vector2D{X: sum.X / mass, Y: sum.Y / mass}), center)
+	x, y := float32(p.X), float32(p.Y)
+	vector.StrokeCircle(dst, x, y, 5, 1, c, true)
+	vector.StrokeLine(dst, x-8, y, x+8, y, 1, c, true)
+	vector.StrokeLine(dst, x, y-8, x, y+8, 1, c, true)
+}
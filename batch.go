@@ -0,0 +1,137 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// batch collects triangles drawn from one source image, or with one
+// shader, so that they go to the GPU in as few draw calls as the index
+// limit allows rather than one call per shape.
+type batch struct {
+	src       *ebiten.Image
+	shader    *ebiten.Shader // draws the triangles instead of src, if set
+	blend     ebiten.Blend   // zero for source-over
+	vertices  []ebiten.Vertex
+	indices   []uint16
+	antiAlias bool // smooth the triangles' edges, at the cost of extra passes
+}
+
+// reserve makes room for nv more vertices and ni more indices, flushing to
+// dst first if the batch is full, and returns the index of the first new
+// vertex.
+func (b *batch) reserve(dst *ebiten.Image, nv, ni int) uint16 {
+	if len(b.vertices)+nv > ebiten.MaxVertexCount || len(b.indices)+ni > ebiten.MaxIndicesCount {
+		b.flush(dst)
+	}
+	return uint16(len(b.vertices))
+}
+
+// flush draws the queued triangles to dst and empties the batch.
+func (b *batch) flush(dst *ebiten.Image) {
+	if len(b.indices) == 0 {
+		return
+	}
+	if b.shader != nil {
+		op := &ebiten.DrawTrianglesShaderOptions{Blend: b.blend, AntiAlias: b.antiAlias}
+		dst.DrawTrianglesShader(b.vertices, b.indices, b.shader, op)
+	} else {
+		op := &ebiten.DrawTrianglesOptions{
+			ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha,
+			Filter:         ebiten.FilterLinear,
+			AntiAlias:      b.antiAlias,
+			Blend:          b.blend,
+		}
+		dst.DrawTriangles(b.vertices, b.indices, b.src, op)
+	}
+	b.vertices = b.vertices[:0]
+	b.indices = b.indices[:0]
+}
+
+// vertexColor returns a vertex carrying c.
+func vertexColor(c color.Color) ebiten.Vertex {
+	r, g, b, a := c.RGBA()
+	return ebiten.Vertex{
+		ColorR: float32(r) / 0xffff,
+		ColorG: float32(g) / 0xffff,
+		ColorB: float32(b) / 0xffff,
+		ColorA: float32(a) / 0xffff,
+	}
+}
+
+// whiteImage returns a white source image for untextured triangles. It is
+// the middle of a white 3×3 image, so sampling never reaches an edge.
+func whiteImage() *ebiten.Image {
+	white := ebiten.NewImage(3, 3)
+	white.Fill(color.White)
+	return white.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+}
+
+// circleBatch draws filled circles as triangle fans coloured per vertex,
+// anti-aliased so their rims don't step.
+type circleBatch struct {
+	batch
+}
+
+// Segments of a circle's fan: about one per circleSegmentLength pixels of
+// circumference, within these bounds.
+const (
+	circleSegmentLength = 4
+	minCircleSegments   = 8
+	maxCircleSegments   = 96
+)
+
+// add queues a disc of radius pixels at (x, y).
+func (c *circleBatch) add(dst *ebiten.Image, x, y, radius float64, col color.Color) {
+	if c.src == nil {
+		c.src, c.antiAlias = whiteImage(), true
+	}
+	segments := int(2 * math.Pi * radius / circleSegmentLength)
+	segments = min(max(segments, minCircleSegments), maxCircleSegments)
+	base := c.reserve(dst, segments+1, 3*segments)
+
+	v := vertexColor(col)
+	v.SrcX, v.SrcY = 1.5, 1.5
+	v.DstX, v.DstY = float32(x), float32(y)
+	c.vertices = append(c.vertices, v)
+	for k := range segments {
+		a := 2 * math.Pi * float64(k) / float64(segments)
+		v.DstX = float32(x + radius*math.Cos(a))
+		v.DstY = float32(y + radius*math.Sin(a))
+		c.vertices = append(c.vertices, v)
+		next := uint16((k+1)%segments) + 1
+		c.indices = append(c.indices, base, base+uint16(k)+1, base+next)
+	}
+}
+
+// lineBatch draws one-pixel lines as thin quads coloured per vertex.
+type lineBatch struct {
+	batch
+}
+
+// add queues a line from a to b.
+func (l *lineBatch) add(dst *ebiten.Image, a, b nbody.Vector2D, col color.Color) {
+	if l.src == nil {
+		l.src = whiteImage()
+	}
+	dx, dy := b.X-a.X, b.Y-a.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	// Half a pixel to either side of the line.
+	nx, ny := -dy/length/2, dx/length/2
+	base := l.reserve(dst, 4, 6)
+	v := vertexColor(col)
+	v.SrcX, v.SrcY = 1.5, 1.5
+	for _, p := range [4]nbody.Vector2D{{X: a.X + nx, Y: a.Y + ny}, {X: a.X - nx, Y: a.Y - ny}, {X: b.X + nx, Y: b.Y + ny}, {X: b.X - nx, Y: b.Y - ny}} {
+		v.DstX, v.DstY = float32(p.X), float32(p.Y)
+		l.vertices = append(l.vertices, v)
+	}
+	l.indices = append(l.indices, base, base+1, base+2, base+1, base+3, base+2)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"n-body/nbody"
+)
+
+// Bookmark is a camera saved to come back to.
+type Bookmark struct {
+	Center nbody.Vector2D `json:"center"` // in the viewport's frame
+	Zoom   float64        `json:"zoom"`   // device-independent pixels per world unit
+	Follow int            `json:"follow"` // ID of the body followed, or nbody.NoBody
+}
+
+// bookmarks are cameras saved with Ctrl+1 to 9 and recalled with Shift and
+// the same digit, for jumping between views during a demo.
+type bookmarks struct {
+	marks   [9]*Bookmark
+	changed bool // bookmarks saved since loading
+}
+
+// loadBookmarks reads bookmarks written by save. A missing file has none.
+func loadBookmarks(path string) (bookmarks, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return bookmarks{}, nil
+	}
+	if err != nil {
+		return bookmarks{}, err
+	}
+	var b bookmarks
+	if err := json.Unmarshal(data, &b.marks); err != nil {
+		return bookmarks{}, fmt.Errorf("parsing bookmarks %s: %w", path, err)
+	}
+	for i, m := range b.marks {
+		if m != nil && !(m.Zoom > 0) {
+			return bookmarks{}, fmt.Errorf("bookmarks %s: bookmark %d has zoom %g", path, i+1, m.Zoom)
+		}
+	}
+	return b, nil
+}
+
+// save writes the bookmarks to path as JSON, if any were saved.
+func (b *bookmarks) save(path string) error {
+	if !b.changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(b.marks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// bookmarkCamera saves the active viewport's camera as the n-th bookmark,
+// counting from 0.
+func (g *Game) bookmarkCamera(n int) {
+	cam := g.activeViewport().Camera
+	g.bookmarks.marks[n] = &Bookmark{Center: cam.Center, Zoom: cam.Zoom / uiScale, Follow: cam.Follow}
+	g.bookmarks.changed = true
+	log.Printf("bookmarked the camera as %d", n+1)
+}
+
+// recallCamera moves the active viewport's camera to the n-th bookmark,
+// counting from 0, following the body it followed if that is still there.
+func (g *Game) recallCamera(n int) {
+	m := g.bookmarks.marks[n]
+	if m == nil {
+		log.Printf("no camera bookmark %d (Ctrl+%d saves one)", n+1, n+1)
+		return
+	}
+	vp := g.activeViewport()
+	vp.Camera = Camera{Center: m.Center, Zoom: m.Zoom * uiScale, Follow: nbody.NoBody}
+	if _, ok := g.sim.IndexOf(m.Follow); ok {
+		vp.Camera.Follow = m.Follow
+		vp.Camera.follow(g.bodies, &vp.Frame)
+	}
+	g.path.playing = false
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"slices"
+
+	"n-body/nbody"
+)
+
+// CameraKey is where the camera is at a moment of simulated time.
+type CameraKey struct {
+	Time   float64        `json:"time"`   // simulated seconds
+	Center nbody.Vector2D `json:"center"` // in the viewport's frame
+	Zoom   float64        `json:"zoom"`   // device-independent pixels per world unit
+}
+
+// cameraPath is a camera move for demo videos: keyframes recorded with S
+// while the simulation runs and played back, smoothly, with Shift+S. Played
+// back, the camera passes through every keyframe at its time, on a cubic
+// curve through the centers and the logarithms of the zooms, and holds
+// still before the first and after the last.
+type cameraPath struct {
+	keys    []CameraKey
+	playing bool
+	changed bool // keys added since loading
+}
+
+// loadCameraPath reads a path written by save. A missing file is an empty
+// path, to be recorded.
+func loadCameraPath(path string) (cameraPath, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cameraPath{}, nil
+	}
+	if err != nil {
+		return cameraPath{}, err
+	}
+	var p cameraPath
+	if err := json.Unmarshal(data, &p.keys); err != nil {
+		return cameraPath{}, fmt.Errorf("parsing camera path %s: %w", path, err)
+	}
+	for i, k := range p.keys {
+		if !(k.Zoom > 0) {
+			return cameraPath{}, fmt.Errorf("camera path %s: keyframe %d has zoom %g", path, i+1, k.Zoom)
+		}
+	}
+	slices.SortStableFunc(p.keys, func(a, b CameraKey) int { return cmp.Compare(a.Time, b.Time) })
+	return p, nil
+}
+
+// save writes the keyframes to path as JSON, if any were added.
+func (p *cameraPath) save(path string) error {
+	if !p.changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(p.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// add keyframes cam at simulated time t, replacing a keyframe at the same
+// time.
+func (p *cameraPath) add(t float64, cam Camera) {
+	k := CameraKey{Time: t, Center: cam.Center, Zoom: cam.Zoom / uiScale}
+	i, found := slices.BinarySearchFunc(p.keys, t, func(k CameraKey, t float64) int { return cmp.Compare(k.Time, t) })
+	if found {
+		p.keys[i] = k
+	} else {
+		p.keys = slices.Insert(p.keys, i, k)
+	}
+	p.changed = true
+	log.Printf("camera keyframe %d of %d at t = %s", i+1, len(p.keys), formatDuration(t))
+}
+
+// toggle starts or stops playback, releasing cam from any body it follows.
+func (p *cameraPath) toggle(cam *Camera) {
+	if len(p.keys) == 0 {
+		log.Print("no camera keyframes to play (S records one)")
+		return
+	}
+	p.playing = !p.playing
+	if p.playing {
+		cam.Follow = nbody.NoBody
+	}
+}
+
+// apply moves cam to where the path has it at simulated time t.
+func (p *cameraPath) apply(cam *Camera, t float64) {
+	keys := p.keys
+	switch {
+	case len(keys) == 0:
+		return
+	case t <= keys[0].Time:
+		cam.Center, cam.Zoom = keys[0].Center, keys[0].Zoom*uiScale
+		return
+	case t >= keys[len(keys)-1].Time:
+		cam.Center, cam.Zoom = keys[len(keys)-1].Center, keys[len(keys)-1].Zoom*uiScale
+		return
+	}
+	i, _ := slices.BinarySearchFunc(keys, t, func(k CameraKey, t float64) int { return cmp.Compare(k.Time, t) })
+	a, b := i-1, i // keys[a].Time < t <= keys[b].Time
+	h := keys[b].Time - keys[a].Time
+	s := (t - keys[a].Time) / h
+	// Cubic Hermite basis, with tangents from the neighbouring keyframes.
+	h00, h10 := 2*s*s*s-3*s*s+1, s*s*s-2*s*s+s
+	h01, h11 := -2*s*s*s+3*s*s, s*s*s-s*s
+	curve := func(v func(CameraKey) float64) float64 {
+		return h00*v(keys[a]) + h10*h*p.slope(a, v) + h01*v(keys[b]) + h11*h*p.slope(b, v)
+	}
+	cam.Center.X = curve(func(k CameraKey) float64 { return k.Center.X })
+	cam.Center.Y = curve(func(k CameraKey) float64 { return k.Center.Y })
+	cam.Zoom = math.Exp(curve(func(k CameraKey) float64 { return math.Log(k.Zoom) })) * uiScale
+}
+
+// slope is the rate of change of v at keyframe i, from its neighbours, or
+// from the one neighbour at either end.
+func (p *cameraPath) slope(i int, v func(CameraKey) float64) float64 {
+	lo, hi := max(i-1, 0), min(i+1, len(p.keys)-1)
+	dt := p.keys[hi].Time - p.keys[lo].Time
+	if dt == 0 {
+		return 0
+	}
+	return (v(p.keys[hi]) - v(p.keys[lo])) / dt
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/scenario"
+)
+
+// drawAnnotations prints the annotations active at simulated time t along
+// the bottom of the screen, one per line.
+func drawAnnotations(screen *ebiten.Image, annotations []scenario.Annotation, t float64) {
+	y := screen.Bounds().Dy() - 32
+	for i := len(annotations) - 1; i >= 0; i-- {
+		a := annotations[i]
+		if t < a.Start || t >= a.End {
+			continue
+		}
+		lines := strings.Split(a.Text, "\n")
+		slices.Reverse(lines)
+		for _, line := range lines {
+			x := (screen.Bounds().Dx() - len(line)*debugCharWidth) / 2
+			drawText(screen, line, max(x, 4), y)
+			y -= debugLineHeight
+		}
+	}
+}
+
+// caption is an annotation placed on the video timeline.
+type caption struct {
+	start, end time.Duration
+	text       string
+}
+
+// captions maps annotations from simulated time onto the timeline of a
+// video whose first frame shows time start and which lasts length,
+// dropping those that fall outside it.
+func captions(annotations []scenario.Annotation, start float64, length time.Duration) []caption {
+	var cs []caption
+	for _, a := range annotations {
+		c := caption{
+			start: max(seconds(a.Start-start), 0),
+			end:   min(seconds(a.End-start), length),
+			text:  a.Text,
+		}
+		if c.end > c.start {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+// writeCaptions writes cs as SubRip (vtt false) or WebVTT (vtt true).
+func writeCaptions(path string, cs []caption, vtt bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	sep := ","
+	if vtt {
+		sep = "."
+		fmt.Fprint(w, "WEBVTT\n\n")
+	}
+	for i, c := range cs {
+		// A blank line would end the cue early.
+		text := strings.Join(strings.FieldsFunc(c.text, func(r rune) bool { return r == '\n' }), "\n")
+		fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1, captionTime(c.start, sep), captionTime(c.end, sep), text)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// captionTime formats d as HH:MM:SS followed by sep and milliseconds.
+func captionTime(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
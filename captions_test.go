@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"n-body/scenario"
+)
+
+func TestCaptions(t *testing.T) {
+	annotations := []scenario.Annotation{
+		{Start: 0, End: 5, Text: "before the video"},
+		{Start: 8, End: 12, Text: "across its start"},
+		{Start: 12.5, End: 14.25, Text: "inside"},
+		{Start: 18, End: 30, Text: "across its end"},
+		{Start: 25, End: 26, Text: "after the video"},
+		{Start: 14, End: 14, Text: "empty"},
+	}
+	got := captions(annotations, 10, 10*time.Second)
+	want := []caption{
+		{0, 2 * time.Second, "across its start"},
+		{2500 * time.Millisecond, 4250 * time.Millisecond, "inside"},
+		{8 * time.Second, 10 * time.Second, "across its end"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("captions = %v, want %v", got, want)
+	}
+}
+
+func TestCaptionTime(t *testing.T) {
+	for _, tt := range []struct {
+		d        time.Duration
+		sep, out string
+	}{
+		{0, ",", "00:00:00,000"},
+		{1500 * time.Millisecond, ",", "00:00:01,500"},
+		{time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, ".", "01:02:03.004"},
+		{100*time.Hour + 999*time.Microsecond, ".", "100:00:00.000"},
+	} {
+		if got := captionTime(tt.d, tt.sep); got != tt.out {
+			t.Errorf("captionTime(%v, %q) = %q, want %q", tt.d, tt.sep, got, tt.out)
+		}
+	}
+}
+
+func TestWriteCaptions(t *testing.T) {
+	cs := []caption{
+		{0, 1500 * time.Millisecond, "one"},
+		{2 * time.Second, 3 * time.Second, "two\n\nlines"},
+	}
+	for _, tt := range []struct {
+		vtt  bool
+		want string
+	}{
+		{false, "1\n00:00:00,000 --> 00:00:01,500\none\n\n2\n00:00:02,000 --> 00:00:03,000\ntwo\nlines\n\n"},
+		{true, "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.500\none\n\n2\n00:00:02.000 --> 00:00:03.000\ntwo\nlines\n\n"},
+	} {
+		path := filepath.Join(t.TempDir(), "captions")
+		if err := writeCaptions(path, cs, tt.vtt); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("writeCaptions(vtt %t) wrote %q, want %q", tt.vtt, got, tt.want)
+		}
+	}
+	if err := writeCaptions(filepath.Join(t.TempDir(), "missing", "captions.srt"), cs, false); err == nil {
+		t.Error("writeCaptions into a missing directory succeeded")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"n-body/nbody"
+)
+
+// maxCatchUp caps the wall-clock time, in seconds, one tick may simulate.
+// After a long stall, such as the window being dragged, the simulation
+// falls behind by the excess instead of freezing to take hundreds of steps.
+const maxCatchUp = 0.25
+
+// maxStepTime caps the wall-clock time, in seconds, the physics steps of
+// one tick may take, judged by how long steps have been taking. At a high
+// time warp or with many bodies the simulation then runs slower than asked
+// instead of freezing the window.
+const maxStepTime = 0.05
+
+// maxTickSteps caps the physics steps of one tick however cheap they are,
+// and bounds the first ticks, before any step has been timed.
+const maxTickSteps = 4096
+
+// Bounds of the time warp.
+const (
+	minWarp = 1e-3
+	maxWarp = 1e4
+)
+
+// clock turns elapsed wall-clock time into physics steps of dt seconds,
+// carrying the remainder over to the next tick. Time warp speeds the
+// simulation up or slows it down by taking more or fewer steps per tick,
+// never longer ones, so it costs speed rather than accuracy.
+type clock struct {
+	dt          float64
+	warp        float64 // simulated seconds per wall-clock second, as a multiple of normal; 0 means 1
+	last        time.Time
+	accumulator float64 // warped wall-clock seconds not yet simulated
+	stepCost    float64 // wall-clock seconds a physics step takes, smoothed; 0 until measured
+}
+
+// tick returns how many physics steps are due at now. Steps beyond what
+// fits in maxStepTime are dropped rather than carried over, so the
+// simulation falls behind instead of owing ever more steps.
+func (c *clock) tick(now time.Time) int {
+	if !c.last.IsZero() {
+		c.accumulator += math.Min(now.Sub(c.last).Seconds(), maxCatchUp) * c.speed()
+	}
+	c.last = now
+	steps := int(c.accumulator / c.dt)
+	c.accumulator -= float64(steps) * c.dt
+	return min(steps, c.limit())
+}
+
+// limit returns the most physics steps one tick may take.
+func (c *clock) limit() int {
+	if c.stepCost == 0 {
+		return maxTickSteps
+	}
+	return min(max(int(maxStepTime/c.stepCost), 1), maxTickSteps)
+}
+
+// measure records that steps physics steps took elapsed.
+func (c *clock) measure(steps int, elapsed time.Duration) {
+	if steps == 0 {
+		return
+	}
+	cost := elapsed.Seconds() / float64(steps)
+	if c.stepCost == 0 {
+		c.stepCost = cost
+		return
+	}
+	c.stepCost += (cost - c.stepCost) / 8
+}
+
+// speed returns the time warp.
+func (c *clock) speed() float64 {
+	if c.warp == 0 {
+		return 1
+	}
+	return c.warp
+}
+
+// scale multiplies the time warp by f, within its bounds.
+func (c *clock) scale(f float64) {
+	c.warp = math.Min(math.Max(c.speed()*f, minWarp), maxWarp)
+}
+
+// hold lets the wall clock run on to now without the simulation falling
+// behind it, as while paused.
+func (c *clock) hold(now time.Time) {
+	c.last = now
+}
+
+// alpha returns how far the present lies between the last two physics
+// steps, from 0 to 1.
+func (c *clock) alpha() float64 {
+	return c.accumulator / c.dt
+}
+
+// interpolateBodies appends to dst the bodies of cur with their positions
+// moved back towards prev, alpha of the way from prev to cur. Bodies that
+// are new, or that wrapped around the width × height world, are left where
+// they are.
+func interpolateBodies(dst, prev, cur []nbody.Body, alpha, width, height float64) []nbody.Body {
+	dst = append(dst, cur...)
+	if len(prev) != len(cur) {
+		return dst
+	}
+	for i := range dst {
+		p, c := prev[i].Position, cur[i].Position
+		if prev[i].ID != cur[i].ID || math.Abs(c.X-p.X) > width/2 || math.Abs(c.Y-p.Y) > height/2 {
+			continue
+		}
+		dst[i].Position = nbody.Vector2D{X: p.X + (c.X-p.X)*alpha, Y: p.Y + (c.Y-p.Y)*alpha}
+	}
+	return dst
+}
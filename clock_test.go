@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockLimitsSteps(t *testing.T) {
+	start := time.Unix(0, 0)
+	tests := []struct {
+		name     string
+		warp     float64
+		stepCost float64
+		want     int
+	}{
+		{"normal", 1, 1e-4, 6},
+		{"unmeasured", maxWarp, 0, maxTickSteps},
+		{"cheap", maxWarp, 1e-9, maxTickSteps},
+		{"costly", maxWarp, 1e-3, int(maxStepTime / 1e-3)},
+		{"slower than a tick", maxWarp, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := clock{dt: 1.0 / 60, warp: tt.warp, stepCost: tt.stepCost}
+			c.tick(start)
+			if got := c.tick(start.Add(100 * time.Millisecond)); got != tt.want {
+				t.Errorf("tick = %d steps, want %d", got, tt.want)
+			}
+			if c.alpha() < 0 || c.alpha() >= 1 {
+				t.Errorf("alpha = %g after the tick, want the surplus dropped", c.alpha())
+			}
+		})
+	}
+}
+
+func TestClockMeasure(t *testing.T) {
+	var c clock
+	c.measure(0, time.Second)
+	if c.stepCost != 0 {
+		t.Errorf("step cost = %g after no steps, want unmeasured", c.stepCost)
+	}
+	c.measure(10, 10*time.Millisecond)
+	if c.stepCost != 1e-3 {
+		t.Errorf("step cost = %g, want 1e-3 from the first measurement", c.stepCost)
+	}
+	for range 100 {
+		c.measure(10, 20*time.Millisecond)
+	}
+	if c.stepCost < 1.99e-3 || c.stepCost > 2e-3 {
+		t.Errorf("step cost = %g, want it to have settled at 2e-3", c.stepCost)
+	}
+}
@@ -0,0 +1,186 @@
+// Command nbody-server runs the simulation headlessly and serves it over
+// the HTTP API of package server, with metrics at /metrics. It links no
+// graphics code, so it builds with CGO_ENABLED=0 and runs in a bare
+// container.
+//
+// Every flag can also be set through an environment variable named after
+// it in upper case with an NBODY_ prefix and dashes turned into
+// underscores, e.g. NBODY_CONTROL_TOKEN for -control-token. Flags given on
+// the command line take precedence.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"n-body/nbody"
+	"n-body/scenario"
+	"n-body/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to serve the HTTP API on")
+	scenarioNames := flag.String("scenario", "solar-system", "comma-separated built-in scenario names or paths to scenario JSON files; all but the first are used by -rotate")
+	rotate := flag.Duration("rotate", 0, "load the next -scenario in turn this often (0 never rotates)")
+	solver := flag.String("solver", "direct", "gravity solver: direct, fmm or pm")
+	fmmOrder := flag.Int("fmm-order", 2, "FMM expansion order (0-2)")
+	fmmTheta := flag.Float64("fmm-theta", 0.5, "FMM opening angle; smaller is more accurate")
+	pmGrid := flag.Int("pm-grid", 128, "particle-mesh grid nodes per axis (rounded up to a power of two)")
+	substeps := flag.Int("substeps", 1, "physics steps per tick; more are more accurate without changing the speed of time")
+	autoSubsteps := flag.Int("auto-substeps", 16, "raise -substeps up to this many if the scenario's fastest orbit needs them (0 only warns)")
+	tps := flag.Float64("tps", 60, "simulation steps per second")
+	dt := flag.Float64("dt", 0, "simulated seconds per step (0 uses the scenario's, by default 1/60)")
+	readToken := flag.String("read-token", "", "token granting read-only access")
+	controlToken := flag.String("control-token", "", "token granting control of the simulation")
+	public := flag.Bool("public", false, "let clients without a token read the state when tokens are set")
+	pprofAddr := flag.String("pprof", "", "serve net/http/pprof on this address (e.g. localhost:6060) and label profiles by simulation phase")
+	maxRate := flag.Float64("max-rate", 60, "highest update rate in Hz a client may request")
+	flag.Parse()
+	if err := flagsFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+
+	var scenarios []*scenario.Scenario
+	for _, name := range strings.Split(*scenarioNames, ",") {
+		sc, ok := scenario.Builtin(name)
+		if !ok {
+			var err error
+			if sc, err = scenario.Load(name); err != nil {
+				log.Fatal(err)
+			}
+		}
+		scenarios = append(scenarios, sc)
+	}
+	// configure applies the step flags to sim, built from a scenario.
+	configure := func(sim *nbody.Simulation) {
+		sim.Substeps = *substeps
+		if *dt > 0 {
+			sim.Dt = *dt
+		}
+		sim.CheckStep(*autoSubsteps, log.Printf)
+	}
+	sc := scenarios[0]
+	sim := sc.Build()
+	configure(sim)
+	switch *solver {
+	case "direct":
+	case "fmm":
+		sim.Solver = nbody.NewFMM(*fmmOrder, *fmmTheta)
+	case "pm":
+		sim.Solver = nbody.NewPM(*pmGrid)
+	default:
+		log.Fatalf("unknown solver %q", *solver)
+	}
+
+	srv := server.New()
+	srv.SetScenario(sc)
+	srv.Configure = configure
+	srv.MaxRate = *maxRate
+	if *readToken != "" || *controlToken != "" {
+		srv.Anonymous = server.RoleNone
+		if *public {
+			srv.Anonymous = server.RoleRead
+		}
+	}
+	if *readToken != "" {
+		srv.AddToken(*readToken, server.RoleRead)
+	}
+	if *controlToken != "" {
+		srv.AddToken(*controlToken, server.RoleControl)
+	} else {
+		log.Print("the HTTP API is read-only without -control-token")
+	}
+
+	if *pprofAddr != "" {
+		nbody.SetProfileLabels(true)
+		go func() {
+			log.Fatal(http.ListenAndServe(*pprofAddr, nil))
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	httpServer := &http.Server{Addr: *addr, Handler: srv.Handler()}
+	go func() {
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	log.Printf("serving %s on %s", sc.Name, *addr)
+
+	if *rotate > 0 && len(scenarios) > 1 {
+		go rotateScenarios(ctx, srv, scenarios, *rotate)
+	}
+	run(ctx, sim, srv, *tps)
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdown); err != nil {
+		log.Print(err)
+	}
+}
+
+// run steps the simulation tps times a second until ctx is done,
+// publishing every step.
+func run(ctx context.Context, sim *nbody.Simulation, srv *server.Server, tps float64) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / tps))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		start := time.Now()
+		sim = srv.Apply(sim)
+		sim.Update()
+		srv.ObserveStep(time.Since(start))
+		srv.Publish(sim.Time, sim.AppendBodies)
+	}
+}
+
+// rotateScenarios loads the next of scenarios every interval, wrapping
+// around, until ctx is done.
+func rotateScenarios(ctx context.Context, srv *server.Server, scenarios []*scenario.Scenario, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 1; ; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		sc := scenarios[i%len(scenarios)]
+		log.Printf("rotating to %s", sc.Name)
+		srv.LoadScenario(sc)
+	}
+}
+
+// flagsFromEnv sets every flag not given on the command line from its
+// NBODY_ environment variable, if present.
+func flagsFromEnv() error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		name := "NBODY_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		v, ok := os.LookupEnv(name)
+		if !ok || set[f.Name] || err != nil {
+			return
+		}
+		if e := f.Value.Set(v); e != nil {
+			err = fmt.Errorf("%s: %w", name, e)
+		}
+	})
+	return err
+}
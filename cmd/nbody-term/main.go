@@ -0,0 +1,123 @@
+// Command nbody-term runs the simulation and draws it in the terminal with
+// render.Terminal, for demos over SSH or on machines without a display or
+// GPU. Like nbody-server it links no graphics code.
+//
+// The view fits the world to the terminal, resizing with it; -zoom
+// magnifies it about the center and -follow keeps a body there. Ctrl+C
+// quits.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"n-body/nbody"
+	"n-body/render"
+	"n-body/scenario"
+)
+
+// maxCatchUp caps the wall-clock time, in seconds, one frame may simulate,
+// as in the windowed simulation.
+const maxCatchUp = 0.25
+
+func main() {
+	scenarioName := flag.String("scenario", "solar-system", "built-in scenario name or path to a scenario JSON file")
+	solver := flag.String("solver", "direct", "gravity solver: direct, fmm or pm")
+	fmmOrder := flag.Int("fmm-order", 2, "FMM expansion order (0-2)")
+	fmmTheta := flag.Float64("fmm-theta", 0.5, "FMM opening angle; smaller is more accurate")
+	pmGrid := flag.Int("pm-grid", 128, "particle-mesh grid nodes per axis (rounded up to a power of two)")
+	substeps := flag.Int("substeps", 1, "physics steps per tick; more are more accurate without changing the speed of time")
+	autoSubsteps := flag.Int("auto-substeps", 16, "raise -substeps up to this many if the scenario's fastest orbit needs them (0 only warns)")
+	dt := flag.Float64("dt", 0, "simulated seconds per step (0 uses the scenario's, by default 1/60)")
+	fps := flag.Float64("fps", 20, "frames drawn per second; physics keeps pace with the wall clock regardless")
+	chars := flag.String("charset", "braille", "characters to draw with: braille (2×4 dots per cell) or blocks (2 per cell)")
+	colored := flag.Bool("color", true, "draw bodies in their colors with 24-bit color escapes")
+	cols := flag.Int("cols", 80, "terminal columns, if the terminal's size can't be read")
+	rows := flag.Int("rows", 24, "terminal rows, if the terminal's size can't be read")
+	zoom := flag.Float64("zoom", 1, "magnification; 1 fits the world to the terminal")
+	follow := flag.String("follow", "", "name of a body to keep at the center")
+	flag.Parse()
+
+	sc, ok := scenario.Builtin(*scenarioName)
+	if !ok {
+		var err error
+		if sc, err = scenario.Load(*scenarioName); err != nil {
+			log.Fatal(err)
+		}
+	}
+	sim := sc.Build()
+	sim.Substeps = *substeps
+	if *dt > 0 {
+		sim.Dt = *dt
+	}
+	sim.CheckStep(*autoSubsteps, log.Printf)
+	switch *solver {
+	case "direct":
+	case "fmm":
+		sim.Solver = nbody.NewFMM(*fmmOrder, *fmmTheta)
+	case "pm":
+		sim.Solver = nbody.NewPM(*pmGrid)
+	default:
+		log.Fatalf("unknown solver %q", *solver)
+	}
+	term := render.NewTerminal(os.Stdout)
+	var err error
+	if term.Charset, err = render.ParseCharset(*chars); err != nil {
+		log.Fatal(err)
+	}
+	term.Color, term.Zoom = *colored, *zoom
+	term.Cols, term.Rows = *cols, *rows
+	if *follow != "" {
+		for _, b := range sim.AppendBodies(nil) {
+			if b.Name == *follow {
+				term.Follow = b.ID
+			}
+		}
+		if term.Follow == nbody.NoBody {
+			log.Fatalf("no body named %q in %s", *follow, sc.Name)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = run(ctx, sim, sc.Name, term, *fps)
+	if cerr := term.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run steps sim in time with the wall clock and renders it with r fps
+// times a second until ctx is done.
+func run(ctx context.Context, sim *nbody.Simulation, title string, r render.Renderer, fps float64) error {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / fps))
+	defer ticker.Stop()
+	last := time.Now()
+	var behind float64 // wall-clock seconds not yet simulated
+	frame := render.Frame{Title: title}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			behind += math.Min(now.Sub(last).Seconds(), maxCatchUp)
+			last = now
+		}
+		for dt := sim.StepSize(); behind >= dt; behind -= dt {
+			sim.Update()
+		}
+		frame.Time, frame.Width, frame.Height, frame.Center = sim.Time, sim.Width, sim.Height, sim.Center()
+		frame.Bodies = sim.AppendBodies(frame.Bodies[:0])
+		if err := r.Render(&frame); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// colorMode selects what a body's color shows.
+type colorMode int
+
+const (
+	colorByBody         colorMode = iota // each body's own color
+	colorBySpeed                         // speed, linearly
+	colorByMass                          // mass, logarithmically
+	colorByAcceleration                  // acceleration, logarithmically
+	colorModes
+)
+
+var colorModeNames = [colorModes]string{"body", "speed", "mass", "acceleration"}
+
+func (m colorMode) String() string { return colorModeNames[m] }
+
+func parseColorMode(s string) (colorMode, error) {
+	for m, name := range colorModeNames {
+		if s == name {
+			return colorMode(m), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown color mode %q", s)
+}
+
+// colormap is a sequence of evenly spaced RGB stops, low to high.
+type colormap [][3]float64
+
+var colormaps = map[string]colormap{
+	"inferno": {{0, 0, 4}, {87, 16, 110}, {188, 55, 84}, {249, 142, 9}, {252, 255, 164}},
+	"viridis": {{68, 1, 84}, {59, 82, 139}, {33, 145, 140}, {94, 201, 98}, {253, 231, 37}},
+	"plasma":  {{13, 8, 135}, {126, 3, 168}, {204, 71, 120}, {248, 149, 64}, {240, 249, 33}},
+	"cool":    {{0, 255, 255}, {255, 0, 255}},
+	"gray":    {{40, 40, 40}, {255, 255, 255}},
+}
+
+func parseColormap(s string) (colormap, error) {
+	if m, ok := colormaps[s]; ok {
+		return m, nil
+	}
+	names := make([]string, 0, len(colormaps))
+	for name := range colormaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown colormap %q (have %s)", s, strings.Join(names, ", "))
+}
+
+// at interpolates the stops at t in [0, 1].
+func (m colormap) at(t float64) [3]float64 {
+	t = math.Max(0, math.Min(t, 1)) * float64(len(m)-1)
+	i := min(int(t), len(m)-2)
+	f := t - float64(i)
+	var c [3]float64
+	for k := range c {
+		c[k] = m[i][k] + f*(m[i+1][k]-m[i][k])
+	}
+	return c
+}
+
+func (m colormap) rgba(t float64) color.RGBA {
+	c := m.at(t)
+	return color.RGBA{byte(c[0]), byte(c[1]), byte(c[2]), 255}
+}
+
+// legendWidth is the length in pixels of the colormap's legend bar.
+const legendWidth = 120
+
+// colorizer recolors bodies by a property, scaled over the range the
+// bodies on screen span, so generated particle sets show their structure
+// rather than the handful of colors they were created with.
+type colorizer struct {
+	mode   colorMode
+	cmap   colormap
+	values []float64
+	lo, hi float64 // range of the finite values, in the scale shown
+}
+
+// apply sets the color of each of bodies, interpolated from st's prev and
+// cur, by the property selected. Textures would hide the colors, so it
+// drops them.
+func (c *colorizer) apply(bodies []nbody.Body, st *renderState) {
+	if c.mode == colorByBody {
+		return
+	}
+	c.values = slices.Grow(c.values[:0], len(bodies))[:len(bodies)]
+	c.lo, c.hi = math.Inf(1), math.Inf(-1)
+	for i, b := range bodies {
+		var v float64
+		switch c.mode {
+		case colorBySpeed:
+			v = math.Hypot(b.Velocity.X, b.Velocity.Y)
+		case colorByMass:
+			v = math.Log10(b.Mass)
+		case colorByAcceleration:
+			v = math.NaN()
+			if len(st.accel) == len(bodies) {
+				v = math.Log10(math.Hypot(st.accel[i].X, st.accel[i].Y))
+			}
+		}
+		c.values[i] = v
+		if !math.IsInf(v, 0) && !math.IsNaN(v) {
+			c.lo, c.hi = math.Min(c.lo, v), math.Max(c.hi, v)
+		}
+	}
+	for i, v := range c.values {
+		t := 0.5
+		switch {
+		case math.IsNaN(v) || math.IsInf(v, -1):
+			t = 0
+		case math.IsInf(v, 1):
+			t = 1
+		case c.hi > c.lo:
+			t = (v - c.lo) / (c.hi - c.lo)
+		}
+		bodies[i].Color = c.cmap.rgba(t)
+		bodies[i].Texture = ""
+	}
+}
+
+// drawLegend shows the property and the range of the colormap in the top
+// left corner of the screen, below the top pixels taken by the HUD.
+func (c *colorizer) drawLegend(screen *ebiten.Image, top int) {
+	if c.mode == colorByBody || c.lo > c.hi {
+		return
+	}
+	lo, hi := c.lo, c.hi
+	if c.mode != colorBySpeed {
+		lo, hi = math.Pow(10, lo), math.Pow(10, hi)
+	}
+	x, y := 4, debugLineHeight+4+top
+	drawText(screen, fmt.Sprintf("%s: %.3g - %.3g", c.mode, lo, hi), x, y)
+	y += debugLineHeight + 2
+	w := int(legendWidth * uiScale)
+	for k := range w {
+		vector.DrawFilledRect(screen, float32(x+k), float32(y), 1, float32(8*uiScale), c.cmap.rgba(float64(k)/float64(w-1)), false)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+const (
+	// cometTailLength is the length in pixels of the tail of a comet 1 AU
+	// from the nearest luminous body. Tails grow as comets close in, like
+	// real ones, up to cometTailMax.
+	cometTailLength = 40
+	cometTailMax    = 240
+	// cometTailSpread is how much wider than the head a tail ends.
+	cometTailSpread = 3
+	// cometTailOpacity is the opacity of a tail at the head.
+	cometTailOpacity = 0.6
+)
+
+// tailBatch draws comet tails as quads fading from the comet's color at the
+// head to nothing at the end.
+type tailBatch struct {
+	batch
+}
+
+// add queues a tail from head, length pixels long in the direction (ux, uy),
+// a unit vector, and width pixels across at the head.
+func (t *tailBatch) add(dst *ebiten.Image, head nbody.Vector2D, ux, uy, width, length float64, c color.Color) {
+	if t.src == nil {
+		t.src, t.antiAlias = whiteImage(), true
+	}
+	base := t.reserve(dst, 4, 6)
+	end := nbody.Vector2D{X: head.X + ux*length, Y: head.Y + uy*length}
+	nx, ny := -uy*width/2, ux*width/2
+	v := vertexColor(c)
+	v.SrcX, v.SrcY = 1.5, 1.5
+	v.ColorR *= cometTailOpacity
+	v.ColorG *= cometTailOpacity
+	v.ColorB *= cometTailOpacity
+	v.ColorA *= cometTailOpacity
+	for _, p := range [2]nbody.Vector2D{{X: head.X + nx, Y: head.Y + ny}, {X: head.X - nx, Y: head.Y - ny}} {
+		v.DstX, v.DstY = float32(p.X), float32(p.Y)
+		t.vertices = append(t.vertices, v)
+	}
+	v.ColorR, v.ColorG, v.ColorB, v.ColorA = 0, 0, 0, 0
+	for _, s := range [2]float64{cometTailSpread, -cometTailSpread} {
+		v.DstX, v.DstY = float32(end.X+nx*s), float32(end.Y+ny*s)
+		t.vertices = append(t.vertices, v)
+	}
+	t.indices = append(t.indices, base, base+1, base+2, base+1, base+3, base+2)
+}
+
+// drawCometTails draws the tail of each comet in vp, pointing away from the
+// nearest luminous body. center is the camera center.
+func (g *Game) drawCometTails(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	for i, body := range g.drawBodies {
+		if !body.Comet {
+			continue
+		}
+		ux, uy, d, ok := g.awayFromLight(i)
+		if !ok {
+			continue
+		}
+		length := math.Min(cometTailLength*au/metersPerUnit/d, cometTailMax) * uiScale
+		width := 2 * math.Max(g.sizes.radius(body, vp.Camera.Zoom), minPointRadius*uiScale)
+		g.tails.add(dst, vp.toScreen(g.view(g.framePositions[i]), center), ux, uy, width, length, body.Color)
+	}
+	g.tails.flush(dst)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Bounds of the trail length slider, in positions kept per body.
+const (
+	minTrailLength = 2
+	maxTrailLength = 5000
+)
+
+// controls is a panel of buttons and sliders in the bottom left corner for
+// what is otherwise done with keys: pausing, time warp, trails, overlays
+// and the gravity solver. It folds away to its title.
+type controls struct {
+	open     bool
+	bounds   image.Rectangle // of the panel as last laid out
+	dragging string          // ID of the slider being dragged, or ""
+}
+
+// controlPanel describes the panel to w, changing what its widgets were
+// used to change.
+func (g *Game) controlPanel(w *widgets) {
+	c := &g.controls
+	title := "+ controls"
+	if c.open {
+		title = "- controls"
+	}
+	if w.button(title, false) {
+		c.open = !c.open
+	}
+	if !c.open {
+		return
+	}
+
+	w.newline()
+	pause := "pause"
+	if g.paused {
+		pause = "resume"
+	}
+	if w.button(pause, g.paused) {
+		g.togglePause()
+	}
+	if w.button("step", false) && g.paused {
+		g.stepPaused()
+	}
+	if w.button("reverse", g.sim.Reverse) {
+		g.sim.Reverse = !g.sim.Reverse
+	}
+
+	w.newline()
+	w.label("speed ")
+	if v, ok := w.slider("speed", slider{min: minWarp, max: maxWarp, log: true}, g.clock.speed()); ok {
+		g.clock.warp = v
+	}
+	w.label("x" + formatWarp(g.clock.speed()))
+
+	w.newline()
+	w.label("trails")
+	if v, ok := w.slider("trail", slider{min: minTrailLength, max: maxTrailLength, log: true}, float64(g.trailLength)); ok && int(v) != g.trailLength {
+		g.trailLength = int(v)
+		g.resetTrails()
+	}
+	w.label(fmt.Sprint(g.trailLength))
+
+	toggles := []struct {
+		label string
+		on    bool
+		flip  func()
+	}{
+		{"trails", g.trailsOn, g.toggleTrails},
+		{"labels", g.labelsOn, func() { g.labelsOn = !g.labelsOn }},
+		{"grid", g.gridOn, func() { g.gridOn = !g.gridOn }},
+		{"heat", g.heatOn, func() { g.heatOn = !g.heatOn }},
+		{"field", g.fieldOn, func() { g.fieldOn = !g.fieldOn }},
+		{"exposure", g.exposeOn, g.toggleExposure},
+		{"barycenters", g.barycentersOn, func() { g.barycentersOn = !g.barycentersOn }},
+		{"Hill", g.hillOn, func() { g.hillOn = !g.hillOn }},
+		{"Roche", g.rocheOn, func() { g.rocheOn = !g.rocheOn }},
+		{"Lagrange", g.lagrangeOn, func() { g.lagrangeOn = !g.lagrangeOn }},
+		{"Kepler", g.keplerOn, func() { g.keplerOn = !g.keplerOn }},
+		{"eclipses", g.eclipses.on, func() { g.eclipses.on = !g.eclipses.on }},
+		{"inset", g.insetOn, func() { g.insetOn = !g.insetOn }},
+		{"HUD", g.hud.on, func() { g.hud.on = !g.hud.on }},
+	}
+	for i, t := range toggles {
+		if i%5 == 0 {
+			w.newline()
+		}
+		if w.button(t.label, t.on) {
+			t.flip()
+		}
+	}
+
+	w.newline()
+	w.label("solver")
+	for _, name := range solverNames {
+		if w.button(name, g.solver == name) && g.solver != name {
+			g.setSolver(name)
+		}
+	}
+}
+
+// controlsOrigin is where the panel starts: high enough up for its bottom
+// to clear the timeline.
+func (g *Game) controlsOrigin() image.Point {
+	bottom := g.height - int(24*uiScale) - debugLineHeight - 8
+	return image.Pt(6, bottom-g.controls.bounds.Dy())
+}
+
+// updateControls handles the mouse for the panel and reports whether it
+// took it.
+func (g *Game) updateControls() bool {
+	w := newWidgets(nil, g.controlsOrigin(), &g.controls.dragging)
+	g.controlPanel(w)
+	g.controls.bounds = w.bounds
+	return w.took || w.pressed && w.cursor.In(w.bounds.Inset(-2))
+}
+
+// drawControls draws the panel.
+func (g *Game) drawControls(screen *ebiten.Image) {
+	r := g.controls.bounds.Inset(-2)
+	vector.DrawFilledRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), theme.Panel, false)
+	g.controlPanel(newWidgets(screen, g.controlsOrigin(), &g.controls.dragging))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"n-body/nbody"
+)
+
+// Perturbation is the acceleration one body contributes to another.
+type Perturbation struct {
+	Body         int            `json:"body"`
+	Acceleration nbody.Vector2D `json:"acceleration"`
+	Magnitude    float64        `json:"magnitude"`
+	Share        float64        `json:"share"` // fraction of the summed magnitudes
+}
+
+// DataSheet is a snapshot of everything known about one body.
+type DataSheet struct {
+	Time          float64                `json:"time"`
+	ID            int                    `json:"id"`
+	Name          string                 `json:"name,omitempty"`
+	Mass          float64                `json:"mass"`
+	Charge        float64                `json:"charge"`
+	Radius        float64                `json:"radius"`
+	Position      nbody.Vector2D         `json:"position"`
+	Velocity      nbody.Vector2D         `json:"velocity"`
+	Speed         float64                `json:"speed"`
+	Groups        []string               `json:"groups"`           // tags the body is grouped by
+	Hidden        bool                   `json:"hidden,omitempty"` // whether one of its groups is hidden
+	Elements      *nbody.OrbitalElements `json:"elements,omitempty"`
+	Perturbations []Perturbation         `json:"perturbations"` // strongest first
+}
+
+// newDataSheet builds the data sheet of the i-th body of bodies.
+func newDataSheet(bodies []nbody.Body, i int, time float64) DataSheet {
+	b := bodies[i]
+	ds := DataSheet{
+		Time:     time,
+		ID:       b.ID,
+		Name:     b.Name,
+		Mass:     b.Mass,
+		Charge:   b.Charge,
+		Radius:   b.Radius,
+		Position: b.Position,
+		Velocity: b.Velocity,
+		Speed:    math.Hypot(b.Velocity.X, b.Velocity.Y),
+		Groups:   strings.Fields(b.Tags),
+	}
+	if ds.Groups == nil {
+		ds.Groups = []string{}
+	}
+	if p, ok := nbody.DominantBody(bodies, i); ok {
+		el := nbody.OsculatingElements(b, bodies[p])
+		ds.Elements = &el
+	}
+
+	total := 0.0
+	for j := range bodies {
+		if j == i {
+			continue
+		}
+		f := nbody.GravitationalForce(&bodies[i], &bodies[j])
+		if b.Charge != 0 && bodies[j].Charge != 0 {
+			f = nbody.AddVectors(f, nbody.ElectrostaticForce(&bodies[i], &bodies[j]))
+		}
+		a := nbody.ScaleVector(f, 1/b.Mass)
+		m := math.Hypot(a.X, a.Y)
+		total += m
+		ds.Perturbations = append(ds.Perturbations, Perturbation{Body: bodies[j].ID, Acceleration: a, Magnitude: m})
+	}
+	for k := range ds.Perturbations {
+		if total > 0 {
+			ds.Perturbations[k].Share = ds.Perturbations[k].Magnitude / total
+		}
+	}
+	sort.SliceStable(ds.Perturbations, func(a, b int) bool {
+		return ds.Perturbations[a].Magnitude > ds.Perturbations[b].Magnitude
+	})
+	return ds
+}
+
+// WriteJSON writes the data sheet as indented JSON.
+func (ds DataSheet) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ds)
+}
+
+// WriteMarkdown writes the data sheet as a Markdown document suitable for
+// pasting into a report.
+func (ds DataSheet) WriteMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	if ds.Name != "" {
+		fmt.Fprintf(&sb, "# %s (body %d)\n\n", ds.Name, ds.ID)
+	} else {
+		fmt.Fprintf(&sb, "# Body %d\n\n", ds.ID)
+	}
+	fmt.Fprintf(&sb, "Simulation time: %.3f\n\n", ds.Time)
+	sb.WriteString("## State\n\n| Quantity | Value |\n|---|---|\n")
+	fmt.Fprintf(&sb, "| Mass | %.6g |\n", ds.Mass)
+	fmt.Fprintf(&sb, "| Charge | %.6g |\n", ds.Charge)
+	fmt.Fprintf(&sb, "| Radius | %.6g |\n", ds.Radius)
+	fmt.Fprintf(&sb, "| Position | (%.6g, %.6g) |\n", ds.Position.X, ds.Position.Y)
+	fmt.Fprintf(&sb, "| Velocity | (%.6g, %.6g) |\n", ds.Velocity.X, ds.Velocity.Y)
+	fmt.Fprintf(&sb, "| Speed | %.6g |\n", ds.Speed)
+	groups := strings.Join(ds.Groups, ", ")
+	if groups == "" {
+		groups = "none"
+	}
+	if ds.Hidden {
+		groups += " (hidden)"
+	}
+	fmt.Fprintf(&sb, "| Groups | %s |\n", groups)
+
+	if el := ds.Elements; el != nil {
+		fmt.Fprintf(&sb, "\n## Orbital elements (around body %d)\n\n| Element | Value |\n|---|---|\n", el.Primary)
+		fmt.Fprintf(&sb, "| Distance | %.6g |\n", el.Distance)
+		fmt.Fprintf(&sb, "| Relative speed | %.6g |\n", el.RelSpeed)
+		fmt.Fprintf(&sb, "| Semi-major axis | %.6g |\n", el.SemiMajorAxis)
+		fmt.Fprintf(&sb, "| Eccentricity | %.6g |\n", el.Eccentricity)
+		fmt.Fprintf(&sb, "| Argument of periapsis | %.4f° |\n", el.ArgPeriapsis*180/math.Pi)
+		fmt.Fprintf(&sb, "| True anomaly | %.4f° |\n", el.TrueAnomaly*180/math.Pi)
+		fmt.Fprintf(&sb, "| Periapsis | %.6g |\n", el.Periapsis)
+		if el.Bound {
+			fmt.Fprintf(&sb, "| Apoapsis | %.6g |\n", el.Apoapsis)
+			fmt.Fprintf(&sb, "| Period | %.6g |\n", el.Period)
+		}
+		fmt.Fprintf(&sb, "| Bound | %t |\n", el.Bound)
+	}
+
+	sb.WriteString("\n## Perturbations\n\n| Body | Acceleration | Share |\n|---|---|---|\n")
+	for _, p := range ds.Perturbations {
+		fmt.Fprintf(&sb, "| %d | %.6g | %.2f%% |\n", p.Body, p.Magnitude, p.Share*100)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Export writes the data sheet to <base>.json and <base>.md.
+func (ds DataSheet) Export(base string) error {
+	for _, f := range []struct {
+		ext   string
+		write func(io.Writer) error
+	}{
+		{".json", ds.WriteJSON},
+		{".md", ds.WriteMarkdown},
+	} {
+		file, err := os.Create(base + f.ext)
+		if err != nil {
+			return err
+		}
+		err = f.write(file)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"n-body/nbody"
+)
+
+// bodyDrag moves a body with the mouse while paused: pressing on a body
+// selects it and picks it up, and it follows the cursor, its predicted path
+// updating as it goes, until released.
+type bodyDrag struct {
+	id     int        // ID of the body being moved, or nbody.NoBody
+	vp     int        // index of the viewport it is moved in
+	before nbody.Body // as it was when picked up
+}
+
+// updateBodyDrag picks up, moves and puts down bodies while paused, and
+// reports whether it took the mouse.
+func (g *Game) updateBodyDrag() bool {
+	d := &g.moving
+	if !g.paused {
+		d.id = nbody.NoBody
+		return false
+	}
+	cursor := image.Pt(ebiten.CursorPosition())
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && !g.powerZoomOn {
+		vp := g.viewportAt(cursor)
+		if id := g.bodyAt(&g.viewports[vp], cursor); id != nbody.NoBody {
+			d.id, d.vp = id, vp
+			g.selected = id
+			if i, ok := g.sim.IndexOf(id); ok {
+				d.before = g.sim.Body(i)
+			}
+		}
+	}
+	if d.id == nbody.NoBody {
+		return false
+	}
+	i, ok := g.sim.IndexOf(d.id)
+	if !ok || d.vp >= len(g.viewports) {
+		d.id = nbody.NoBody
+		return true
+	}
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		if b := g.sim.Body(i); b != d.before {
+			g.edits.add(edit{op: "move", time: g.sim.Time, index: i, before: d.before, after: b})
+		}
+		d.id = nbody.NoBody
+		return true
+	}
+	vp := &g.viewports[d.vp]
+	p := vp.Frame.invert(vp.toFrame(cursor, vp.Camera.Center))
+	p.X = math.Mod(math.Mod(p.X, g.sim.Width)+g.sim.Width, g.sim.Width)
+	p.Y = math.Mod(math.Mod(p.Y, g.sim.Height)+g.sim.Height, g.sim.Height)
+	if p != g.sim.Position(i) {
+		g.sim.SetPosition(i, p)
+		g.resync()
+		g.predictor.invalidate()
+	}
+	g.predictor.start(g.sim, d.id)
+	return true
+}
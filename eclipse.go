@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+const (
+	// eclipseTolerance widens the angular radii of the bodies, in radians,
+	// when deciding whether one covers another, so that bodies drawn far
+	// larger than they are still line up where it looks like they do.
+	eclipseTolerance = 0.01
+	// eclipseMaxBodies bounds the bodies searched for alignments, since
+	// each step tries every triple with a luminous body in it.
+	eclipseMaxBodies = 200
+)
+
+// alignment is a body passing in front of a luminous body as seen from
+// another: an eclipse if it covers the light completely, a transit if it
+// crosses the face of the light.
+type alignment struct {
+	light, occulter, observer int // IDs
+	eclipse                   bool
+	start                     float64 // simulated time it began
+}
+
+// eclipses finds the alignments under way, step by step, and logs each as
+// it begins.
+type eclipses struct {
+	on     bool
+	active []alignment
+	next   []alignment
+}
+
+// update finds the alignments among bodies at simulated time t. epoch, if
+// not zero, dates the log messages.
+func (e *eclipses) update(bodies []nbody.Body, t float64, epoch time.Time) {
+	e.next = e.next[:0]
+	if !e.on || len(bodies) > eclipseMaxBodies {
+		e.active = e.active[:0]
+		return
+	}
+	for l, light := range bodies {
+		if !light.Luminous {
+			continue
+		}
+		for o, observer := range bodies {
+			if o == l || observer.Luminous {
+				continue
+			}
+			lx, ly := light.Position.X-observer.Position.X, light.Position.Y-observer.Position.Y
+			dl := math.Hypot(lx, ly)
+			if dl == 0 {
+				continue
+			}
+			lightSize := math.Asin(math.Min(light.Radius/dl, 1))
+			for b, occulter := range bodies {
+				if b == l || b == o {
+					continue
+				}
+				bx, by := occulter.Position.X-observer.Position.X, occulter.Position.Y-observer.Position.Y
+				db := math.Hypot(bx, by)
+				if db == 0 || db >= dl {
+					continue
+				}
+				size := math.Asin(math.Min(occulter.Radius/db, 1))
+				apart := math.Acos(math.Max(-1, math.Min((lx*bx+ly*by)/(dl*db), 1)))
+				if apart > lightSize+size+eclipseTolerance {
+					continue
+				}
+				a := alignment{light: light.ID, occulter: occulter.ID, observer: observer.ID, eclipse: size >= lightSize, start: t}
+				if k := slices.IndexFunc(e.active, a.same); k >= 0 {
+					a.start = e.active[k].start
+				} else {
+					e.report(a, bodies[l], bodies[b], bodies[o], epoch)
+				}
+				e.next = append(e.next, a)
+			}
+		}
+	}
+	e.active, e.next = e.next, e.active
+}
+
+// same reports whether a and b are the same bodies lined up.
+func (a alignment) same(b alignment) bool {
+	return a.light == b.light && a.occulter == b.occulter && a.observer == b.observer
+}
+
+// report logs the start of a.
+func (e *eclipses) report(a alignment, light, occulter, observer nbody.Body, epoch time.Time) {
+	kind := "transit"
+	if a.eclipse {
+		kind = "eclipse"
+	}
+	when := "t = " + formatDuration(a.start)
+	if !epoch.IsZero() {
+		when += " (" + calendarTime(epoch, a.start).Format("2006-01-02 15:04:05 MST") + ")"
+	}
+	log.Printf("%s: %s of %s by %s, seen from %s", when, kind, bodyName(light), bodyName(occulter), bodyName(observer))
+}
+
+// drawEclipses highlights the alignments under way in vp with a line from
+// the light through the occulter to the observer, labelled at the observer.
+// center is the camera center.
+func (g *Game) drawEclipses(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	at := func(id int) (nbody.Vector2D, bool) {
+		i := slices.IndexFunc(g.drawBodies, func(b nbody.Body) bool { return b.ID == id })
+		if i < 0 {
+			return nbody.Vector2D{}, false
+		}
+		return vp.toScreen(g.view(g.framePositions[i]), center), true
+	}
+	for _, a := range g.eclipses.active {
+		l, ok1 := at(a.light)
+		o, ok2 := at(a.observer)
+		if !ok1 || !ok2 {
+			continue
+		}
+		vector.StrokeLine(dst, float32(l.X), float32(l.Y), float32(o.X), float32(o.Y), 1, theme.Eclipse, true)
+		label := "transit"
+		if a.eclipse {
+			label = "eclipse"
+		}
+		drawText(dst, label, int(o.X)+6, int(o.Y)-debugLineHeight)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// editorRange is how far the mass and radius sliders go each way from
+// the body's values when it was selected.
+const editorRange = 1000
+
+// Properties the editor sets, in the order of its sliders.
+const (
+	editMass = iota
+	editRadius
+	editSpeed
+	editHeading
+	editProperties
+)
+
+var editorLabels = [editProperties]string{"mass", "radius", "speed", "heading"}
+
+// propertyEditor changes the selected body's mass, radius and velocity
+// with sliders, while the simulation runs, below the inspector. The
+// sliders' ranges are set around the body as it was when selected, so
+// they stay put while it is edited.
+type propertyEditor struct {
+	on       bool
+	id       int // body the ranges were set for, or nbody.NoBody
+	sliders  [editProperties]slider
+	dragging int        // slider being dragged, or -1
+	before   nbody.Body // as it was when the drag started
+}
+
+// prepare sets the sliders' ranges around bodies[i] if it was not the body
+// they were set for.
+func (e *propertyEditor) prepare(bodies []nbody.Body, i int) {
+	b := bodies[i]
+	if e.id == b.ID {
+		return
+	}
+	e.id = b.ID
+	e.dragging = -1
+	fastest := 0.0
+	for _, o := range bodies {
+		fastest = max(fastest, math.Hypot(o.Velocity.X, o.Velocity.Y))
+	}
+	speed := math.Hypot(b.Velocity.X, b.Velocity.Y)
+	// Log scales can't reach zero, so massless and pointlike bodies get
+	// those of spawned ones to scale from.
+	mass, radius := b.Mass, b.Radius
+	if mass <= 0 {
+		mass = spawnMass
+	}
+	if radius <= 0 {
+		radius = spawnRadius
+	}
+	e.sliders = [editProperties]slider{
+		editMass:    {min: mass / editorRange, max: mass * editorRange, log: true},
+		editRadius:  {min: radius / editorRange, max: radius * editorRange, log: true},
+		editSpeed:   {min: 0, max: max(3*speed, fastest, 1)},
+		editHeading: {min: -180, max: 180},
+	}
+}
+
+// property returns b's value of property p.
+func property(b nbody.Body, p int) float64 {
+	switch p {
+	case editMass:
+		return b.Mass
+	case editRadius:
+		return b.Radius
+	case editSpeed:
+		return math.Hypot(b.Velocity.X, b.Velocity.Y)
+	default:
+		return math.Atan2(b.Velocity.Y, b.Velocity.X) * 180 / math.Pi
+	}
+}
+
+// setProperty sets b's property p to v.
+func setProperty(b *nbody.Body, p int, v float64) {
+	switch p {
+	case editMass:
+		b.Mass = v
+	case editRadius:
+		b.Radius = v
+	case editSpeed:
+		heading := math.Atan2(b.Velocity.Y, b.Velocity.X)
+		b.Velocity = nbody.Vector2D{X: v * math.Cos(heading), Y: v * math.Sin(heading)}
+	default:
+		speed := math.Hypot(b.Velocity.X, b.Velocity.Y)
+		heading := v * math.Pi / 180
+		b.Velocity = nbody.Vector2D{X: speed * math.Cos(heading), Y: speed * math.Sin(heading)}
+	}
+}
+
+// formatProperty formats value v of property p.
+func formatProperty(p int, v float64) string {
+	switch p {
+	case editMass:
+		return fmt.Sprintf("%.4g kg", v)
+	case editRadius:
+		return fmt.Sprintf("%.3g", v)
+	case editSpeed:
+		return formatDistance(v) + "/s"
+	default:
+		return fmt.Sprintf("%.0f deg", v)
+	}
+}
+
+// Columns of the editor, in characters.
+const (
+	editorLabelChars = 8
+	editorValueChars = 15
+)
+
+// editorLayout returns the editor's panel on a screen width pixels wide and
+// the track of each slider in it.
+func editorLayout(width int) (panel image.Rectangle, tracks [editProperties]image.Rectangle) {
+	w := editorLabelChars*debugCharWidth + int(sliderWidth*uiScale) + 8 + editorValueChars*debugCharWidth
+	x := width - w - 6
+	y := inspectorTop() + inspectorMaxLines*debugLineHeight + 8
+	panel = image.Rect(x-2, y-2, x+w+2, y+(editProperties+1)*debugLineHeight+2)
+	for p := range tracks {
+		top := y + (p+1)*debugLineHeight
+		left := x + editorLabelChars*debugCharWidth
+		tracks[p] = image.Rect(left, top+2, left+int(sliderWidth*uiScale), top+debugLineHeight-2)
+	}
+	return panel, tracks
+}
+
+// updateEditor drags the editor's sliders, setting the selected body's
+// properties as they move, and reports whether it took the mouse.
+func (g *Game) updateEditor() bool {
+	e := &g.editor
+	i, ok := g.sim.IndexOf(g.selected)
+	if !e.on || !ok {
+		e.dragging = -1
+		return false
+	}
+	if j := slices.IndexFunc(g.bodies, func(b nbody.Body) bool { return b.ID == g.selected }); j >= 0 {
+		e.prepare(g.bodies, j)
+	}
+	panel, tracks := editorLayout(g.width)
+	cursor := image.Pt(ebiten.CursorPosition())
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if !cursor.In(panel) {
+			return false
+		}
+		for p, r := range tracks {
+			if cursor.In(r.Inset(-int(2 * uiScale))) {
+				e.dragging = p
+				e.before = g.sim.Body(i)
+			}
+		}
+		return true
+	}
+	if e.dragging < 0 {
+		return false
+	}
+	b := g.sim.Body(i)
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		if b != e.before {
+			g.edits.add(edit{op: "set", property: editorLabels[e.dragging], time: g.sim.Time, index: i, before: e.before, after: b})
+		}
+		e.dragging = -1
+		return true
+	}
+	v := e.sliders[e.dragging].at(sliderFraction(tracks[e.dragging], cursor.X))
+	if v != property(b, e.dragging) {
+		setProperty(&b, e.dragging, v)
+		g.sim.SetBody(i, b)
+		g.resync()
+		g.predictor.invalidate()
+	}
+	return true
+}
+
+// drawEditor draws the editor for the selected body.
+func (g *Game) drawEditor(screen *ebiten.Image) {
+	e := &g.editor
+	i := slices.IndexFunc(g.drawBodies, func(b nbody.Body) bool { return b.ID == g.selected })
+	if !e.on || i < 0 || e.id != g.selected {
+		return
+	}
+	b := g.drawBodies[i]
+	panel, tracks := editorLayout(screen.Bounds().Dx())
+	vector.DrawFilledRect(screen, float32(panel.Min.X), float32(panel.Min.Y), float32(panel.Dx()), float32(panel.Dy()), theme.Panel, false)
+	drawText(screen, "edit "+bodyName(b)+" (F4 closes)", panel.Min.X+2, panel.Min.Y+2)
+	for p, r := range tracks {
+		v := property(b, p)
+		drawText(screen, editorLabels[p], panel.Min.X+2, r.Min.Y-2)
+		drawSlider(screen, r, e.sliders[p].fraction(v), e.dragging == p)
+		drawText(screen, formatProperty(p, v), r.Max.X+8, r.Min.Y-2)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"n-body/nbody"
+	"n-body/server"
+)
+
+// edit is a change made to the running simulation by hand.
+type edit struct {
+	op            string     // what was done: "spawn", "remove", "move" or "set"
+	property      string     // for "set", what of the body was set
+	time          float64    // simulated seconds it was done at
+	index         int        // of the body in the simulation
+	before, after nbody.Body // the body as it was and became, where there was one
+}
+
+// body returns the body e left, or removed.
+func (e *edit) body() nbody.Body {
+	if e.op == "remove" {
+		return e.before
+	}
+	return e.after
+}
+
+// String describes e for the log.
+func (e *edit) String() string {
+	b := e.body()
+	if e.op == "set" {
+		return fmt.Sprintf("set %s of %s (#%d)", e.property, bodyName(b), b.ID)
+	}
+	return fmt.Sprintf("%s %s (#%d)", e.op, bodyName(b), b.ID)
+}
+
+// editRecord is an edit as written to the edit log: enough to make it
+// again, through the HTTP API or by hand, on a simulation in the same
+// state. Undoing an edit writes it again with Undo set, at the time it was
+// undone.
+type editRecord struct {
+	Time float64         `json:"time"`
+	Op   string          `json:"op"`
+	Set  string          `json:"set,omitempty"` // the property set by a "set"
+	Undo bool            `json:"undo,omitempty"`
+	Body server.WireBody `json:"body"`
+}
+
+// editLog keeps the edits made to the simulation, oldest first, so they can
+// be undone and redone, and writes each to out, if set, as a line of JSON,
+// so a session can be replayed.
+type editLog struct {
+	edits  []edit
+	undone []edit // most recently undone last
+	out    io.WriteCloser
+}
+
+// openEditLog returns an edit log writing to the file at path, created or
+// truncated, or only keeping edits in memory if path is empty.
+func openEditLog(path string) (*editLog, error) {
+	if path == "" {
+		return &editLog{}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("edit log: %w", err)
+	}
+	return &editLog{out: f}, nil
+}
+
+// add records e, which has been made. Edits undone before can no longer be
+// redone.
+func (l *editLog) add(e edit) {
+	l.edits = append(l.edits, e)
+	l.undone = l.undone[:0]
+	log.Printf("t = %s: %s", formatDuration(e.time), &e)
+	l.write(editRecord{Time: e.time, Op: e.op, Set: e.property, Body: server.NewWireBody(e.body())})
+}
+
+// write writes r to the file, if any.
+func (l *editLog) write(r editRecord) {
+	if l.out == nil {
+		return
+	}
+	data, err := json.Marshal(r)
+	if err == nil {
+		_, err = l.out.Write(append(data, '\n'))
+	}
+	if err != nil {
+		log.Printf("edit log: %v", err)
+	}
+}
+
+// forget drops the edits kept, once the simulation they were made to is
+// gone.
+func (l *editLog) forget() {
+	l.edits, l.undone = l.edits[:0], l.undone[:0]
+}
+
+// Close closes the file written to, if any.
+func (l *editLog) Close() error {
+	if l.out == nil {
+		return nil
+	}
+	return l.out.Close()
+}
+
+// undo reverts the latest edit still in effect.
+func (g *Game) undo() {
+	l := g.edits
+	if len(l.edits) == 0 {
+		log.Print("nothing to undo")
+		return
+	}
+	e := l.edits[len(l.edits)-1]
+	l.edits = l.edits[:len(l.edits)-1]
+	if !g.applyEdit(&e, true) {
+		log.Printf("can't undo %s: the body has come or gone since", &e)
+		return
+	}
+	l.undone = append(l.undone, e)
+	log.Printf("t = %s: undid %s", formatDuration(g.sim.Time), &e)
+	l.write(editRecord{Time: g.sim.Time, Op: e.op, Set: e.property, Undo: true, Body: server.NewWireBody(e.body())})
+}
+
+// redo makes the latest undone edit again.
+func (g *Game) redo() {
+	l := g.edits
+	if len(l.undone) == 0 {
+		log.Print("nothing to redo")
+		return
+	}
+	e := l.undone[len(l.undone)-1]
+	l.undone = l.undone[:len(l.undone)-1]
+	if !g.applyEdit(&e, false) {
+		log.Printf("can't redo %s: the body has come or gone since", &e)
+		return
+	}
+	l.edits = append(l.edits, e)
+	log.Printf("t = %s: redid %s", formatDuration(g.sim.Time), &e)
+	l.write(editRecord{Time: g.sim.Time, Op: e.op, Set: e.property, Body: server.NewWireBody(e.body())})
+}
+
+// applyEdit makes e, or reverts it if undo is set, to the bodies as they
+// are now, and reports whether it could: the body it changed may have gone
+// or, for a removal, come back. Spawns and removals take out or put back
+// the body as it was when they were made; moves and property edits set only
+// what they changed, leaving the body's motion since alone.
+func (g *Game) applyEdit(e *edit, undo bool) bool {
+	i, ok := g.sim.IndexOf(e.body().ID)
+	to := e.after
+	if undo {
+		to = e.before
+	}
+	switch {
+	case e.op == "spawn" && !undo || e.op == "remove" && undo:
+		if ok {
+			return false
+		}
+		g.sim.InsertBody(min(e.index, g.sim.Len()), to)
+	case e.op == "spawn" || e.op == "remove":
+		if !ok {
+			return false
+		}
+		g.sim.RemoveBody(i)
+		g.deselect(e.body().ID)
+	default:
+		if !ok {
+			return false
+		}
+		b := g.sim.Body(i)
+		if e.op == "move" {
+			b.Position = to.Position
+		} else {
+			p := slices.Index(editorLabels[:], e.property)
+			setProperty(&b, p, property(to, p))
+		}
+		g.sim.SetBody(i, b)
+	}
+	g.resync()
+	g.predictor.invalidate()
+	return true
+}
+
+// removeBody removes the i-th body from the simulation, logging it.
+func (g *Game) removeBody(i int) {
+	b := g.sim.Body(i)
+	g.sim.RemoveBody(i)
+	g.edits.add(edit{op: "remove", time: g.sim.Time, index: i, before: b})
+	g.deselect(b.ID)
+	g.resync()
+	g.predictor.invalidate()
+}
+
+// deselect stops selecting or pairing body id, which is gone.
+func (g *Game) deselect(id int) {
+	if g.selected == id {
+		g.selected = nbody.NoBody
+	}
+	if g.partner == id {
+		g.partner = nbody.NoBody
+	}
+}
+
+// updateRemoval removes the body right-clicked.
+func (g *Game) updateRemoval() {
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		return
+	}
+	cursor := image.Pt(ebiten.CursorPosition())
+	if i, ok := g.sim.IndexOf(g.bodyAt(&g.viewports[g.viewportAt(cursor)], cursor)); ok {
+		g.removeBody(i)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// exposureCells is the number of cells along the longer side of the world
+// that visits are counted in.
+const exposureCells = 512
+
+// exposure is a long-exposure picture of where bodies have been: every
+// step, each body adds a visit to the cell of a grid over the world it is
+// in, in the viewport's frame. Shaded by the log of the visits, orbits
+// that repeat show as sharp rings, resonances as patterns and chaotic
+// regions as haze, at the cost of a counter per cell rather than a trail
+// per body.
+type exposure struct {
+	cols, rows int
+	cell       float64   // side of a cell in world units
+	visits     []float32 // row by row
+	most       float32   // visits to the busiest cell
+	dirty      bool      // visits changed since pixels were written
+	pixels     []byte
+	img        *ebiten.Image
+}
+
+// record adds a visit for each of bodies, at their positions in frame, to
+// the grid over a width × height world. Bodies outside the world are left
+// out.
+func (e *exposure) record(bodies []nbody.Body, frame *Frame, width, height float64) {
+	cell := math.Max(width, height) / exposureCells
+	if cell != e.cell || e.visits == nil {
+		e.cell = cell
+		e.cols, e.rows = int(math.Ceil(width/cell)), int(math.Ceil(height/cell))
+		e.visits = make([]float32, e.cols*e.rows)
+		e.most = 0
+	}
+	for _, b := range bodies {
+		p := frame.apply(b.Position)
+		i, j := int(math.Floor(p.X/cell)), int(math.Floor(p.Y/cell))
+		if i < 0 || i >= e.cols || j < 0 || j >= e.rows {
+			continue
+		}
+		k := j*e.cols + i
+		e.visits[k]++
+		e.most = max(e.most, e.visits[k])
+	}
+	e.dirty = true
+}
+
+// reset forgets every visit.
+func (e *exposure) reset() {
+	clear(e.visits)
+	e.most = 0
+	e.dirty = true
+}
+
+// draw shades the visits over vp. center is the camera center.
+func (e *exposure) draw(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	if e.most == 0 {
+		return
+	}
+	if e.img == nil || e.img.Bounds().Dx() != e.cols || e.img.Bounds().Dy() != e.rows {
+		if e.img != nil {
+			e.img.Deallocate()
+		}
+		e.img = ebiten.NewImage(e.cols, e.rows)
+		e.dirty = true
+	}
+	if e.dirty {
+		e.pixels = slices.Grow(e.pixels[:0], 4*len(e.visits))[:4*len(e.visits)]
+		scale := 1 / math.Log1p(float64(e.most))
+		for k, v := range e.visits {
+			px := e.pixels[4*k : 4*k+4]
+			if v == 0 {
+				px[0], px[1], px[2], px[3] = 0, 0, 0, 0
+				continue
+			}
+			c := heatmapColors.at(math.Log1p(float64(v)) * scale)
+			px[0], px[1], px[2], px[3] = byte(c[0]), byte(c[1]), byte(c[2]), 255
+		}
+		e.img.WritePixels(e.pixels)
+		e.dirty = false
+	}
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(e.cell*vp.Camera.Zoom, e.cell*vp.Camera.Zoom)
+	origin := vp.toScreen(nbody.Vector2D{}, center)
+	op.GeoM.Translate(origin.X, origin.Y)
+	dst.DrawImage(e.img, op)
+}
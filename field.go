@@ -0,0 +1,96 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+const (
+	// fieldSpacing is the distance in pixels between arrows, before
+	// fieldMaxPairs spreads them further.
+	fieldSpacing = 32
+	// fieldArrowMin is the length in pixels of the weakest arrow.
+	fieldArrowMin = 4
+	// fieldMaxPairs bounds the sample-body pairs summed per frame.
+	fieldMaxPairs = 2e6
+)
+
+// vectorField is the gravitational acceleration sampled on a grid of
+// points over a viewport, drawn as arrows.
+type vectorField struct {
+	samples []fieldSample
+}
+
+type fieldSample struct {
+	at   nbody.Vector2D // screen position
+	a    nbody.Vector2D // acceleration
+	size float64        // log of its magnitude
+}
+
+// draw queues the field's arrows over vp on lines, from bodies at positions
+// in vp's frame. center is the camera center. Field strength spans orders
+// of magnitude, so arrow length follows its logarithm between the weakest
+// and strongest samples.
+func (f *vectorField) draw(dst *ebiten.Image, lines *lineBatch, vp *Viewport, bodies []nbody.Body, positions []nbody.Vector2D, center nbody.Vector2D) {
+	w, h := vp.Bounds.Dx(), vp.Bounds.Dy()
+	minSpacing := fieldSpacing * uiScale
+	spacing := int(minSpacing)
+	if pairs := float64(w*h) / minSpacing / minSpacing * float64(len(bodies)); pairs > fieldMaxPairs {
+		spacing = int(math.Ceil(minSpacing * math.Sqrt(pairs/fieldMaxPairs)))
+	}
+	cols, rows := w/spacing, h/spacing
+	arrowMax := float64(spacing) - 6*uiScale
+	arrowMin := fieldArrowMin * uiScale
+
+	f.samples = f.samples[:0]
+	lo, hi := math.Inf(1), math.Inf(-1)
+	offX := float64(w-(cols-1)*spacing) / 2
+	offY := float64(h-(rows-1)*spacing) / 2
+	for j := range rows {
+		for i := range cols {
+			s := nbody.Vector2D{
+				X: float64(vp.Bounds.Min.X) + offX + float64(i*spacing),
+				Y: float64(vp.Bounds.Min.Y) + offY + float64(j*spacing),
+			}
+			x := center.X + (s.X-float64(vp.Bounds.Min.X)-float64(w)/2)/vp.Camera.Zoom
+			y := center.Y + (s.Y-float64(vp.Bounds.Min.Y)-float64(h)/2)/vp.Camera.Zoom
+			var a nbody.Vector2D
+			for k, b := range bodies {
+				ax, ay := nbody.PairAcceleration(positions[k].X-x, positions[k].Y-y, nbody.G*b.Mass)
+				if !math.IsNaN(ax) {
+					a.X, a.Y = a.X+ax, a.Y+ay
+				}
+			}
+			size := math.Log(math.Hypot(a.X, a.Y))
+			if math.IsInf(size, -1) {
+				continue
+			}
+			lo, hi = math.Min(lo, size), math.Max(hi, size)
+			f.samples = append(f.samples, fieldSample{s, a, size})
+		}
+	}
+
+	for _, s := range f.samples {
+		length := arrowMax
+		if hi > lo {
+			length = arrowMin + (arrowMax-arrowMin)*(s.size-lo)/(hi-lo)
+		}
+		mag := math.Hypot(s.a.X, s.a.Y)
+		ux, uy := s.a.X/mag, s.a.Y/mag
+		// Centered on the sample point, pointing along the field.
+		tail := nbody.Vector2D{X: s.at.X - ux*length/2, Y: s.at.Y - uy*length/2}
+		tip := nbody.Vector2D{X: s.at.X + ux*length/2, Y: s.at.Y + uy*length/2}
+		lines.add(dst, tail, tip, theme.Field)
+		head := math.Min(length/3, 5)
+		for _, side := range [2]float64{1, -1} {
+			lines.add(dst, tip, nbody.Vector2D{
+				X: tip.X - head*(ux+side*uy/2),
+				Y: tip.Y - head*(uy-side*ux/2),
+			}, theme.Field)
+		}
+	}
+	lines.flush(dst)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+
+	"n-body/nbody"
+)
+
+// FrameKind selects the reference frame a viewport renders in.
+type FrameKind int
+
+const (
+	FrameInertial     FrameKind = iota // world coordinates as simulated
+	FrameBarycentric                   // centered on the system barycenter
+	FrameBodyCentered                  // centered on a reference body
+	FrameCoRotating                    // barycentric, rotating with a reference body
+	frameKinds
+)
+
+var frameNames = [frameKinds]string{"inertial", "barycentric", "body-centered", "co-rotating"}
+
+func (k FrameKind) String() string { return frameNames[k] }
+
+// Frame maps world positions into a reference frame. Every non-inertial
+// frame keeps its origin at the middle of the world, so a default camera
+// stays pointed at it.
+type Frame struct {
+	Kind FrameKind
+	Body int // reference body ID for body-centered and co-rotating frames
+
+	center   nbody.Vector2D // middle of the world
+	origin   nbody.Vector2D
+	velocity nbody.Vector2D // of the origin
+	rotation float64        // angle by which positions are rotated back
+	spin     float64        // angular velocity of the rotation, in radians per second
+	angle0   float64        // reference body angle when rotation started
+	rotating bool
+}
+
+// prepare computes the frame's origin and rotation for the current state of
+// a world whose middle is center.
+func (f *Frame) prepare(bodies []nbody.Body, center nbody.Vector2D) {
+	f.center = center
+	f.origin, f.velocity = center, nbody.Vector2D{}
+	f.rotation, f.spin = 0, 0
+	ref, ok := nbody.FindBody(bodies, f.Body)
+
+	switch f.Kind {
+	case FrameBarycentric:
+		f.origin, f.velocity = nbody.Barycenter(bodies), nbody.BarycenterVelocity(bodies)
+	case FrameBodyCentered:
+		if ok {
+			f.origin, f.velocity = ref.Position, ref.Velocity
+		}
+	case FrameCoRotating:
+		f.origin, f.velocity = nbody.Barycenter(bodies), nbody.BarycenterVelocity(bodies)
+		if !ok {
+			break
+		}
+		rx, ry := ref.Position.X-f.origin.X, ref.Position.Y-f.origin.Y
+		angle := math.Atan2(ry, rx)
+		if !f.rotating {
+			f.angle0, f.rotating = angle, true
+		}
+		f.rotation = angle - f.angle0
+		// The reference body's angular velocity about the barycenter.
+		if r2 := rx*rx + ry*ry; r2 > 0 {
+			vx, vy := ref.Velocity.X-f.velocity.X, ref.Velocity.Y-f.velocity.Y
+			f.spin = (rx*vy - ry*vx) / r2
+		}
+	}
+	if f.Kind != FrameCoRotating {
+		f.rotating = false
+	}
+}
+
+// apply maps a world position into the frame.
+func (f *Frame) apply(p nbody.Vector2D) nbody.Vector2D {
+	if f.Kind == FrameInertial {
+		return p
+	}
+	dx, dy := p.X-f.origin.X, p.Y-f.origin.Y
+	if f.rotation != 0 {
+		sin, cos := math.Sincos(-f.rotation)
+		dx, dy = dx*cos-dy*sin, dx*sin+dy*cos
+	}
+	return nbody.Vector2D{X: f.center.X + dx, Y: f.center.Y + dy}
+}
+
+// invert maps a position in the frame back to the world.
+func (f *Frame) invert(p nbody.Vector2D) nbody.Vector2D {
+	if f.Kind == FrameInertial {
+		return p
+	}
+	dx, dy := p.X-f.center.X, p.Y-f.center.Y
+	if f.rotation != 0 {
+		sin, cos := math.Sincos(f.rotation)
+		dx, dy = dx*cos-dy*sin, dx*sin+dy*cos
+	}
+	return nbody.Vector2D{X: f.origin.X + dx, Y: f.origin.Y + dy}
+}
+
+// worldVelocity returns the velocity in the world of something at frame
+// position p moving at v through the frame.
+func (f *Frame) worldVelocity(p, v nbody.Vector2D) nbody.Vector2D {
+	if f.Kind == FrameInertial {
+		return v
+	}
+	if f.rotation != 0 {
+		sin, cos := math.Sincos(f.rotation)
+		v = nbody.Vector2D{X: v.X*cos - v.Y*sin, Y: v.X*sin + v.Y*cos}
+	}
+	w := f.invert(p)
+	return nbody.Vector2D{
+		X: v.X + f.velocity.X - f.spin*(w.Y-f.origin.Y),
+		Y: v.Y + f.velocity.Y + f.spin*(w.X-f.origin.X),
+	}
+}
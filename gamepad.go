@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"n-body/nbody"
+)
+
+const (
+	// stickDeadZone is how far a stick may rest off center without moving
+	// anything.
+	stickDeadZone = 0.2
+	// padPanSpeed is how fast, in pixels a second before scaling, the left
+	// stick pans when pushed all the way.
+	padPanSpeed = 600
+	// padZoomRate is how many times over a second the right stick zooms
+	// when pushed all the way.
+	padZoomRate = 4
+)
+
+// padBinding is what pressing a gamepad button does. Buttons are named by
+// their place on an Xbox-style pad.
+type padBinding struct {
+	button ebiten.StandardGamepadButton
+	name   string
+	help   string
+	do     func(g *Game)
+}
+
+// padmap is every gamepad button the game handles, in the order help lists
+// them, for gamepads with a standard layout. The left stick pans the active
+// viewport and the right stick zooms it.
+var padmap = []padBinding{
+	{ebiten.StandardGamepadButtonRightBottom, "A", "pause or resume", (*Game).togglePause},
+	{ebiten.StandardGamepadButtonRightRight, "B", "step once while paused", func(g *Game) {
+		if g.paused {
+			g.stepPaused()
+		}
+	}},
+	{ebiten.StandardGamepadButtonRightLeft, "X", "run time backwards or forwards", func(g *Game) { g.sim.Reverse = !g.sim.Reverse }},
+	{ebiten.StandardGamepadButtonRightTop, "Y", "reset the camera", func(g *Game) {
+		g.activeViewport().Camera = defaultCamera(g.sim.Center())
+	}},
+	{ebiten.StandardGamepadButtonFrontTopRight, "RB", "select and follow the next body", func(g *Game) {
+		g.followBody(nbody.NextBodyID(g.bodies, g.activeViewport().Camera.Follow))
+	}},
+	{ebiten.StandardGamepadButtonFrontTopLeft, "LB", "select and follow the previous body", func(g *Game) {
+		g.followBody(nbody.PrevBodyID(g.bodies, g.activeViewport().Camera.Follow))
+	}},
+	{ebiten.StandardGamepadButtonLeftTop, "Up", "speed time up x2", func(g *Game) { g.clock.scale(2) }},
+	{ebiten.StandardGamepadButtonLeftBottom, "Down", "slow time down x2", func(g *Game) { g.clock.scale(0.5) }},
+	{ebiten.StandardGamepadButtonLeftLeft, "Left", "reset the speed of time", func(g *Game) { g.clock.warp = 1 }},
+	{ebiten.StandardGamepadButtonCenterRight, "Start", "show or hide this help", func(g *Game) { g.helpOn = !g.helpOn }},
+}
+
+// followBody selects body id and has the active viewport's camera follow it.
+func (g *Game) followBody(id int) {
+	g.selected = id
+	g.activeViewport().Camera.Follow = id
+}
+
+// updateGamepads does what the buttons pressed this tick and the sticks of
+// every connected gamepad with a standard layout do.
+func (g *Game) updateGamepads() {
+	for _, id := range inpututil.AppendJustConnectedGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			log.Printf("gamepad connected: %s", ebiten.GamepadName(id))
+		} else {
+			log.Printf("gamepad connected: %s, which has no standard layout and is ignored", ebiten.GamepadName(id))
+		}
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for _, b := range padmap {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, b.button) {
+				b.do(g)
+			}
+		}
+		dt := 1 / float64(ebiten.TPS())
+		c := &g.activeViewport().Camera
+		x := stick(ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal))
+		y := stick(ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical))
+		if x != 0 || y != 0 {
+			c.Follow = nbody.NoBody
+			c.Center.X += x * padPanSpeed * uiScale * dt / c.Zoom
+			c.Center.Y += y * padPanSpeed * uiScale * dt / c.Zoom
+		}
+		if z := stick(ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisRightStickVertical)); z != 0 {
+			c.Zoom *= math.Pow(padZoomRate, -z*dt)
+		}
+	}
+}
+
+// stick returns a stick's axis value v with the dead zone taken out, so it
+// still runs from -1 to 1.
+func stick(v float64) float64 {
+	if math.Abs(v) < stickDeadZone {
+		return 0
+	}
+	return math.Copysign((math.Abs(v)-stickDeadZone)/(1-stickDeadZone), v)
+}
+
+// gamepadHelp lists the gamepad's sticks and buttons, after the mouse in
+// help.
+func gamepadHelp() []string {
+	lines := []string{"gamepad: left stick pans, right stick zooms"}
+	for _, b := range padmap {
+		lines = append(lines, fmt.Sprintf("  %-5s  %s", b.name, b.help))
+	}
+	return lines
+}
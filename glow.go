@@ -0,0 +1,102 @@
+package main
+
+import (
+	_ "embed"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+//go:embed glow.kage
+var glowShaderSource []byte
+
+const (
+	// glowExtent is the radius of a glow in body radii.
+	glowExtent = 4
+	// glowMinRadius and glowMaxRadius bound the radius of a glow in pixels.
+	glowMinRadius = 12
+	glowMaxRadius = 400
+	// glowFullRadius is the largest a luminous body can be on screen, in
+	// pixels, and still glow at full intensity. Beyond it the glow dims in
+	// proportion, so a close-up shows the body rather than its halo.
+	glowFullRadius = 8
+	// glowMinIntensity keeps a faint glow however close the camera is.
+	glowMinIntensity = 0.15
+)
+
+// glowBatch draws halos around luminous bodies with a shader, blended
+// additively so overlapping glows brighten each other. If the shader
+// failed to compile, it draws nothing.
+type glowBatch struct {
+	batch
+	failed bool
+}
+
+// add queues a glow for a body of radius pixels at (x, y).
+func (g *glowBatch) add(dst *ebiten.Image, x, y, radius float64, c color.Color) {
+	if g.shader == nil && !g.failed {
+		s, err := ebiten.NewShader(glowShaderSource)
+		if err != nil {
+			log.Printf("compiling glow shader: %v", err)
+			g.failed = true
+		}
+		g.shader, g.blend = s, ebiten.BlendLighter
+	}
+	if g.shader == nil {
+		return
+	}
+	base := g.reserve(dst, 4, 6)
+	r := float32(math.Min(math.Max(radius*glowExtent, glowMinRadius*uiScale), glowMaxRadius*uiScale))
+	intensity := float32(math.Max(math.Min(glowFullRadius*uiScale/radius, 1), glowMinIntensity))
+	v := vertexColor(c)
+	v.ColorR *= intensity
+	v.ColorG *= intensity
+	v.ColorB *= intensity
+	v.ColorA *= intensity
+	for _, corner := range [4][2]float32{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+		v.DstX = float32(x) + corner[0]*r
+		v.DstY = float32(y) + corner[1]*r
+		v.SrcX, v.SrcY = corner[0], corner[1]
+		g.vertices = append(g.vertices, v)
+	}
+	g.indices = append(g.indices, base, base+1, base+2, base+1, base+3, base+2)
+}
+
+// awayFromLight returns the direction, as a unit vector, from the luminous
+// body nearest the i-th drawn body to it, and their distance, in the frame
+// of the viewport being drawn. ok is false if there is no other luminous
+// body.
+func (g *Game) awayFromLight(i int) (ux, uy, d float64, ok bool) {
+	p := g.framePositions[i]
+	d = math.Inf(1)
+	for j, sun := range g.drawBodies {
+		if !sun.Luminous || j == i {
+			continue
+		}
+		q := g.framePositions[j]
+		if r := math.Hypot(p.X-q.X, p.Y-q.Y); r < d && r > 0 {
+			ux, uy, d = (p.X-q.X)/r, (p.Y-q.Y)/r, r
+		}
+	}
+	return ux, uy, d, !math.IsInf(d, 1)
+}
+
+// drawGlows draws the glow of each luminous body in vp, unless the quality
+// governor has turned glows off. center is the camera center.
+func (g *Game) drawGlows(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	if g.quality.level >= qualityNoGlow {
+		return
+	}
+	for i, body := range g.drawBodies {
+		if !body.Luminous {
+			continue
+		}
+		pos := vp.toScreen(g.view(g.framePositions[i]), center)
+		g.glows.add(dst, pos.X, pos.Y, g.sizes.radius(body, vp.Camera.Zoom), body.Color)
+	}
+	g.glows.flush(dst)
+}
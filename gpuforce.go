@@ -0,0 +1,161 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"image"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// maxGPUBodies is the loop bound compiled into the shader.
+const maxGPUBodies = 16384
+
+//go:embed gpuforce.kage
+var gpuForceShader []byte
+
+// gpuSolver computes pairwise gravity in a Kage shader. Ebiten images hold
+// 8 bits per channel, so every value crosses the CPU/GPU boundary as a
+// 24-bit fixed-point fraction spread over the RGB channels of one pixel:
+// positions relative to the world bounds, masses on a log scale between the
+// lightest and heaviest body, and accelerations relative to the largest
+// acceleration the softened force law allows. Each body occupies one row of
+// a 4-pixel-wide image (x, y, mass, massive flag) and its acceleration is
+// read back from the first two pixels of the same row of the output.
+//
+// The shader works in float32 and only handles gravity; charges, constraints
+// and MOND are still applied on the CPU. Systems larger than maxGPUBodies
+// fall back to the direct CPU sum.
+type gpuSolver struct {
+	shader   *ebiten.Shader
+	input    *ebiten.Image
+	output   *ebiten.Image
+	capacity int
+	pixels   []byte
+	warned   bool
+}
+
+func newGPUSolver() (*gpuSolver, error) {
+	shader, err := ebiten.NewShader(gpuForceShader)
+	if err != nil {
+		return nil, fmt.Errorf("compiling force shader: %w", err)
+	}
+	return &gpuSolver{shader: shader}, nil
+}
+
+// ensureCapacity (re)allocates the data images so they hold at least n rows.
+func (g *gpuSolver) ensureCapacity(n int) {
+	if n <= g.capacity {
+		return
+	}
+	capacity := 64
+	for capacity < n {
+		capacity *= 2
+	}
+	if g.input != nil {
+		g.input.Deallocate()
+		g.output.Deallocate()
+	}
+	opts := &ebiten.NewImageOptions{Unmanaged: true}
+	g.input = ebiten.NewImageWithOptions(image.Rect(0, 0, 4, capacity), opts)
+	g.output = ebiten.NewImageWithOptions(image.Rect(0, 0, 4, capacity), opts)
+	g.capacity = capacity
+	g.pixels = make([]byte, 4*4*capacity)
+}
+
+func (g *gpuSolver) Accelerations(s *nbody.Simulation, acc []nbody.Vector2D) {
+	n := s.Len()
+	x, y := s.Positions()
+	masses := s.Masses()
+	if n > maxGPUBodies {
+		if !g.warned {
+			log.Printf("gpu: %d bodies exceed the shader limit of %d, using the CPU", n, maxGPUBodies)
+			g.warned = true
+		}
+		cpuGravity(x, y, masses, acc)
+		return
+	}
+	g.ensureCapacity(n)
+
+	logMin, logMax := math.Inf(1), math.Inf(-1)
+	sumGM := 0.0
+	for _, m := range masses {
+		if m > 0 {
+			gm := nbody.G * m * nbody.ScaleFactor
+			logMin = math.Min(logMin, math.Log(gm))
+			logMax = math.Max(logMax, math.Log(gm))
+			sumGM += gm
+		}
+	}
+	if sumGM == 0 {
+		clear(acc)
+		return
+	}
+	logSpan := math.Max(logMax-logMin, 1e-9)
+	accelScale := sumGM / (nbody.Softening * nbody.Softening)
+
+	clear(g.pixels)
+	for i, m := range masses {
+		row := g.pixels[i*16 : i*16+16]
+		encodeFraction(row[0:], x[i]/s.Width)
+		encodeFraction(row[4:], y[i]/s.Height)
+		if m > 0 {
+			encodeFraction(row[8:], (math.Log(nbody.G*m*nbody.ScaleFactor)-logMin)/logSpan)
+			row[12], row[15] = 255, 255
+		}
+	}
+	g.input.WritePixels(g.pixels)
+
+	op := &ebiten.DrawRectShaderOptions{Blend: ebiten.BlendCopy}
+	op.Images[0] = g.input
+	op.Uniforms = map[string]any{
+		"Count":       float32(n),
+		"Bounds":      []float32{float32(s.Width), float32(s.Height)},
+		"MassLogMin":  float32(logMin),
+		"MassLogSpan": float32(logSpan),
+		"AccelScale":  float32(accelScale),
+		"Softening2":  float32(nbody.Softening * nbody.Softening),
+	}
+	g.output.DrawRectShader(4, g.capacity, g.shader, op)
+	g.output.ReadPixels(g.pixels)
+
+	for i := range acc[:n] {
+		row := g.pixels[i*16 : i*16+16]
+		acc[i] = nbody.Vector2D{
+			X: (decodeFraction(row[0:])*2 - 1) * accelScale,
+			Y: (decodeFraction(row[4:])*2 - 1) * accelScale,
+		}
+	}
+}
+
+// encodeFraction stores u, clamped to [0, 1], as a 24-bit fixed-point value
+// in the RGB channels of the pixel p, with an opaque alpha so premultiplied
+// alpha leaves the channels untouched.
+func encodeFraction(p []byte, u float64) {
+	q := uint32(math.Round(math.Max(0, math.Min(1, u)) * (1<<24 - 1)))
+	p[0], p[1], p[2], p[3] = byte(q>>16), byte(q>>8), byte(q), 255
+}
+
+func decodeFraction(p []byte) float64 {
+	return float64(uint32(p[0])<<16|uint32(p[1])<<8|uint32(p[2])) / (1<<24 - 1)
+}
+
+// cpuGravity is the direct-sum fallback used when the GPU can't take the
+// system.
+func cpuGravity(x, y, masses []float64, acc []nbody.Vector2D) {
+	for i := range masses {
+		var ax, ay float64
+		for j := range masses {
+			if i != j {
+				gx, gy := nbody.PairAcceleration(x[j]-x[i], y[j]-y[i], nbody.G*masses[j])
+				ax += gx
+				ay += gy
+			}
+		}
+		acc[i] = nbody.Vector2D{X: ax, Y: ay}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// groups are the tags bodies are grouped by, such as "moons" or
+// "asteroids", and which of them are hidden. Digits 1-9 hide and show the
+// groups in order, so dense scenarios can be thinned out to what matters.
+// A body is hidden if any group it belongs to is; hidden bodies still pull
+// on the rest but are left out of everything drawn.
+type groups struct {
+	tags   []string // sorted
+	hidden map[string]bool
+}
+
+// update collects the tags of bodies, keeping which are hidden.
+func (gr *groups) update(bodies []nbody.Body) {
+	gr.tags = gr.tags[:0]
+	for _, b := range bodies {
+		for _, tag := range strings.Fields(b.Tags) {
+			if i, found := slices.BinarySearch(gr.tags, tag); !found {
+				gr.tags = slices.Insert(gr.tags, i, tag)
+			}
+		}
+	}
+}
+
+// toggle hides or shows the n-th group, counting from zero.
+func (gr *groups) toggle(n int) {
+	if n >= len(gr.tags) {
+		return
+	}
+	tag := gr.tags[n]
+	if gr.hidden == nil {
+		gr.hidden = make(map[string]bool)
+	}
+	gr.hidden[tag] = !gr.hidden[tag]
+	if gr.hidden[tag] {
+		log.Printf("hiding %s", tag)
+	} else {
+		log.Printf("showing %s", tag)
+	}
+}
+
+// visible reports whether b is in no hidden group.
+func (gr *groups) visible(b nbody.Body) bool {
+	if len(gr.hidden) == 0 || b.Tags == "" {
+		return true
+	}
+	for _, tag := range strings.Fields(b.Tags) {
+		if gr.hidden[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// filter removes the hidden bodies from bodies in place and returns what
+// is left.
+func (gr *groups) filter(bodies []nbody.Body) []nbody.Body {
+	if len(gr.hidden) == 0 {
+		return bodies
+	}
+	return slices.DeleteFunc(bodies, func(b nbody.Body) bool { return !gr.visible(b) })
+}
+
+// draw lists the groups and their keys in the bottom right corner, above
+// the scale bar, marking the hidden ones.
+func (gr *groups) draw(screen *ebiten.Image) {
+	n := min(len(gr.tags), 9)
+	y := screen.Bounds().Dy() - (n+2)*debugLineHeight
+	for k, tag := range gr.tags[:n] {
+		line := fmt.Sprintf("%d %s", k+1, tag)
+		if gr.hidden[tag] {
+			line += " (hidden)"
+		}
+		drawText(screen, line, screen.Bounds().Dx()-len(line)*debugCharWidth-4, y)
+		y += debugLineHeight
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// checkFinite stops the simulation if the last step left a body NaN or
+// infinite, keeping the last good state on screen instead of garbage. It
+// reports whether the simulation may go on.
+func (g *Game) checkFinite() bool {
+	if err := g.sim.CheckFinite(); err != nil {
+		g.fault = err
+		log.Printf("simulation stopped: %v", err)
+		return false
+	}
+	g.history.Record(g.sim)
+	if g.timeline.history != nil {
+		g.timeline.history.Record(g.sim)
+	}
+	return true
+}
+
+// rollback restores the newest snapshot in the history and resumes.
+func (g *Game) rollback() {
+	if !g.history.Rollback(g.sim) {
+		log.Print("no snapshot left to roll back to")
+		return
+	}
+	log.Printf("rolled back to step %d (t=%.2fs)", g.sim.Steps, g.sim.Time)
+	g.fault = nil
+	for i := range g.viewports {
+		g.viewports[i].trails.reset()
+	}
+	g.resync()
+}
+
+// drawFault explains why the simulation stopped and what can be done.
+func (g *Game) drawFault(screen *ebiten.Image) {
+	if g.fault == nil {
+		return
+	}
+	msg := "Simulation stopped: " + g.fault.Error()
+	if g.history.Len() > 0 {
+		msg += fmt.Sprintf("\nPress B to roll back (%d snapshots left).", g.history.Len())
+	} else {
+		msg += "\nNo snapshot to roll back to."
+	}
+	drawText(screen, msg, 4, 4)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"n-body/render"
+)
+
+// newRenderer returns the renderer named name for running without a window:
+// terminal or null.
+func newRenderer(name string) (render.Renderer, error) {
+	switch name {
+	case "terminal":
+		return render.NewTerminal(os.Stdout), nil
+	case "null":
+		return &render.Null{}, nil
+	}
+	return nil, fmt.Errorf("unknown renderer %q (want window, terminal or null)", name)
+}
+
+// runHeadless runs the game without a window until interrupted or the
+// simulation fails, stepping it with the wall clock as the window would
+// and handing r a frame fps times a second. title names what is simulated.
+func (g *Game) runHeadless(r render.Renderer, title string, fps float64) (err error) {
+	defer func() {
+		if cerr := r.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / fps))
+	defer ticker.Stop()
+	frame := render.Frame{Title: title}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := g.advance(); err != nil {
+			return err
+		}
+		if g.fault != nil {
+			return g.fault
+		}
+		frame.Time, frame.Width, frame.Height, frame.Center = g.sim.Time, g.sim.Width, g.sim.Height, g.sim.Center()
+		frame.Bodies = g.groups.filter(append(frame.Bodies[:0], g.bodies...))
+		if err := r.Render(&frame); err != nil {
+			return err
+		}
+		g.shots.captureFrame(&frame)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+const (
+	// heatmapCell is the side in pixels of the squares the potential is
+	// sampled on, before heatmapMaxPairs makes them coarser.
+	heatmapCell = 8
+	// heatmapMaxPairs bounds the sample-body pairs summed per frame, so the
+	// heatmap stays interactive however many bodies there are.
+	heatmapMaxPairs = 4e6
+)
+
+// heatmapColors is the color map from the shallowest potential on screen
+// to the deepest, dark to bright.
+var heatmapColors = colormaps["inferno"]
+
+// heatmapBrightness dims the map so bodies stay visible on top of it.
+const heatmapBrightness = 0.6
+
+// contourLevels is the number of equipotentials drawn.
+const contourLevels = 16
+
+// heatmap is the gravitational potential sampled on a coarse grid over a
+// viewport, shaded logarithmically so wells and the saddle points between
+// them both show. In a rotating frame it is the effective potential, which
+// adds the centrifugal term so the Lagrange points are its saddles and
+// peaks.
+type heatmap struct {
+	cell       int       // sample spacing in pixels
+	cols, rows int       // samples across and down
+	depths     []float64 // depth of the potential per unit mass, row by row
+	lo, hi     float64   // range of the finite depths
+	pixels     []byte
+	img        *ebiten.Image
+}
+
+// sample computes the potential at the middle of each cell of vp from
+// bodies at positions in vp's frame. center is the camera center.
+func (h *heatmap) sample(vp *Viewport, bodies []nbody.Body, positions []nbody.Vector2D, center nbody.Vector2D) {
+	w, ht := vp.Bounds.Dx(), vp.Bounds.Dy()
+	h.cell = heatmapCell
+	if pairs := float64(w*ht) / heatmapCell / heatmapCell * float64(len(bodies)); pairs > heatmapMaxPairs {
+		h.cell = int(math.Ceil(heatmapCell * math.Sqrt(pairs/heatmapMaxPairs)))
+	}
+	h.cols, h.rows = (w+h.cell-1)/h.cell, (ht+h.cell-1)/h.cell
+	h.depths = slices.Grow(h.depths[:0], h.cols*h.rows)[:h.cols*h.rows]
+	h.lo, h.hi = math.Inf(1), math.Inf(-1)
+
+	zoom, spin, axis := vp.Camera.Zoom, vp.Frame.spin, vp.Frame.center
+	for j := range h.rows {
+		y := center.Y + (float64(j*h.cell)+float64(h.cell)/2-float64(ht)/2)/zoom
+		for i := range h.cols {
+			x := center.X + (float64(i*h.cell)+float64(h.cell)/2-float64(w)/2)/zoom
+			var phi float64
+			for k, b := range bodies {
+				r := math.Hypot(positions[k].X-x, positions[k].Y-y)
+				phi += nbody.PairPotential(r, nbody.G*b.Mass)
+			}
+			if spin != 0 {
+				dx, dy := x-axis.X, y-axis.Y
+				phi -= spin * spin * (dx*dx + dy*dy) / 2
+			}
+			d := depth(phi)
+			h.depths[j*h.cols+i] = d
+			if !math.IsInf(d, -1) {
+				h.lo, h.hi = math.Min(h.lo, d), math.Max(h.hi, d)
+			}
+		}
+	}
+}
+
+// draw shades the sampled potential over vp.
+func (h *heatmap) draw(dst *ebiten.Image, vp *Viewport) {
+	if !(h.hi > h.lo) {
+		return
+	}
+	if h.img == nil || h.img.Bounds().Dx() != h.cols || h.img.Bounds().Dy() != h.rows {
+		if h.img != nil {
+			h.img.Deallocate()
+		}
+		h.img = ebiten.NewImage(h.cols, h.rows)
+	}
+	h.pixels = slices.Grow(h.pixels[:0], 4*len(h.depths))[:4*len(h.depths)]
+	for i, d := range h.depths {
+		r, g, b := heatmapColor((d - h.lo) / (h.hi - h.lo))
+		h.pixels[4*i], h.pixels[4*i+1], h.pixels[4*i+2], h.pixels[4*i+3] = r, g, b, 255
+	}
+	h.img.WritePixels(h.pixels)
+
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(float64(h.cell), float64(h.cell))
+	op.GeoM.Translate(float64(vp.Bounds.Min.X), float64(vp.Bounds.Min.Y))
+	dst.DrawImage(h.img, op)
+}
+
+// contours queues lines along contourLevels equipotentials, evenly spaced
+// in depth between the shallowest and deepest samples, on lines. It traces
+// them by marching squares over the cells between sample points.
+func (h *heatmap) contours(dst *ebiten.Image, lines *lineBatch, vp *Viewport) {
+	if !(h.hi > h.lo) {
+		return
+	}
+	step := (h.hi - h.lo) / (contourLevels + 1)
+	at := func(i, j int) nbody.Vector2D {
+		return nbody.Vector2D{
+			X: float64(vp.Bounds.Min.X) + (float64(i)+0.5)*float64(h.cell),
+			Y: float64(vp.Bounds.Min.Y) + (float64(j)+0.5)*float64(h.cell),
+		}
+	}
+	var crossings [4]nbody.Vector2D
+	for j := range h.rows - 1 {
+		for i := range h.cols - 1 {
+			// Corners clockwise from the top left; edge k runs from
+			// corner k to corner k+1.
+			corners := [4]nbody.Vector2D{at(i, j), at(i+1, j), at(i+1, j+1), at(i, j+1)}
+			d := [4]float64{h.depths[j*h.cols+i], h.depths[j*h.cols+i+1], h.depths[(j+1)*h.cols+i+1], h.depths[(j+1)*h.cols+i]}
+			if slices.ContainsFunc(d[:], func(v float64) bool { return math.IsInf(v, -1) }) {
+				continue
+			}
+			lo, hi := slices.Min(d[:]), slices.Max(d[:])
+			for level := h.lo + step*math.Ceil((lo-h.lo)/step); level < hi; level += step {
+				n := 0
+				for k := range 4 {
+					a, b := d[k], d[(k+1)%4]
+					if (a < level) == (b < level) {
+						continue
+					}
+					t := (level - a) / (b - a)
+					p, q := corners[k], corners[(k+1)%4]
+					crossings[n] = nbody.Vector2D{X: p.X + t*(q.X-p.X), Y: p.Y + t*(q.Y-p.Y)}
+					n++
+				}
+				switch n {
+				case 2:
+					lines.add(dst, crossings[0], crossings[1], theme.Contour)
+				case 4:
+					// A saddle: the middle decides which opposite
+					// corners the contour separates.
+					if mid := (d[0] + d[1] + d[2] + d[3]) / 4; (mid < level) == (d[0] < level) {
+						lines.add(dst, crossings[0], crossings[1], theme.Contour)
+						lines.add(dst, crossings[2], crossings[3], theme.Contour)
+					} else {
+						lines.add(dst, crossings[3], crossings[0], theme.Contour)
+						lines.add(dst, crossings[1], crossings[2], theme.Contour)
+					}
+				}
+			}
+		}
+	}
+	lines.flush(dst)
+}
+
+// depth is the log of the depth of a potential well, so each factor of ten
+// gets the same share of the color map.
+func depth(phi float64) float64 {
+	return math.Log10(-phi)
+}
+
+// heatmapColor is the dimmed color of heatmapColors at t in [0, 1].
+func heatmapColor(t float64) (r, g, b byte) {
+	c := heatmapColors.at(t)
+	return byte(c[0] * heatmapBrightness), byte(c[1] * heatmapBrightness), byte(c[2] * heatmapBrightness)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// uiScale is the number of screen pixels per device-independent pixel: the
+// device scale factor of the monitor the window is on. The screen is laid
+// out in screen pixels, so circles and lines are drawn at the display's
+// full resolution, and sizes meant for the eye are multiplied by uiScale.
+var uiScale = 1.0
+
+// textScale is the whole number of screen pixels each pixel of the debug
+// font is drawn as. A whole number keeps the bitmap glyphs sharp.
+var textScale = 1
+
+// Metrics of ebitenutil's debug font, in its own pixels.
+const (
+	debugFontWidth  = 6
+	debugFontHeight = 16
+)
+
+// debugCharWidth and debugLineHeight are the metrics of text drawn by
+// drawText, in screen pixels.
+var (
+	debugCharWidth  = debugFontWidth
+	debugLineHeight = debugFontHeight
+)
+
+// setUIScale changes uiScale to s, with the text metrics to match, and
+// returns the factor by which screen lengths grew.
+func setUIScale(s float64) float64 {
+	if !(s > 0) {
+		s = 1
+	}
+	factor := s / uiScale
+	uiScale = s
+	textScale = max(1, int(math.Round(s)))
+	debugCharWidth, debugLineHeight = debugFontWidth*textScale, debugFontHeight*textScale
+	return factor
+}
+
+// deviceScale returns the device scale factor of the window's monitor.
+func deviceScale() float64 {
+	if m := ebiten.Monitor(); m != nil {
+		return m.DeviceScaleFactor()
+	}
+	return 1
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// drawHillSpheres outlines the Hill sphere of each body bound to a heavier
+// one in vp. center is the camera center.
+func (g *Game) drawHillSpheres(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	for i, p := range g.subsystems.primaries {
+		if p < 0 {
+			continue
+		}
+		r := nbody.HillRadius(g.drawBodies[i], g.drawBodies[p]) * vp.Camera.Zoom
+		s := vp.toScreen(g.view(g.framePositions[i]), center)
+		vector.StrokeCircle(dst, float32(s.X), float32(s.Y), float32(r), 1, theme.Hill, true)
+	}
+}
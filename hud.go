@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// hudInterval is how often, in wall-clock seconds, the HUD's rates are
+// measured afresh.
+const hudInterval = 0.5
+
+// hud shows how fast the simulation is running in the top left corner:
+// frames drawn and physics steps taken per second, the simulated time and
+// how much faster than the wall clock it passes, and the number of bodies.
+// Given an epoch, it shows the simulated date too.
+type hud struct {
+	on    bool
+	epoch time.Time // calendar time at simulated time zero, or zero for none
+
+	since    time.Time // start of the current measurement
+	steps    int       // sim.Steps at since
+	simTime  float64   // sim.Time at since
+	stepRate float64   // physics steps per wall-clock second
+	warp     float64   // simulated seconds per wall-clock second
+	timeWarp float64   // asked for with [ and ], as a multiple of normal speed
+}
+
+// measure takes the simulation's step count and time at now, updating the
+// rates every hudInterval.
+func (h *hud) measure(now time.Time, steps int, simTime float64) {
+	elapsed := now.Sub(h.since).Seconds()
+	if h.since.IsZero() || steps < h.steps {
+		// Just started, or another simulation was swapped in.
+		h.since, h.steps, h.simTime = now, steps, simTime
+		return
+	}
+	if elapsed < hudInterval {
+		return
+	}
+	h.stepRate = float64(steps-h.steps) / elapsed
+	h.warp = (simTime - h.simTime) / elapsed
+	h.since, h.steps, h.simTime = now, steps, simTime
+}
+
+// lines returns the HUD's text for simulated time t and n bodies.
+func (h *hud) lines(t float64, n int) []string {
+	speed := fmt.Sprintf("t = %s  (%sx)", formatDuration(t), formatWarp(h.warp))
+	if h.timeWarp != 1 {
+		speed += "  warp x" + formatWarp(h.timeWarp)
+	}
+	lines := []string{
+		fmt.Sprintf("FPS %.0f  steps/s %.0f", ebiten.ActualFPS(), h.stepRate),
+		speed,
+	}
+	if !h.epoch.IsZero() {
+		lines = append(lines, calendarTime(h.epoch, t).Format("2006-01-02 15:04:05 MST"))
+	}
+	return append(lines, fmt.Sprintf("%d bodies", n))
+}
+
+// calendarTime returns the calendar time t simulated seconds after epoch.
+// Whole days are added separately, since a time.Duration spans only a few
+// centuries.
+func calendarTime(epoch time.Time, t float64) time.Time {
+	days := math.Floor(t / 86400)
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration((t - days*86400) * 1e9))
+}
+
+// draw shows the HUD for simulated time t and n bodies and returns the
+// height it took.
+func (h *hud) draw(screen *ebiten.Image, t float64, n int) int {
+	if !h.on {
+		return 0
+	}
+	lines := h.lines(t, n)
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line)*debugCharWidth)
+	}
+	x, y := 4, debugLineHeight+4
+	height := len(lines)*debugLineHeight + 4
+	vector.DrawFilledRect(screen, float32(x-2), float32(y-2), float32(width+4), float32(height), theme.Panel, false)
+	for _, line := range lines {
+		drawText(screen, line, x, y)
+		y += debugLineHeight
+	}
+	return height
+}
+
+// formatDuration formats a simulated time in seconds as days, hours,
+// minutes and seconds, or in years from a year up.
+func formatDuration(t float64) string {
+	const day, year = 86400, 365.25 * 86400
+	sign := ""
+	if t < 0 {
+		sign, t = "-", -t
+	}
+	if t >= year {
+		return fmt.Sprintf("%s%.2f yr", sign, t/year)
+	}
+	s := int64(t)
+	d := s / day
+	s -= d * day
+	clock := fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+	if d > 0 {
+		return fmt.Sprintf("%s%dd %s", sign, d, clock)
+	}
+	return sign + clock
+}
+
+// formatWarp formats a time warp factor with three significant digits.
+func formatWarp(w float64) string {
+	if w != 0 && (math.Abs(w) >= 1e4 || math.Abs(w) < 1e-2) {
+		return fmt.Sprintf("%.2e", w)
+	}
+	return fmt.Sprintf("%.3g", w)
+}
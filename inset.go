@@ -0,0 +1,54 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+const (
+	insetWidth  = 200
+	insetHeight = 150
+	// insetZoom is the inset's magnification over the first viewport.
+	insetZoom = 10
+)
+
+// insetBounds returns where the picture-in-picture view goes: the bottom
+// left corner of the screen, above the frame label.
+func (g *Game) insetBounds() image.Rectangle {
+	bottom := g.height - debugLineHeight - 4
+	w, h := int(insetWidth*uiScale), int(insetHeight*uiScale)
+	return image.Rect(4, bottom-h, 4+w, bottom)
+}
+
+// showInset reports whether the picture-in-picture view is shown.
+func (g *Game) showInset() bool {
+	return g.insetOn && g.selected != nbody.NoBody
+}
+
+// aimInset points the picture-in-picture view at the selected body, in a
+// frame centered on it, starting afresh when the selection changes.
+func (g *Game) aimInset() {
+	vp := &g.inset
+	if vp.Frame.Kind == FrameBodyCentered && vp.Frame.Body == g.selected {
+		return
+	}
+	*vp = Viewport{Bounds: g.insetBounds(), Camera: defaultCamera(g.sim.Center()), inset: true}
+	vp.lockOn(g.selected)
+}
+
+// drawInset draws the picture-in-picture view over the screen.
+func (g *Game) drawInset(screen *ebiten.Image, st *renderState) {
+	vp := &g.inset
+	if vp.Frame.Body != g.selected {
+		return // not aimed yet
+	}
+	vp.Bounds = g.insetBounds()
+	vp.Camera.Zoom = g.viewports[0].Camera.Zoom * insetZoom
+	dst := screen.SubImage(vp.Bounds).(*ebiten.Image)
+	dst.Fill(theme.Background)
+	g.drawViewport(dst, vp, st)
+	drawViewportBorder(screen, vp)
+}
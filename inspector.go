@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// inspectorMaxLines is the most lines inspectorLines returns.
+const inspectorMaxLines = 11
+
+// inspectorTop is where the inspector starts down the screen, below where
+// the minimap goes.
+func inspectorTop() int {
+	return debugLineHeight + 4 + int(minimapSize*uiScale) + 8
+}
+
+// inspectorLines describes the i-th of bodies: what it is, where it is
+// going and, if something holds it, its orbit around that.
+func inspectorLines(bodies []nbody.Body, i int) []string {
+	b := bodies[i]
+	lines := []string{
+		fmt.Sprintf("%s (#%d)", bodyName(b), b.ID),
+		fmt.Sprintf("mass      %.4g kg", b.Mass),
+		fmt.Sprintf("position  (%.1f, %.1f)", b.Position.X, b.Position.Y),
+		fmt.Sprintf("velocity  (%.3g, %.3g)", b.Velocity.X, b.Velocity.Y),
+		fmt.Sprintf("speed     %s/s", formatDistance(math.Hypot(b.Velocity.X, b.Velocity.Y))),
+	}
+	p, ok := nbody.DominantBody(bodies, i)
+	if !ok {
+		return lines
+	}
+	el := nbody.OsculatingElements(b, bodies[p])
+	lines = append(lines,
+		fmt.Sprintf("primary   %s, %s away", bodyName(bodies[p]), formatDistance(el.Distance)),
+		fmt.Sprintf("e         %.4f", el.Eccentricity),
+	)
+	if !el.Bound {
+		return append(lines, "unbound", fmt.Sprintf("periapsis %s", formatDistance(el.Periapsis)))
+	}
+	return append(lines,
+		fmt.Sprintf("a         %s", formatDistance(el.SemiMajorAxis)),
+		fmt.Sprintf("periapsis %s", formatDistance(el.Periapsis)),
+		fmt.Sprintf("apoapsis  %s", formatDistance(el.Apoapsis)),
+		fmt.Sprintf("period    %s", formatDuration(el.Period)),
+	)
+}
+
+// drawInspector shows the selected body's details, live, in the top right
+// corner below where the minimap goes.
+func (g *Game) drawInspector(screen *ebiten.Image) {
+	i := slices.IndexFunc(g.drawBodies, func(b nbody.Body) bool { return b.ID == g.selected })
+	if i < 0 {
+		return
+	}
+	lines := inspectorLines(g.drawBodies, i)
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line)*debugCharWidth)
+	}
+	x := screen.Bounds().Dx() - width - 6
+	y := inspectorTop()
+	vector.DrawFilledRect(screen, float32(x-2), float32(y-2), float32(width+4), float32(len(lines)*debugLineHeight+4), theme.Panel, false)
+	for _, line := range lines {
+		drawText(screen, line, x, y)
+		y += debugLineHeight
+	}
+}
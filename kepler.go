@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// keplerSegments is the number of lines a ghost orbit is drawn with.
+const keplerSegments = 128
+
+// drawKeplerOrbit draws the two-body orbit the selected body would follow
+// around its dominant body if nothing else pulled on it, as a ghost to
+// compare its real motion against. center is the camera center.
+func (g *Game) drawKeplerOrbit(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	i := slices.IndexFunc(g.drawBodies, func(b nbody.Body) bool { return b.ID == g.selected })
+	if i < 0 {
+		return
+	}
+	p, ok := nbody.DominantBody(g.drawBodies, i)
+	if !ok {
+		return
+	}
+	el := nbody.OsculatingElements(g.drawBodies[i], g.drawBodies[p])
+	if el.Distance == 0 || math.IsNaN(el.Eccentricity) {
+		return
+	}
+	// A whole ellipse, or the near part of a hyperbola's branch, which
+	// reaches infinity at ±acos(-1/e).
+	lo, hi := 0.0, 2*math.Pi
+	if !el.Bound {
+		limit := 0.95 * math.Acos(-1/el.Eccentricity)
+		lo, hi = -limit, limit
+	}
+	origin := g.drawBodies[p].Position
+	at := func(k int) nbody.Vector2D {
+		q := el.PositionAt(lo + (hi-lo)*float64(k)/keplerSegments)
+		w := nbody.Vector2D{X: origin.X + q.X, Y: origin.Y + q.Y}
+		return vp.toScreen(g.view(vp.Frame.apply(w)), center)
+	}
+	prev := at(0)
+	for k := 1; k <= keplerSegments; k++ {
+		next := at(k)
+		g.lines.add(dst, prev, next, theme.Kepler)
+		prev = next
+	}
+	g.lines.flush(dst)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// modifier is a key held down with another to change what it does.
+type modifier int
+
+const (
+	noModifier modifier = iota
+	shift
+	ctrl
+)
+
+// held reports whether m is held down; noModifier always is.
+func (m modifier) held() bool {
+	switch m {
+	case shift:
+		return ebiten.IsKeyPressed(ebiten.KeyShift)
+	case ctrl:
+		return ebiten.IsKeyPressed(ebiten.KeyControl)
+	}
+	return true
+}
+
+// binding is what pressing a key does.
+type binding struct {
+	key  ebiten.Key
+	mod  modifier
+	name string        // how help shows the key, if not by keyName
+	help string        // what it does, or "" to leave it out of help
+	do   func(g *Game) // nil for keys handled elsewhere
+}
+
+// keymap is every key the game handles, in the order help lists them.
+// Where a key does one thing alone and another with a modifier, the
+// binding with the modifier comes first and takes the key while it is
+// held.
+var keymap = slices.Concat(
+	[]binding{
+		{key: ebiten.KeyF1, help: "show or hide this help", do: func(g *Game) { g.helpOn = !g.helpOn }},
+		{key: ebiten.KeySlash, mod: shift, name: "?", help: "show or hide this help", do: func(g *Game) { g.helpOn = !g.helpOn }},
+
+		{key: ebiten.KeySpace, help: "pause or resume", do: (*Game).togglePause},
+		{key: ebiten.KeyPeriod, help: "step once while paused", do: func(g *Game) {
+			if g.paused {
+				g.stepPaused()
+			}
+		}},
+		{key: ebiten.KeyComma, help: "run time backwards or forwards", do: func(g *Game) { g.sim.Reverse = !g.sim.Reverse }},
+		{key: ebiten.KeyBracketRight, help: "speed time up x2 (Shift x10, Ctrl x100)", do: func(g *Game) { g.clock.scale(warpStep()) }},
+		{key: ebiten.KeyBracketLeft, help: "slow time down x2 (Shift x10, Ctrl x100)", do: func(g *Game) { g.clock.scale(1 / warpStep()) }},
+		{key: ebiten.KeyBackslash, help: "reset the speed of time", do: func(g *Game) { g.clock.warp = 1 }},
+		{key: ebiten.KeyB, help: "roll back after the simulation blows up", do: func(g *Game) {
+			if g.fault != nil {
+				g.rollback()
+			}
+		}},
+
+		{key: ebiten.KeySlash, help: "find a body by name and jump to it", do: func(g *Game) { g.search.open() }},
+		{key: ebiten.KeyTab, help: "select the next body", do: func(g *Game) { g.selected = nbody.NextBodyID(g.bodies, g.selected) }},
+		{key: ebiten.KeyD, mod: shift, help: "pick the next kind of body to spawn", do: func(g *Game) {
+			g.spawner.preset = (g.spawner.preset + 1) % len(spawnPresets)
+		}},
+		{key: ebiten.KeyD, help: "spawn bodies with the mouse", do: func(g *Game) { g.spawner.on = !g.spawner.on }},
+		{key: ebiten.KeyDelete, help: "remove the selected body", do: func(g *Game) {
+			if i, ok := g.sim.IndexOf(g.selected); ok {
+				g.removeBody(i)
+			}
+		}},
+		{key: ebiten.KeyF4, help: "edit the selected body", do: func(g *Game) { g.editor.on = !g.editor.on }},
+		{key: ebiten.KeyZ, mod: ctrl, help: "undo the last edit", do: (*Game).undo},
+		{key: ebiten.KeyY, mod: ctrl, help: "redo the last edit undone", do: (*Game).redo},
+		{key: ebiten.KeyE, help: "export the selected body's data sheet", do: (*Game).exportDataSheet},
+
+		{key: ebiten.KeyHome, help: "reset the camera", do: func(g *Game) {
+			g.activeViewport().Camera = defaultCamera(g.sim.Center())
+		}},
+		{key: ebiten.KeyF, help: "follow the next body", do: func(g *Game) {
+			vp := g.activeViewport()
+			vp.Camera.Follow = nbody.NextBodyID(g.bodies, vp.Camera.Follow)
+		}},
+		{key: ebiten.KeyL, help: "lock onto the selected body", do: func(g *Game) {
+			if g.selected != nbody.NoBody {
+				g.activeViewport().lockOn(g.selected)
+			}
+		}},
+		{key: ebiten.KeyR, help: "cycle the reference frame", do: func(g *Game) { g.activeViewport().cycleFrame(g.bodies) }},
+		{key: ebiten.KeyEqual, help: "zoom in", do: func(g *Game) { g.activeViewport().Camera.Zoom *= zoomStep }},
+		{key: ebiten.KeyMinus, help: "zoom out", do: func(g *Game) { g.activeViewport().Camera.Zoom /= zoomStep }},
+		{key: ebiten.KeyP, help: "power zoom", do: func(g *Game) { g.powerZoomOn = !g.powerZoomOn }},
+		{key: ebiten.KeyV, help: "cycle the number of viewports", do: func(g *Game) {
+			g.setViewportCount(len(g.viewports)%maxViewport + 1)
+		}},
+		{key: ebiten.KeyQ, help: "magnified inset of the selected body", do: func(g *Game) { g.insetOn = !g.insetOn }},
+		{key: ebiten.KeyS, mod: shift, help: "play the camera path", do: func(g *Game) { g.path.toggle(&g.viewports[0].Camera) }},
+		{key: ebiten.KeyS, help: "add a camera path keyframe", do: func(g *Game) { g.path.add(g.sim.Time, g.viewports[0].Camera) }},
+
+		{key: ebiten.KeyT, help: "orbit trails", do: (*Game).toggleTrails},
+		{key: ebiten.KeyN, help: "labels", do: func(g *Game) { g.labelsOn = !g.labelsOn }},
+		{key: ebiten.KeyW, help: "cycle what colors show", do: func(g *Game) { g.colors.mode = (g.colors.mode + 1) % colorModes }},
+		{key: ebiten.KeyZ, help: "true or logarithmic radii", do: func(g *Game) { g.sizes.toggle() }},
+		{key: ebiten.KeyO, help: "grid", do: func(g *Game) { g.gridOn = !g.gridOn }},
+		{key: ebiten.KeyH, help: "potential heatmap", do: func(g *Game) { g.heatOn = !g.heatOn }},
+		{key: ebiten.KeyC, help: "potential contours", do: func(g *Game) { g.contours = !g.contours }},
+		{key: ebiten.KeyA, help: "gravity field arrows", do: func(g *Game) { g.fieldOn = !g.fieldOn }},
+		{key: ebiten.KeyY, help: "long exposure", do: (*Game).toggleExposure},
+		{key: ebiten.KeyM, mod: ctrl, help: "measure distances with the mouse", do: func(g *Game) { g.measurer.on = !g.measurer.on }},
+		{key: ebiten.KeyM, help: "barycenters", do: func(g *Game) { g.barycentersOn = !g.barycentersOn }},
+		{key: ebiten.KeyI, help: "Hill spheres", do: func(g *Game) { g.hillOn = !g.hillOn }},
+		{key: ebiten.KeyU, help: "Roche limits", do: func(g *Game) { g.rocheOn = !g.rocheOn }},
+		{key: ebiten.KeyJ, help: "Lagrange points", do: func(g *Game) { g.lagrangeOn = !g.lagrangeOn }},
+		{key: ebiten.KeyK, help: "Kepler's second law", do: func(g *Game) { g.keplerOn = !g.keplerOn }},
+		{key: ebiten.KeyX, help: "eclipses", do: func(g *Game) { g.eclipses.on = !g.eclipses.on }},
+	},
+	digitBindings(ctrl, "Ctrl+1-9", "bookmark the camera", (*Game).bookmarkCamera),
+	digitBindings(shift, "Shift+1-9", "recall a camera bookmark", (*Game).recallCamera),
+	digitBindings(noModifier, "1-9", "show or hide a group", func(g *Game, n int) { g.groups.toggle(n) }),
+	[]binding{
+		{key: ebiten.KeyF2, help: "worksheet, given -worksheet"}, // handled by the worksheet
+		{key: ebiten.KeyF3, help: "HUD", do: func(g *Game) { g.hud.on = !g.hud.on }},
+		{key: ebiten.KeyG, help: "quality governor", do: func(g *Game) { g.quality.toggle() }},
+		{key: ebiten.KeyF9, help: "start or stop recording", do: (*Game).toggleRecording},
+		{key: ebiten.KeyF12, help: "screenshot", do: func(g *Game) { g.shots.pending = true }},
+		{key: ebiten.KeyF11, help: "fullscreen", do: func(*Game) { ebiten.SetFullscreen(!ebiten.IsFullscreen()) }},
+	},
+)
+
+// digitBindings binds 1 to 9 with mod to do the n-th of something,
+// counting from 0, listed in help as one binding called name.
+func digitBindings(mod modifier, name, help string, do func(g *Game, n int)) []binding {
+	bs := make([]binding, 9)
+	for n := range bs {
+		bs[n] = binding{key: ebiten.KeyDigit1 + ebiten.Key(n), mod: mod, do: func(g *Game) { do(g, n) }}
+	}
+	bs[0].name, bs[0].help = name, help
+	return bs
+}
+
+// mouseHelp says what the mouse does, after the keymap in help.
+var mouseHelp = []string{
+	"drag    pan; while paused, drag a body to move it",
+	"wheel   zoom",
+	"click   select a body (Shift pairs it with the selected one), or measure",
+	"right   remove a body",
+	"touch   tap to select, drag with one or two fingers to pan, pinch to zoom",
+}
+
+// keyNames are the names help gives keys other than by ebiten.Key.String.
+var keyNames = map[ebiten.Key]string{
+	ebiten.KeyPeriod:       ".",
+	ebiten.KeyComma:        ",",
+	ebiten.KeyBracketLeft:  "[",
+	ebiten.KeyBracketRight: "]",
+	ebiten.KeyBackslash:    "\\",
+	ebiten.KeyEqual:        "=",
+	ebiten.KeyMinus:        "-",
+	ebiten.KeySlash:        "/",
+}
+
+// keyName is how help shows b's key.
+func (b *binding) keyName() string {
+	if b.name != "" {
+		return b.name
+	}
+	name, ok := keyNames[b.key]
+	if !ok {
+		name = b.key.String()
+	}
+	switch b.mod {
+	case shift:
+		name = "Shift+" + name
+	case ctrl:
+		name = "Ctrl+" + name
+	}
+	return name
+}
+
+// handleKeys does what the keys pressed this tick do.
+func (g *Game) handleKeys() {
+	var taken []ebiten.Key
+	for i := range keymap {
+		b := &keymap[i]
+		if b.do == nil || !inpututil.IsKeyJustPressed(b.key) || !b.mod.held() || slices.Contains(taken, b.key) {
+			continue
+		}
+		taken = append(taken, b.key)
+		b.do(g)
+	}
+}
+
+// helpLines lists the keymap, a line per binding with help.
+func helpLines() []string {
+	width := 0
+	for i := range keymap {
+		width = max(width, len(keymap[i].keyName()))
+	}
+	var lines []string
+	for i := range keymap {
+		if b := &keymap[i]; b.help != "" {
+			lines = append(lines, fmt.Sprintf("%-*s  %s", width, b.keyName(), b.help))
+		}
+	}
+	return lines
+}
+
+// drawHelp lists the keymap over the middle of the screen, in as many
+// columns as it takes to fit.
+func (g *Game) drawHelp(screen *ebiten.Image) {
+	if !g.helpOn {
+		return
+	}
+	lines := slices.Concat(helpLines(), []string{""}, mouseHelp, []string{""}, gamepadHelp())
+	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+	rows := max((h-4*debugLineHeight)/debugLineHeight, 1)
+	cols := (len(lines) + rows - 1) / rows
+	rows = (len(lines) + cols - 1) / cols
+	colWidth := 0
+	for _, line := range lines {
+		colWidth = max(colWidth, len(line)*debugCharWidth)
+	}
+	colWidth += 2 * debugCharWidth
+	x, y := (w-cols*colWidth)/2, (h-rows*debugLineHeight)/2
+	vector.DrawFilledRect(screen, float32(x-8), float32(y-8), float32(cols*colWidth+16), float32(rows*debugLineHeight+16), theme.Panel, false)
+	for i, line := range lines {
+		drawText(screen, strings.TrimRight(line, " "), x+i/rows*colWidth, y+i%rows*debugLineHeight)
+	}
+}
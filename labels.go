@@ -0,0 +1,82 @@
+package main
+
+import (
+	"cmp"
+	"image"
+	"slices"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// maxLabels caps the labels considered per viewport, so systems of
+// thousands of bodies only label their heaviest.
+const maxLabels = 200
+
+// labeler places body labels in a viewport without letting them overlap:
+// heavier bodies are labelled first, and a label that would cover one
+// already placed is left out. Zoomed out, only the most massive bodies of
+// a crowded region keep their labels.
+type labeler struct {
+	order  []int // body indices, heaviest first
+	ids    []int // the IDs order was sorted for
+	placed []image.Rectangle
+}
+
+// draw labels the bodies of vp, whose screen positions are at and whose
+// radii on screen sizes decides. Bodies are labelled by name, or by ID when
+// they have none.
+func (l *labeler) draw(dst *ebiten.Image, vp *Viewport, bodies []nbody.Body, sizes *sizer, at func(i int) nbody.Vector2D) {
+	l.sort(bodies)
+
+	l.placed = l.placed[:0]
+	considered := 0
+	for _, i := range l.order {
+		if considered == maxLabels {
+			break
+		}
+		b := bodies[i]
+		p := at(i)
+		r := int(sizes.radius(b, vp.Camera.Zoom)) + 2
+		pt := image.Pt(int(p.X)+r, int(p.Y)-debugLineHeight/2)
+		if !pt.In(vp.Bounds) {
+			continue
+		}
+		considered++
+		text := bodyName(b)
+		rect := image.Rect(pt.X, pt.Y, pt.X+len(text)*debugCharWidth, pt.Y+debugLineHeight)
+		if slices.ContainsFunc(l.placed, rect.Overlaps) {
+			continue
+		}
+		l.placed = append(l.placed, rect)
+		drawText(dst, text, pt.X, pt.Y)
+	}
+}
+
+// sort orders the bodies by mass, unless they are the same bodies as last
+// time: masses only change when bodies merge, which changes the set too.
+func (l *labeler) sort(bodies []nbody.Body) {
+	same := len(l.ids) == len(bodies)
+	for i := 0; same && i < len(bodies); i++ {
+		same = bodies[i].ID == l.ids[i]
+	}
+	if same {
+		return
+	}
+	l.order, l.ids = l.order[:0], l.ids[:0]
+	for i, b := range bodies {
+		l.order = append(l.order, i)
+		l.ids = append(l.ids, b.ID)
+	}
+	slices.SortFunc(l.order, func(a, b int) int { return cmp.Compare(bodies[b].Mass, bodies[a].Mass) })
+}
+
+// bodyName returns b's name, or its ID when it has none.
+func bodyName(b nbody.Body) string {
+	if b.Name == "" {
+		return "#" + strconv.Itoa(b.ID)
+	}
+	return b.Name
+}
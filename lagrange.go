@@ -0,0 +1,47 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// lagrangePair returns the indices in the bodies being drawn of the pair
+// whose Lagrange points are shown: the selected body and the body picked
+// with a shift-click, or, failing that, the body dominating the selected
+// one's motion.
+func (g *Game) lagrangePair() (int, int, bool) {
+	i := slices.IndexFunc(g.drawBodies, func(b nbody.Body) bool { return b.ID == g.selected })
+	if i < 0 {
+		return 0, 0, false
+	}
+	if j := slices.IndexFunc(g.drawBodies, func(b nbody.Body) bool { return b.ID == g.partner }); j >= 0 && j != i {
+		return i, j, true
+	}
+	j, ok := nbody.DominantBody(g.drawBodies, i)
+	return i, j, ok
+}
+
+// drawLagrangePoints marks L1 to L5 of the selected pair in vp. center is
+// the camera center.
+func (g *Game) drawLagrangePoints(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	i, j, ok := g.lagrangePair()
+	if !ok {
+		return
+	}
+	points, ok := nbody.LagrangePoints(g.drawBodies[i], g.drawBodies[j])
+	if !ok {
+		return
+	}
+	for k, p := range points {
+		s := vp.toScreen(g.view(vp.Frame.apply(p)), center)
+		x, y := float32(s.X), float32(s.Y)
+		vector.StrokeLine(dst, x-4, y-4, x+4, y+4, 1, theme.Lagrange, true)
+		vector.StrokeLine(dst, x-4, y+4, x+4, y-4, 1, theme.Lagrange, true)
+		drawText(dst, "L"+strconv.Itoa(k+1), int(x)+5, int(y)-debugLineHeight)
+	}
+}
@@ -1,190 +1,855 @@
 package main
 
 import (
-	"image/color"
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"log"
 	"math"
+	"net/http"
+	_ "net/http/pprof"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+	"n-body/render"
+	"n-body/scenario"
+	"n-body/server"
 )
 
+// Initial window size. The screen is laid out at the window's size, and
+// follows it when the window is resized.
 const (
 	screenWidth  = 1000
 	screenHeight = 800
-	G            = 6.67430e-11 // gravitational constant
-	timeStep     = 1.0 / 60    // simulation time step
-	scaleFactor  = 1e-9        // scale factor to make the simulation visible
-	orbitScale   = 1e-9        // scale down the orbit sizes to fit on screen
-	speedScale   = 300000
 )
 
-type Vector2D struct {
-	X, Y float64
+type Game struct {
+	sim     *nbody.Simulation
+	bodies  []nbody.Body  // snapshot of the simulation's bodies, refreshed every step
+	state   renderState   // the bodies after the last steps, as the physics side sees them
+	states  *stateBuffer  // hands state to Draw
+	solver  string        // name of the simulation's gravity solver
+	solvers solverOptions // for switching to another
+
+	clock    clock // paces physics steps against the wall clock
+	paused   bool
+	helpOn   bool
+	stepping int // physics steps asked for while paused
+
+	history  *nbody.History // recent snapshots to roll back to
+	timeline timeline       // scrubs through the rewind buffer while paused
+	fault    error          // why the simulation stopped, or nil
+
+	width, height int // screen size, as laid out
+	viewports     []Viewport
+	inset         Viewport // magnified view of the selected body
+	insetOn       bool
+	selected      int // ID of the selected body, or nbody.NoBody
+	partner       int // ID of the body paired with it for Lagrange points, or nbody.NoBody
+
+	dragging int         // index of the viewport being panned, or -1
+	dragFrom image.Point // cursor position the drag last moved from
+	dragged  bool        // whether the button moved since it was pressed
+	touch    touchGesture
+
+	powerZoomOn bool
+	powerZoom   powerZoom
+
+	worksheet *worksheetMode // nil unless a worksheet was loaded
+	server    *server.Server // nil unless -http was given
+	recorder  *recorder      // nil unless recording
+	recording recordings
+	shots     screenshots
+
+	annotations []scenario.Annotation
+
+	render  renderMode
+	colors  colorizer
+	sizes   sizer
+	circles circleBatch
+	points  pointBatch
+	lines   lineBatch
+	glows   glowBatch
+	tails   tailBatch
+	shades  shadeBatch
+
+	textures textures
+	stars    *starfield // nil for a plain black background
+	sprites  []int      // indices of the textured bodies in the viewport being drawn
+	discs    []int      // indices of the bodies drawn as discs or sprites, not points
+
+	trailsOn    bool
+	trailLength int     // positions kept per trail
+	apsides     apsides // marked on the trails
+
+	labelsOn bool
+	labels   labeler
+	gridOn   bool
+	heatOn   bool // potential heatmap behind the bodies
+	exposeOn bool // long exposure of where bodies have been, behind the bodies
+	contours bool // equipotential lines behind the bodies
+	fieldOn  bool // gravity arrows behind the bodies
+	field    vectorField
+
+	barycentersOn bool
+	hillOn        bool
+	rocheOn       bool
+	subsystems    subsystems // found while barycenters, Hill spheres or Roche limits are shown
+	lagrangeOn    bool
+	keplerOn      bool
+
+	groups groups // of bodies, hidden and shown together
+
+	predictor predictor // previews the selected body's path
+	spawner   spawner   // adds bodies with the mouse
+	measurer  measurer  // measures distances with the mouse
+	search    search    // finds bodies by name
+	moving    bodyDrag  // moves bodies with the mouse while paused
+	editor    propertyEditor
+	controls  controls   // on-screen buttons and sliders
+	edits     *editLog   // changes made to the simulation by hand
+	path      cameraPath // keyframed camera move of the first viewport
+	bookmarks bookmarks  // cameras saved to come back to
+	eclipses  eclipses   // logged and highlighted while on
+
+	quality governor
+	hud     hud
+
+	renderLabels context.Context // pprof labels for Draw, nil unless -pprof was given
+
+	renderer render.Renderer // draws each frame; window unless another was set
+	window   windowRenderer
+	frame    render.Frame // handed to renderer
+
+	drawBodies     []nbody.Body     // bodies interpolated to the moment being drawn
+	framePositions []nbody.Vector2D // body positions in the viewport being drawn
 }
 
-type Body struct {
-	Position Vector2D
-	Velocity Vector2D
-	Mass     float64
-	Radius   float64
-	Color    color.Color
+func (g *Game) Update() error {
+	defer func(start time.Time) { g.quality.work(time.Since(start)) }(time.Now())
+	if g.worksheet != nil && g.worksheet.update(g.bodies) {
+		return g.advance()
+	}
+	if !g.search.update(g) {
+		g.handleKeys()
+	}
+	g.updateGamepads()
+	if !g.updateTouches() && !g.updateControls() && !g.updateEditor() && !g.updateTimeline() && !g.updateSpawner() && !g.updateMeasurer() && !g.updateBodyDrag() {
+		g.mouseCamera()
+	}
+	g.updateRemoval()
+	return g.advance()
 }
 
-type Simulation struct {
-	Bodies []Body
+// advance takes as many physics steps as the wall-clock time since the last
+// tick calls for, so simulated time runs at the same speed however fast
+// frames are rendered. While paused, it takes only the steps asked for.
+func (g *Game) advance() error {
+	g.quality.apply(g.sim)
+	g.clock.dt = g.sim.StepSize()
+	now := time.Now()
+	steps := g.stepping
+	if g.paused {
+		g.clock.hold(now)
+		g.stepping = 0
+		if g.server != nil && g.server.Stale() {
+			g.server.Publish(g.sim.Time, g.sim.AppendBodies)
+		}
+	} else {
+		steps = g.clock.tick(now)
+	}
+	start := time.Now()
+	for range steps {
+		if g.fault != nil {
+			break
+		}
+		if err := g.step(); err != nil {
+			return err
+		}
+	}
+	g.clock.measure(steps, time.Since(start))
+	g.hud.measure(now, g.sim.Steps, g.sim.Time)
+	g.hud.timeWarp = g.clock.speed()
+	return nil
 }
 
-func NewSimulation() *Simulation {
-	return &Simulation{
-		Bodies: make([]Body, 0),
+// step advances the simulation and everything that tracks it by one
+// physics step.
+func (g *Game) step() error {
+	if g.server != nil {
+		if sim := g.server.Apply(g.sim); sim != g.sim {
+			// A client loaded another scenario: start the views afresh.
+			g.sim, g.selected, g.partner = sim, nbody.NoBody, nbody.NoBody
+			sc := g.server.Scenario()
+			g.annotations, g.recording.annotations = sc.Annotations, sc.Annotations
+			g.hud.epoch = time.Time{}
+			if sc.Epoch != "" {
+				epoch, err := scenario.ParseEpoch(sc.Epoch)
+				if err != nil {
+					return err
+				}
+				g.hud.epoch = epoch
+			}
+			g.edits.forget()
+			g.history.Clear()
+			if g.timeline.history != nil {
+				g.timeline.history.Clear()
+			}
+			g.timeline.current = -1
+			n := len(g.viewports)
+			g.viewports = nil
+			g.setViewportCount(n)
+		}
 	}
+	g.sim.Update()
+	if g.checkFinite() {
+		g.refresh()
+		g.predictor.start(g.sim, g.selected)
+	}
+	return nil
 }
 
-func (s *Simulation) AddBody(b Body) {
-	s.Bodies = append(s.Bodies, b)
+// refresh snapshots the simulation's bodies and updates everything derived
+// from them.
+func (g *Game) refresh() {
+	g.bodies = g.sim.AppendBodies(g.bodies[:0])
+	g.state.advance(g.sim, g.bodies)
+	g.states.publish(&g.state)
+	if g.server != nil {
+		g.server.Publish(g.sim.Time, g.sim.AppendBodies)
+	}
+	g.eclipses.update(g.bodies, g.sim.Time, g.hud.epoch)
+	g.groups.update(g.bodies)
+	var apsides []apsisEvent
+	if g.trailsOn {
+		apsides = g.apsides.update(g.bodies)
+	}
+	for i := range g.viewports {
+		g.track(&g.viewports[i], apsides)
+	}
+	if g.showInset() {
+		g.aimInset()
+		g.track(&g.inset, apsides)
+	}
 }
 
-func (s *Simulation) Update() {
-	for i := range s.Bodies {
-		force := Vector2D{}
-		for j := range s.Bodies {
-			if i != j {
-				force = addVectors(force, calculateGravitationalForce(&s.Bodies[i], &s.Bodies[j]))
-			}
+// resync snapshots the simulation's bodies after an edit made between
+// steps, or a jump to another state, so Draw shows them at once, without
+// interpolating from the bodies before or extending trails or anything
+// else that follows the bodies from step to step. Frames and cameras
+// still move with the bodies they follow.
+func (g *Game) resync() {
+	g.bodies = g.sim.AppendBodies(g.bodies[:0])
+	g.state.reset(g.sim, g.bodies)
+	g.states.publish(&g.state)
+	if g.server != nil {
+		g.server.Publish(g.sim.Time, g.sim.AppendBodies)
+	}
+	g.groups.update(g.bodies)
+	for i := range g.viewports {
+		vp := &g.viewports[i]
+		vp.Frame.prepare(g.bodies, g.sim.Center())
+		vp.Camera.follow(g.bodies, &vp.Frame)
+	}
+}
+
+// track moves vp's frame and camera along with the bodies and extends its
+// trails.
+func (g *Game) track(vp *Viewport, apsides []apsisEvent) {
+	vp.Frame.prepare(g.bodies, g.sim.Center())
+	vp.Camera.follow(g.bodies, &vp.Frame)
+	if g.exposeOn {
+		vp.visits.record(g.bodies, &vp.Frame, g.sim.Width, g.sim.Height)
+	}
+	if g.trailsOn {
+		vp.trails.record(g.bodies, &vp.Frame, g.trailLength)
+		for _, e := range apsides {
+			vp.trails.mark(e, &vp.Frame)
 		}
-		acceleration := scaleVector(force, 1/s.Bodies[i].Mass)
-		s.Bodies[i].Velocity = addVectors(s.Bodies[i].Velocity, scaleVector(acceleration, timeStep))
-		s.Bodies[i].Position = addVectors(s.Bodies[i].Position, scaleVector(s.Bodies[i].Velocity, timeStep))
+	}
+}
 
-		// Keep bodies within the screen
-		s.Bodies[i].Position.X = math.Mod(s.Bodies[i].Position.X+screenWidth, screenWidth)
-		s.Bodies[i].Position.Y = math.Mod(s.Bodies[i].Position.Y+screenHeight, screenHeight)
+func (g *Game) Draw(screen *ebiten.Image) {
+	start := time.Now()
+	if g.renderLabels != nil {
+		pprof.SetGoroutineLabels(g.renderLabels)
+		defer pprof.SetGoroutineLabels(context.Background())
 	}
+	st := g.states.read()
+	g.drawBodies = interpolateBodies(g.drawBodies[:0], st.prev, st.cur, g.clock.alpha(), st.width, st.height)
+	g.frame.Time = st.time - (1-g.clock.alpha())*st.dt // the moment drawn
+	g.frame.Width, g.frame.Height, g.frame.Center = st.width, st.height, st.center()
+	g.frame.Bodies = g.drawBodies
+	g.window.screen, g.window.state = screen, st
+	if err := g.renderer.Render(&g.frame); err != nil && g.fault == nil {
+		g.fault = err
+	}
+	g.window.screen, g.window.state = nil, nil
+	g.quality.work(time.Since(start))
+	g.quality.frame()
 }
 
-func calculateGravitationalForce(b1, b2 *Body) Vector2D {
-	dx := b2.Position.X - b1.Position.X
-	dy := b2.Position.Y - b1.Position.Y
-	distSq := dx*dx + dy*dy
-	dist := math.Sqrt(distSq)
+// drawFrame draws f, interpolated from st, onto screen as the game window
+// shows it.
+func (g *Game) drawFrame(screen *ebiten.Image, st *renderState, f *render.Frame) {
+	t := f.Time
+	g.drawBodies = f.Bodies
+	screen.Fill(theme.Background)
+	if g.path.playing {
+		g.path.apply(&g.viewports[0].Camera, t)
+	}
+	g.colors.apply(g.drawBodies, st)
+	g.drawBodies = g.groups.filter(g.drawBodies)
+	g.sizes.fit(g.drawBodies)
+	if g.barycentersOn || g.hillOn || g.rocheOn {
+		g.subsystems.find(g.drawBodies)
+	}
+	for i := range g.viewports {
+		vp := &g.viewports[i]
+		g.drawViewport(screen.SubImage(vp.Bounds).(*ebiten.Image), vp, st)
+		if len(g.viewports) > 1 {
+			drawViewportBorder(screen, vp)
+		}
+	}
+	if g.showInset() {
+		g.drawInset(screen, st)
+	}
+	if g.worksheet != nil {
+		g.worksheet.draw(screen)
+	}
+	top := g.hud.draw(screen, t, len(g.drawBodies))
+	g.colors.drawLegend(screen, top)
+	g.drawInspector(screen)
+	g.drawEditor(screen)
+	g.drawControls(screen)
+	g.drawSpawnPalette(screen)
+	g.groups.draw(screen)
+	drawAnnotations(screen, g.annotations, t)
+	if g.recorder != nil && g.recorder.capture(screen, t) {
+		g.toggleRecording()
+	}
+	g.quality.draw(screen)
+	g.drawStatus(screen)
+	g.drawTimeline(screen)
+	g.drawFault(screen)
+	g.drawTooltip(screen)
+	g.search.draw(screen)
+	g.drawHelp(screen)
+	g.shots.capture(screen, t)
+}
 
-	// Softening factor to prevent extreme forces at small distances
-	softening := 1e7
-	force := G * b1.Mass * b2.Mass / (distSq + softening*softening)
+// warpStep is the factor ] and [ change the time warp by: 2, or 10 with
+// Shift, or 100 with Ctrl.
+func warpStep() float64 {
+	switch {
+	case ebiten.IsKeyPressed(ebiten.KeyControl):
+		return 100
+	case ebiten.IsKeyPressed(ebiten.KeyShift):
+		return 10
+	}
+	return 2
+}
 
-	return Vector2D{
-		X: force * dx / dist * scaleFactor,
-		Y: force * dy / dist * scaleFactor,
+// drawStatus says at the top of the screen whether the simulation is
+// paused, warped or running backwards, and whether bodies are being spawned
+// or distances measured.
+func (g *Game) drawStatus(screen *ebiten.Image) {
+	var msg string
+	if w := g.clock.speed(); w != 1 {
+		msg = "time x" + formatWarp(w) + " ([ and ] change, \\ resets)"
+	}
+	if g.spawner.on {
+		msg = strings.TrimSpace("spawning " + strings.ToLower(spawnPresets[g.spawner.preset].name) + "s: press to place, drag to aim, release to launch (Shift+D picks another, D stops)  " + msg)
+	}
+	if g.measurer.on {
+		msg = strings.TrimSpace("measuring: click two bodies or points (Ctrl+M stops)  " + msg)
+	}
+	if g.sim.Reverse {
+		msg = strings.TrimSpace("running backwards (, reverses)  " + msg)
+	}
+	if g.paused {
+		msg = "paused (Space resumes, . steps)"
+	}
+	if msg != "" {
+		drawText(screen, msg, (screen.Bounds().Dx()-len(msg)*debugCharWidth)/2, 4)
 	}
 }
 
-func addVectors(v1, v2 Vector2D) Vector2D {
-	return Vector2D{X: v1.X + v2.X, Y: v1.Y + v2.Y}
+// view applies the active view transform (currently only power zoom) to a
+// world position.
+func (g *Game) view(p nbody.Vector2D) nbody.Vector2D {
+	if g.powerZoomOn {
+		return g.powerZoom.apply(p)
+	}
+	return p
 }
 
-func scaleVector(v Vector2D, scalar float64) Vector2D {
-	return Vector2D{X: v.X * scalar, Y: v.Y * scalar}
+func (g *Game) drawViewport(dst *ebiten.Image, vp *Viewport, st *renderState) {
+	g.framePositions = g.framePositions[:0]
+	for _, body := range g.drawBodies {
+		g.framePositions = append(g.framePositions, vp.Frame.apply(body.Position))
+	}
+	if g.powerZoomOn {
+		g.powerZoom.update(g.framePositions, st.center(), math.Min(st.width, st.height)/2)
+	}
+
+	center := g.view(vp.Camera.Center)
+	if g.stars != nil {
+		g.stars.draw(dst, &g.points, vp)
+	}
+	if (g.heatOn || g.contours) && !g.powerZoomOn {
+		vp.heat.sample(vp, g.drawBodies, g.framePositions, center)
+		if g.heatOn {
+			vp.heat.draw(dst, vp)
+		}
+		if g.contours {
+			vp.heat.contours(dst, &g.lines, vp)
+		}
+	}
+	if g.exposeOn && !g.powerZoomOn {
+		vp.visits.draw(dst, vp, center)
+	}
+	if g.powerZoomOn {
+		g.powerZoom.drawScale(dst, vp, center)
+	} else if g.gridOn {
+		drawGrid(dst, &g.lines, vp, center)
+	}
+	if g.fieldOn && !g.powerZoomOn {
+		g.field.draw(dst, &g.lines, vp, g.drawBodies, g.framePositions, center)
+	}
+	if g.trailsOn {
+		g.drawTrails(dst, vp, center, st)
+	}
+	g.drawPrediction(dst, vp, center)
+	g.drawSpawn(dst, vp, center)
+	g.drawMeasurement(dst, vp, center)
+	if g.keplerOn {
+		g.drawKeplerOrbit(dst, vp, center)
+	}
+	g.drawGlows(dst, vp, center)
+	g.drawCometTails(dst, vp, center)
+	points := g.render.points(len(g.drawBodies)) || g.quality.level >= qualityPoints
+	bounds := vp.Bounds
+	selected := -1
+	g.sprites = g.sprites[:0]
+	g.discs = g.discs[:0]
+	for i, body := range g.drawBodies {
+		pos := vp.toScreen(g.view(g.framePositions[i]), center)
+		radius := g.sizes.radius(body, vp.Camera.Zoom)
+		if body.ID == g.selected {
+			selected = i
+		}
+		// Skip bodies entirely outside the viewport, so drawing costs
+		// what is visible rather than what exists.
+		r := math.Max(radius, minPointRadius*uiScale)
+		if pos.X+r < float64(bounds.Min.X) || pos.X-r > float64(bounds.Max.X) ||
+			pos.Y+r < float64(bounds.Min.Y) || pos.Y-r > float64(bounds.Max.Y) {
+			continue
+		}
+		switch {
+		case points || radius < pointRadius:
+			g.points.add(dst, pos.X, pos.Y, radius, body.Color)
+		case body.Texture != "" && g.textures.get(body.Texture) != nil:
+			g.sprites = append(g.sprites, i)
+			g.discs = append(g.discs, i)
+		default:
+			g.circles.add(dst, pos.X, pos.Y, radius, body.Color)
+			g.discs = append(g.discs, i)
+		}
+	}
+	g.circles.flush(dst)
+	g.points.flush(dst)
+	for _, i := range g.sprites {
+		body := g.drawBodies[i]
+		pos := vp.toScreen(g.view(g.framePositions[i]), center)
+		drawSprite(dst, g.textures.get(body.Texture), pos, g.sizes.radius(body, vp.Camera.Zoom))
+	}
+	g.drawNightSides(dst, vp, center)
+	if g.hillOn {
+		g.drawHillSpheres(dst, vp, center)
+	}
+	if g.rocheOn {
+		g.drawRocheLimits(dst, vp, center)
+	}
+	if g.barycentersOn {
+		g.drawBarycenters(dst, vp, center)
+	}
+	if g.lagrangeOn {
+		g.drawLagrangePoints(dst, vp, center)
+	}
+	if g.eclipses.on {
+		g.drawEclipses(dst, vp, center)
+	}
+	if selected >= 0 {
+		pos := vp.toScreen(g.view(g.framePositions[selected]), center)
+		radius := g.sizes.radius(g.drawBodies[selected], vp.Camera.Zoom)
+		vector.StrokeCircle(dst, float32(pos.X), float32(pos.Y), float32(radius+4), 1, theme.Selection, true)
+	}
+	if g.labelsOn {
+		g.labels.draw(dst, vp, g.drawBodies, &g.sizes, func(i int) nbody.Vector2D {
+			return vp.toScreen(g.view(g.framePositions[i]), center)
+		})
+	}
+	if !g.powerZoomOn {
+		drawScaleBar(dst, vp)
+		g.drawMinimap(dst, vp, st, center)
+	}
+	if vp.Frame.Kind != FrameInertial {
+		drawText(dst, vp.Frame.Kind.String()+" frame", vp.Bounds.Min.X+4, vp.Bounds.Max.Y-16)
+	}
 }
 
-type Game struct {
-	sim *Simulation
+// exportDataSheet writes the selected body's data sheet to the working
+// directory as JSON and Markdown.
+func (g *Game) exportDataSheet() {
+	i := slices.IndexFunc(g.bodies, func(b nbody.Body) bool { return b.ID == g.selected })
+	if i < 0 {
+		return
+	}
+	base := fmt.Sprintf("body-%d-t%.0f", g.selected, g.sim.Time)
+	ds := newDataSheet(g.bodies, i, g.sim.Time)
+	ds.Hidden = !g.groups.visible(g.bodies[i])
+	if err := ds.Export(base); err != nil {
+		log.Printf("exporting data sheet: %v", err)
+		return
+	}
+	log.Printf("data sheet written to %s.json and %s.md", base, base)
 }
 
-func (g *Game) Update() error {
-	g.sim.Update()
-	return nil
+// Layout makes the screen as large as the window in the display's own
+// pixels, re-splitting it between the viewports when that changes. Cameras
+// zoom by the change in the device scale factor, so the world looks the
+// same size on any display. While recording, the screen keeps its size so
+// every frame of the video has the same.
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	if g.recorder != nil {
+		return g.width, g.height
+	}
+	if s := deviceScale(); s != uiScale {
+		factor := setUIScale(s)
+		for i := range g.viewports {
+			g.viewports[i].Camera.Zoom *= factor
+		}
+	}
+	w, h := int(math.Ceil(float64(outsideWidth)*uiScale)), int(math.Ceil(float64(outsideHeight)*uiScale))
+	if (w != g.width || h != g.height) && w > 0 && h > 0 {
+		g.width, g.height = w, h
+		g.setViewportCount(len(g.viewports))
+	}
+	return g.width, g.height
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
-	for _, body := range g.sim.Bodies {
-		ebitenutil.DrawCircle(screen, body.Position.X, body.Position.Y, body.Radius, body.Color)
+// loadScenario returns the built-in scenario called name, or else loads name
+// as a scenario file.
+func loadScenario(name string) (*scenario.Scenario, error) {
+	if sc, ok := scenario.Builtin(name); ok {
+		return sc, nil
 	}
+	return scenario.Load(name)
 }
 
-func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return 800, 600
+// parseCharges parses the -charge flag: comma-separated name=charge pairs.
+func parseCharges(s string) (map[string]float64, error) {
+	charges := make(map[string]float64)
+	if s == "" {
+		return charges, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("-charge: %q is not name=charge", pair)
+		}
+		q, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(q) || math.IsInf(q, 0) {
+			return nil, fmt.Errorf("-charge: invalid charge %q for %s", v, name)
+		}
+		charges[name] = q
+	}
+	return charges, nil
+}
+
+// setCharges gives the bodies of sim named in charges their charge, logging
+// names no body has.
+func setCharges(sim *nbody.Simulation, charges map[string]float64) {
+	found := make(map[string]bool)
+	for i := range sim.Len() {
+		b := sim.Body(i)
+		if q, ok := charges[b.Name]; ok {
+			b.Charge = q
+			sim.SetBody(i, b)
+			found[b.Name] = true
+		}
+	}
+	for name := range charges {
+		if !found[name] {
+			log.Printf("-charge: no body named %q", name)
+		}
+	}
 }
 
 func main() {
-	sim := NewSimulation()
-
-	sun := Body{
-		Position: Vector2D{X: screenWidth / 2, Y: screenHeight / 2},
-		Velocity: Vector2D{X: 0, Y: 0},
-		Mass:     1.989e30, // Mass of the Sun in kg
-		Radius:   20,
-		Color:    color.RGBA{255, 255, 0, 255},
-	}
-	sim.AddBody(sun)
-
-	// Venus
-	venusOrbitRadius := 108.2e9 * orbitScale         // 108.2 million km
-	venusSpeed := 35.02e3 * speedScale * scaleFactor // 35.02 km/s
-	venus := Body{
-		Position: Vector2D{X: screenWidth/2 + venusOrbitRadius, Y: screenHeight / 2},
-		Velocity: Vector2D{X: 0, Y: -venusSpeed},
-		Mass:     4.867e24, // Mass of Venus in kg
-		Radius:   4,
-		Color:    color.RGBA{255, 198, 73, 255}, // Light orange
-	}
-	sim.AddBody(venus)
-
-	// Earth
-	earthOrbitRadius := 149.6e9 * orbitScale         // 149.6 million km
-	earthSpeed := 29.78e3 * speedScale * scaleFactor // 29.78 km/s
-	earth := Body{
-		Position: Vector2D{X: screenWidth/2 + earthOrbitRadius, Y: screenHeight / 2},
-		Velocity: Vector2D{X: 0, Y: -earthSpeed},
-		Mass:     5.972e24, // Mass of the Earth in kg
-		Radius:   5,
-		Color:    color.RGBA{0, 0, 255, 255},
-	}
-	sim.AddBody(earth)
-
-	// Earth's Moon
-	moonOrbitRadius := 384400e3 * orbitScale                                              // 384,400 km
-	moonSpeed := (1.022e3 + earthSpeed/scaleFactor/speedScale) * speedScale * scaleFactor // 1.022 km/s + Earth's speed
-	moon := Body{
-		Position: Vector2D{X: earth.Position.X + moonOrbitRadius, Y: earth.Position.Y},
-		Velocity: Vector2D{X: 0, Y: -moonSpeed},
-		Mass:     7.34767309e22, // Mass of the Moon in kg
-		Radius:   2,
-		Color:    color.RGBA{200, 200, 200, 255}, // Light grey
-	}
-	sim.AddBody(moon)
-
-	// Mars
-	marsOrbitRadius := 227.9e9 * orbitScale          // 227.9 million km
-	marsSpeed := 24.077e3 * speedScale * scaleFactor // 24.077 km/s
-	mars := Body{
-		Position: Vector2D{X: screenWidth/2 + marsOrbitRadius, Y: screenHeight / 2},
-		Velocity: Vector2D{X: 0, Y: -marsSpeed},
-		Mass:     6.39e23, // Mass of Mars in kg
-		Radius:   4,
-		Color:    color.RGBA{255, 0, 0, 255},
-	}
-	sim.AddBody(mars)
-
-	// Jupiter
-	jupiterOrbitRadius := 778.5e9 * orbitScale         // 778.5 million km
-	jupiterSpeed := 13.07e3 * speedScale * scaleFactor // 13.07 km/s
-	jupiter := Body{
-		Position: Vector2D{X: screenWidth/2 + jupiterOrbitRadius, Y: screenHeight / 2},
-		Velocity: Vector2D{X: 0, Y: -jupiterSpeed},
-		Mass:     1.898e27, // Mass of Jupiter in kg
-		Radius:   15,
-		Color:    color.RGBA{255, 140, 0, 255}, // Dark orange
-	}
-	sim.AddBody(jupiter)
-
-	game := &Game{
-		sim: sim,
+	var o options
+	flag.BoolVar(&o.mond, "mond", false, "use MOND modified gravity instead of pure Newtonian gravity")
+	flag.Float64Var(&o.a0, "mond-a0", nbody.DefaultMONDA0, "MOND acceleration scale a0 in m/s²")
+	flag.StringVar(&o.charge, "charge", "", "charges in coulombs for bodies by name, overriding the scenario's: comma-separated name=charge pairs, e.g. Earth=1e15,Moon=-1e15")
+	flag.BoolVar(&o.merge, "merge", false, "merge bodies that collide")
+	flag.StringVar(&o.mergerTree, "merger-tree", "", "write the merger tree to this file on exit (.dot/.gv for Graphviz, JSON otherwise)")
+	flag.StringVar(&o.solver, "solver", "direct", "gravity solver: direct, fmm, pm or gpu")
+	flag.IntVar(&o.fmmOrder, "fmm-order", 2, "FMM expansion order (0-2)")
+	flag.Float64Var(&o.fmmTheta, "fmm-theta", 0.5, "FMM opening angle; smaller is more accurate")
+	flag.IntVar(&o.pmGrid, "pm-grid", 128, "particle-mesh grid nodes per axis (rounded up to a power of two)")
+	flag.Float64Var(&o.dt, "dt", nbody.TimeStep, "simulated seconds per physics step; overrides the scenario's")
+	flag.IntVar(&o.tps, "tps", 0, "game ticks per second (0 follows the display refresh rate); physics keeps pace with the wall clock regardless")
+	flag.IntVar(&o.substeps, "substeps", 1, "physics steps per tick; more are more accurate without changing the speed of time")
+	flag.IntVar(&o.split, "viewports", 1, "number of viewports to split the screen into, each with its own camera (V cycles 1-4)")
+	flag.Int64Var(&o.stars, "stars", 1, "seed of the procedural starfield background (0 for plain black)")
+	flag.StringVar(&o.render, "render", "auto", "how bodies are drawn: circles, points (batched sprites, for large N) or auto")
+	flag.StringVar(&o.colorBy, "color-by", "body", "what body colors show: body, speed, mass or acceleration (W cycles)")
+	flag.StringVar(&o.cmap, "colormap", "viridis", "colormap for -color-by: inferno, viridis, plasma, cool or gray")
+	flag.StringVar(&o.radii, "radii", "true", "how big bodies are drawn: true (to scale) or log (every body visible, larger ones larger; Z toggles)")
+	flag.BoolVar(&o.showHUD, "hud", false, "show frame rate, physics rate, simulated time and body count (F3 toggles)")
+	flag.StringVar(&o.themeName, "theme", "dark", "color theme: dark, light, high-contrast or a JSON theme file")
+	flag.BoolVar(&o.labels, "labels", true, "label bodies with their names (N toggles)")
+	flag.IntVar(&o.predict, "predict", 3000, "physics steps to look ahead when previewing the selected body's path (0 turns previews off)")
+	flag.IntVar(&o.trailLength, "trail", 240, "positions kept per body for orbit trails (T toggles them)")
+	flag.BoolVar(&o.govern, "governor", true, "lower visual, then physics, quality while frames take too long (G toggles)")
+	flag.IntVar(&o.historySize, "history", 10, "snapshots kept to roll back to if the simulation blows up (B rolls back)")
+	flag.IntVar(&o.historyEvery, "history-every", 60, "physics steps between -history snapshots")
+	flag.IntVar(&o.rewindMB, "rewind-mb", 256, "memory in MiB for snapshots to rewind to with the timeline shown while paused (0 turns rewinding off)")
+	flag.IntVar(&o.rewindEvery, "rewind-every", 10, "physics steps between rewind snapshots")
+	flag.IntVar(&o.autoSubsteps, "auto-substeps", 16, "raise -substeps up to this many if the scenario's fastest orbit needs them (0 only warns)")
+	flag.BoolVar(&o.single, "float32", false, "sum gravity in single precision (faster for very large N)")
+	flag.StringVar(&o.worksheet, "worksheet", "", "load a classroom worksheet from this JSON file (F2 toggles it)")
+	flag.StringVar(&o.scenarioName, "scenario", "", "built-in scenario name or path to a scenario JSON file (by default, chosen from a menu in the window, or solar-system without one)")
+	flag.StringVar(&o.scenarioDir, "scenario-dir", "scenarios", "directory of scenario JSON files the start menu offers besides the built-in scenarios")
+	flag.StringVar(&o.httpAddr, "http", "", "serve the simulation state over HTTP on this address (e.g. :8080)")
+	flag.StringVar(&o.readToken, "http-read-token", "", "token granting read-only access to the HTTP API")
+	flag.StringVar(&o.controlToken, "http-control-token", "", "token granting control of the simulation through the HTTP API")
+	flag.BoolVar(&o.public, "http-public", false, "let clients without a token read the state when tokens are set")
+	flag.StringVar(&o.pprofAddr, "pprof", "", "serve net/http/pprof on this address (e.g. localhost:6060) and label profiles by simulation phase")
+	flag.StringVar(&o.editLogFile, "edit-log", "", "write changes made by hand, spawning (D), moving, removing (right-click or Delete) and editing (F4) bodies and undoing them (Ctrl+Z, Ctrl+Y redoes), to this file, one JSON object per line")
+	flag.StringVar(&o.bookmarksFile, "bookmarks", "", "camera bookmarks: Ctrl+1-9 saves one, Shift+1-9 recalls it; loaded from and saved to this file")
+	flag.StringVar(&o.cameraPathFile, "camera-path", "", "camera keyframes for the first viewport: S records one, Shift+S plays them back; saved here on exit")
+	flag.StringVar(&o.record, "record", "", "record from the start: rendered frames and an ffmpeg manifest into this directory, or an animated GIF if it ends in .gif")
+	flag.Float64Var(&o.recordFPS, "record-fps", 30, "frame rate of recordings, in frames per simulated second")
+	flag.IntVar(&o.recordEvery, "record-every", 1, "keep only every Nth drawn frame in recordings")
+	flag.Float64Var(&o.recordFor, "record-for", 0, "stop recordings after this many simulated seconds (0 runs until F9 or exit)")
+	flag.StringVar(&o.shotDir, "screenshot-dir", ".", "directory F12 saves screenshots into, and F9 recordings, in the format of -record")
+	flag.Float64Var(&o.shotEvery, "screenshot-every", 0, "also save a screenshot every this many simulated seconds (0 never), with or without a window")
+	flag.StringVar(&o.renderer, "renderer", "window", "where to draw: window, terminal (braille characters, for SSH) or null (nothing)")
+	flag.Float64Var(&o.headlessFPS, "renderer-fps", 20, "frames per second drawn by -renderer terminal or null")
+	flag.BoolVar(&o.soak, "soak", false, "run headless without a window, logging memory statistics, to look for leaks")
+	flag.DurationVar(&o.soakFor, "soak-for", 0, "how long -soak runs (0 runs until interrupted)")
+	flag.DurationVar(&o.soakEvery, "soak-every", time.Minute, "interval between -soak reports")
+	flag.Parse()
+
+	var err error
+	if theme, err = loadTheme(o.themeName); err != nil {
+		log.Fatal(err)
+	}
+	if o.charges, err = parseCharges(o.charge); err != nil {
+		log.Fatal(err)
+	}
+
+	if o.pprofAddr != "" {
+		nbody.SetProfileLabels(true)
+		go func() {
+			log.Fatal(http.ListenAndServe(o.pprofAddr, nil))
+		}()
+	}
+
+	if o.scenarioName == "" && (o.soak || o.renderer != "window") {
+		o.scenarioName = "solar-system"
+	}
+	var game *Game
+	title := "N-Body Simulation"
+	if o.scenarioName != "" {
+		sc, err := loadScenario(o.scenarioName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if game, err = newGame(sc, &o); err != nil {
+			log.Fatal(err)
+		}
+		title = sc.Name
+	}
+
+	if o.soak {
+		if err := game.soak(o.soakFor, o.soakEvery); err != nil {
+			log.Fatal(err)
+		}
+		saveMergers(game.sim, o.mergerTree)
+		return
+	}
+
+	if o.renderer != "window" {
+		r, err := newRenderer(o.renderer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := game.runHeadless(r, title, o.headlessFPS); err != nil {
+			log.Fatal(err)
+		}
+		saveMergers(game.sim, o.mergerTree)
+		return
 	}
 
+	// Physics is paced by the game's clock, so by default ticks can follow
+	// the display.
+	if o.tps > 0 {
+		ebiten.SetTPS(o.tps)
+	} else {
+		ebiten.SetTPS(ebiten.SyncWithFPS)
+	}
 	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Solar System Simulation")
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+
+	if game != nil {
+		ebiten.SetWindowTitle("N-Body Simulation: " + title)
+		if err := ebiten.RunGame(game); err != nil {
+			panic(err)
+		}
+	} else {
+		ebiten.SetWindowTitle(title)
+		menu := newStartMenu(o.scenarioDir, func(sc *scenario.Scenario) (*Game, error) {
+			return newGame(sc, &o)
+		})
+		if err := ebiten.RunGame(menu); err != nil {
+			panic(err)
+		}
+		if game = menu.game; game == nil {
+			return
+		}
+	}
+
+	if game.recorder != nil {
+		game.recording.stop(game.recorder)
+	}
+	game.recording.closing.Wait()
+	if err := game.edits.Close(); err != nil {
+		log.Print(err)
+	}
+	if o.bookmarksFile != "" {
+		if err := game.bookmarks.save(o.bookmarksFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if o.cameraPathFile != "" {
+		if err := game.path.save(o.cameraPathFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	saveMergers(game.sim, o.mergerTree)
+}
+
+// solverNames are the gravity solvers to choose from.
+var solverNames = []string{"direct", "fmm", "pm", "gpu"}
+
+// solverOptions configure the gravity solvers that take any.
+type solverOptions struct {
+	fmmOrder int
+	fmmTheta float64
+	pmGrid   int
+}
+
+// newSolver returns the gravity solver called name, or nil for the direct
+// sum.
+func newSolver(name string, opts solverOptions) (nbody.GravitySolver, error) {
+	switch name {
+	case "direct":
+		return nil, nil
+	case "fmm":
+		return nbody.NewFMM(opts.fmmOrder, opts.fmmTheta), nil
+	case "pm":
+		return nbody.NewPM(opts.pmGrid), nil
+	case "gpu":
+		gpu, err := newGPUSolver()
+		if err != nil {
+			return nil, err
+		}
+		return gpu, nil
+	}
+	return nil, fmt.Errorf("unknown solver %q", name)
+}
 
-	if err := ebiten.RunGame(game); err != nil {
-		panic(err)
+// setSolver switches the simulation to the gravity solver called name.
+func (g *Game) setSolver(name string) {
+	solver, err := newSolver(name, g.solvers)
+	if err != nil {
+		log.Printf("solver: %v", err)
+		return
+	}
+	g.sim.Solver, g.solver = solver, name
+	log.Printf("gravity solver: %s", name)
+}
+
+// togglePause pauses or resumes the simulation.
+func (g *Game) togglePause() {
+	g.paused = !g.paused
+	if !g.paused {
+		g.resumeTimeline()
+	}
+}
+
+// stepPaused advances the paused simulation by one step.
+func (g *Game) stepPaused() {
+	g.resumeTimeline()
+	g.stepping++
+}
+
+// toggleTrails turns orbit trails on or off, starting them afresh.
+func (g *Game) toggleTrails() {
+	g.trailsOn = !g.trailsOn
+	g.resetTrails()
+}
+
+// resetTrails clears every viewport's trails.
+func (g *Game) resetTrails() {
+	for i := range g.viewports {
+		g.viewports[i].trails.reset()
+	}
+	g.inset.trails.reset()
+	clear(g.apsides.byID)
+}
+
+// toggleExposure turns long exposure on or off, starting it afresh.
+func (g *Game) toggleExposure() {
+	g.exposeOn = !g.exposeOn
+	for i := range g.viewports {
+		g.viewports[i].visits.reset()
+	}
+	g.inset.visits.reset()
+}
+
+// toggleRecording stops the recording under way, or starts a new one.
+func (g *Game) toggleRecording() {
+	if g.recorder != nil {
+		g.recording.stop(g.recorder)
+		g.recorder = nil
+		return
+	}
+	r, err := g.recording.start("")
+	if err != nil {
+		log.Printf("recording: %v", err)
+		return
+	}
+	g.recorder = r
+}
+
+// saveMergers writes the merger tree, if one was recorded, to path.
+func saveMergers(sim *nbody.Simulation, path string) {
+	if sim.Mergers == nil {
+		return
+	}
+	if err := sim.Mergers.Save(path); err != nil {
+		log.Fatal(err)
 	}
 }
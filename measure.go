@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// speedOfLight in metres per second.
+const speedOfLight = 299792458
+
+// measureEnd is one end of a measurement: a body, or a fixed point in the
+// world if id is nbody.NoBody.
+type measureEnd struct {
+	id int
+	p  nbody.Vector2D
+}
+
+// measurer shows the distance between two bodies or points, and the time
+// light takes to cross it, while on. Clicks place the ends in turn; a third
+// starts a new measurement.
+type measurer struct {
+	on   bool
+	ends [2]measureEnd
+	n    int // ends placed
+}
+
+// updateMeasurer places ends where clicked while measuring, and reports
+// whether it took the mouse.
+func (g *Game) updateMeasurer() bool {
+	m := &g.measurer
+	if !m.on {
+		m.n = 0
+		return false
+	}
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return false
+	}
+	cursor := image.Pt(ebiten.CursorPosition())
+	vp := &g.viewports[g.viewportAt(cursor)]
+	end := measureEnd{id: g.bodyAt(vp, cursor)}
+	if end.id == nbody.NoBody {
+		end.p = vp.Frame.invert(vp.toFrame(cursor, vp.Camera.Center))
+	}
+	if m.n == len(m.ends) {
+		m.n = 0
+	}
+	m.ends[m.n] = end
+	m.n++
+	return true
+}
+
+// position returns where e is among bodies, and whether it still is.
+func (e measureEnd) position(bodies []nbody.Body) (nbody.Vector2D, bool) {
+	if e.id == nbody.NoBody {
+		return e.p, true
+	}
+	i := slices.IndexFunc(bodies, func(b nbody.Body) bool { return b.ID == e.id })
+	if i < 0 {
+		return nbody.Vector2D{}, false
+	}
+	return bodies[i].Position, true
+}
+
+// formatLightTime formats how long light takes to cross d world units.
+func formatLightTime(d float64) string {
+	t := d * metersPerUnit / speedOfLight
+	if t < 60 {
+		return fmt.Sprintf("%.3g s", t)
+	}
+	return formatDuration(t)
+}
+
+// drawMeasurement draws the measurement line in vp, labelled with its
+// length and light-travel time, once both ends are placed. center is the
+// camera center.
+func (g *Game) drawMeasurement(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	m := &g.measurer
+	if !m.on || m.n == 0 {
+		return
+	}
+	var ends [2]nbody.Vector2D
+	for i := range m.n {
+		p, ok := m.ends[i].position(g.drawBodies)
+		if !ok {
+			return
+		}
+		ends[i] = p
+	}
+	a := vp.toScreen(g.view(vp.Frame.apply(ends[0])), center)
+	if m.n == 1 {
+		vector.StrokeCircle(dst, float32(a.X), float32(a.Y), 4*float32(uiScale), float32(uiScale), theme.Selection, true)
+		return
+	}
+	b := vp.toScreen(g.view(vp.Frame.apply(ends[1])), center)
+	vector.StrokeLine(dst, float32(a.X), float32(a.Y), float32(b.X), float32(b.Y), float32(uiScale), theme.Selection, true)
+	d := math.Hypot(ends[1].X-ends[0].X, ends[1].Y-ends[0].Y)
+	label := formatDistance(d) + ", light " + formatLightTime(d)
+	drawText(dst, label, int((a.X+b.X)/2)+6, int((a.Y+b.Y)/2)-debugLineHeight/2)
+}
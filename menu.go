@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/render"
+	"n-body/scenario"
+)
+
+// Size of the start menu's thumbnails, before scaling.
+const (
+	thumbWidth  = 150
+	thumbHeight = 120
+)
+
+// menuEntry is a scenario offered by the start menu.
+type menuEntry struct {
+	sc    *scenario.Scenario
+	img   *image.RGBA   // thumbnail of its bodies at the start
+	thumb *ebiten.Image // img, once there is a window to upload it to
+}
+
+// startMenu runs in place of the game until a scenario is chosen, from the
+// built-in ones and the scenario files in a directory, each listed with a
+// thumbnail of its bodies at the start and its description. The arrow
+// keys or the wheel and Enter, or a click or tap, choose one, and start
+// sets the game up for it, which then takes over. If that fails, the menu
+// shows why and stays up to choose again.
+type startMenu struct {
+	entries       []menuEntry
+	current       int // highlighted entry
+	top           int // first entry shown
+	width, height int
+	start         func(*scenario.Scenario) (*Game, error)
+	game          *Game // once started
+	err           error // why the last scenario chosen failed to start, or nil
+}
+
+// newStartMenu lists the built-in scenarios, then those in dir, which may
+// not exist. Files that fail to load are logged and left out.
+func newStartMenu(dir string, start func(*scenario.Scenario) (*Game, error)) *startMenu {
+	m := &startMenu{start: start, width: screenWidth, height: screenHeight}
+	for _, name := range scenario.BuiltinNames() {
+		sc, _ := scenario.Builtin(name)
+		m.add(sc)
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, path := range paths {
+		sc, err := scenario.Load(path)
+		if err != nil {
+			log.Printf("start menu: %v", err)
+			continue
+		}
+		m.add(sc)
+	}
+	if len(m.entries) == 0 {
+		log.Fatalf("no scenarios to choose from in %s", dir)
+	}
+	return m
+}
+
+// add lists sc, with a thumbnail.
+func (m *startMenu) add(sc *scenario.Scenario) {
+	sim := sc.Build()
+	img := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	render.Rasterize(img, &render.Frame{Bodies: sim.AppendBodies(nil), Width: sim.Width, Height: sim.Height, Center: sim.Center()})
+	m.entries = append(m.entries, menuEntry{sc: sc, img: img})
+}
+
+// rowHeight is the height of an entry in the list.
+func rowHeight() int { return int((thumbHeight + 12) * uiScale) }
+
+// rows returns how many entries fit on screen.
+func (m *startMenu) rows() int {
+	return max((m.height-3*debugLineHeight)/rowHeight(), 1)
+}
+
+func (m *startMenu) Update() error {
+	if m.game != nil {
+		return m.game.Update()
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		m.current = min(m.current+1, len(m.entries)-1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		m.current = max(m.current-1, 0)
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		m.choose()
+		return nil
+	}
+	if _, wy := ebiten.Wheel(); wy != 0 {
+		m.current = min(max(m.current-int(math.Copysign(1, wy)), 0), len(m.entries)-1)
+	}
+	clicked, y := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft), 0
+	if clicked {
+		_, y = ebiten.CursorPosition()
+	} else if taps := inpututil.AppendJustReleasedTouchIDs(nil); len(taps) > 0 {
+		clicked = true
+		_, y = inpututil.TouchPositionInPreviousTick(taps[0])
+	}
+	if clicked {
+		if i := m.top + (y-3*debugLineHeight)/rowHeight(); y >= 3*debugLineHeight && i < len(m.entries) {
+			m.current = i
+			m.choose()
+			return nil
+		}
+	}
+	// Keep the highlighted entry on screen.
+	m.top = min(max(m.top, m.current-m.rows()+1, 0), m.current, max(len(m.entries)-m.rows(), 0))
+	return nil
+}
+
+// choose starts the highlighted scenario.
+func (m *startMenu) choose() {
+	sc := m.entries[m.current].sc
+	log.Printf("starting %s", sc.Name)
+	game, err := m.start(sc)
+	if err != nil {
+		log.Printf("start menu: %s: %v", sc.Name, err)
+		m.err = fmt.Errorf("%s: %w", sc.Name, err)
+		return
+	}
+	ebiten.SetWindowTitle("N-Body Simulation: " + sc.Name)
+	m.game, m.err = game, nil
+	for _, e := range m.entries {
+		if e.thumb != nil {
+			e.thumb.Deallocate()
+		}
+	}
+	m.entries = nil
+}
+
+func (m *startMenu) Draw(screen *ebiten.Image) {
+	if m.game != nil {
+		m.game.Draw(screen)
+		return
+	}
+	drawText(screen, "Choose a scenario (Up/Down and Enter, or click or tap)", int(16*uiScale), debugLineHeight)
+	if m.err != nil {
+		drawText(screen, "Could not start "+m.err.Error(), int(16*uiScale), 2*debugLineHeight)
+	}
+	tw, th := int(thumbWidth*uiScale), int(thumbHeight*uiScale)
+	textX := int(16*uiScale) + tw + int(12*uiScale)
+	y := 3 * debugLineHeight
+	for i := m.top; i < len(m.entries) && i < m.top+m.rows(); i++ {
+		e := &m.entries[i]
+		if i == m.current {
+			vector.DrawFilledRect(screen, float32(8*uiScale), float32(y), float32(m.width)-float32(16*uiScale), float32(rowHeight()), theme.Panel, false)
+			vector.StrokeRect(screen, float32(8*uiScale), float32(y), float32(m.width)-float32(16*uiScale), float32(rowHeight()), 1, theme.Selection, false)
+		}
+		if e.thumb == nil {
+			e.thumb = ebiten.NewImageFromImage(e.img)
+		}
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(float64(tw)/thumbWidth, float64(th)/thumbHeight)
+		op.GeoM.Translate(16*uiScale, float64(y)+6*uiScale)
+		screen.DrawImage(e.thumb, op)
+		ty := y + int(6*uiScale)
+		drawText(screen, e.sc.Name, textX, ty)
+		for _, line := range wrapText(e.sc.Description, max((m.width-textX)/debugCharWidth-2, 10)) {
+			ty += debugLineHeight
+			if ty+debugLineHeight > y+rowHeight() {
+				break
+			}
+			drawText(screen, line, textX, ty)
+		}
+		y += rowHeight()
+	}
+}
+
+func (m *startMenu) Layout(outsideWidth, outsideHeight int) (int, int) {
+	if m.game != nil {
+		return m.game.Layout(outsideWidth, outsideHeight)
+	}
+	if s := deviceScale(); s != uiScale {
+		setUIScale(s)
+	}
+	m.width, m.height = int(math.Ceil(float64(outsideWidth)*uiScale)), int(math.Ceil(float64(outsideHeight)*uiScale))
+	return m.width, m.height
+}
+
+// wrapText breaks s into lines of at most width characters, between words
+// where it can.
+func wrapText(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for len(word) > width {
+			if line != "" {
+				lines, line = append(lines, line), ""
+			}
+			lines, word = append(lines, word[:width]), word[width:]
+		}
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines, line = append(lines, line), word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
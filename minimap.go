@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+const (
+	// minimapSize is the longer side of the minimap in pixels.
+	minimapSize = 140
+	// minimapZoomedIn is the fraction of the world's area a viewport may
+	// show before the minimap appears.
+	minimapZoomedIn = 0.25
+)
+
+// drawMinimap draws the whole world in the top right corner of vp, with the
+// part vp shows outlined, once vp is zoomed in far enough to lose sight of
+// the rest. center is the camera center.
+func (g *Game) drawMinimap(dst *ebiten.Image, vp *Viewport, st *renderState, center nbody.Vector2D) {
+	if vp.inset {
+		return
+	}
+	viewW := float64(vp.Bounds.Dx()) / vp.Camera.Zoom
+	viewH := float64(vp.Bounds.Dy()) / vp.Camera.Zoom
+	if viewW*viewH > minimapZoomedIn*st.width*st.height {
+		return
+	}
+	scale := minimapSize * uiScale / math.Max(st.width, st.height)
+	w, h := float32(st.width*scale), float32(st.height*scale)
+	x0 := float32(vp.Bounds.Max.X) - w - 4
+	y0 := float32(vp.Bounds.Min.Y + debugLineHeight + 4)
+	vector.DrawFilledRect(dst, x0, y0, w, h, theme.Panel, false)
+	vector.StrokeRect(dst, x0, y0, w, h, 1, theme.Border, false)
+
+	// Frames keep the middle of the world where it is, so the world in a
+	// frame spans the same rectangle around it.
+	world := st.center()
+	toMap := func(p nbody.Vector2D) (float32, float32) {
+		return x0 + w/2 + float32((p.X-world.X)*scale), y0 + h/2 + float32((p.Y-world.Y)*scale)
+	}
+	for i, b := range g.drawBodies {
+		x, y := toMap(g.framePositions[i])
+		if x >= x0 && x < x0+w && y >= y0 && y < y0+h {
+			g.points.add(dst, float64(x), float64(y), minPointRadius, b.Color)
+		}
+	}
+	g.points.flush(dst)
+
+	// At least a few pixels across, so a deep zoom still shows where.
+	cx, cy := toMap(center)
+	rw, rh := float32(math.Max(viewW*scale, 3)), float32(math.Max(viewH*scale, 3))
+	vector.StrokeRect(dst, cx-rw/2, cy-rh/2, rw, rh, 1, theme.Selection, false)
+}
@@ -0,0 +1,99 @@
+package nbody
+
+import "math"
+
+// Steps per orbit of the fastest pair: RecommendedStepsPerOrbit keeps
+// orbits closed to the eye for many revolutions, while below
+// MinStepsPerOrbit they visibly spiral, precess or fly apart.
+const (
+	RecommendedStepsPerOrbit = 100
+	MinStepsPerOrbit         = 20
+)
+
+// adviceMaxPairs bounds the work of AdviseStep. Larger systems are analysed
+// for an evenly spaced sample of bodies, each still against every other.
+const adviceMaxPairs = 1e7
+
+// StepAdvice describes the fastest dynamics in a simulation: the shortest
+// orbital period, or time to close a gap, over all pairs of bodies.
+type StepAdvice struct {
+	Timescale float64 // simulated seconds
+	A, B      int     // IDs of the pair setting it
+}
+
+// AdviseStep finds the pair of bodies with the fastest dynamics, measured
+// with the simulation's own force law: the period of a circular orbit at
+// the pair's separation and mutual pull, or the time to cross that
+// separation at their relative speed if shorter. It reports false when
+// there is no interacting pair.
+func (s *Simulation) AdviseStep() (StepAdvice, bool) {
+	n := s.Len()
+	stride := max(1, int(float64(n)*float64(n)/adviceMaxPairs))
+	best := StepAdvice{Timescale: math.Inf(1)}
+	for i := 0; i < n; i += stride {
+		for j := range n {
+			if j == i || (j < i && (i-j)%stride == 0) {
+				continue // same body, or a pair already measured
+			}
+			dx, dy := s.posX[j]-s.posX[i], s.posY[j]-s.posY[i]
+			r := math.Hypot(dx, dy)
+			ax, ay := PairAcceleration(dx, dy, G*(s.mass[i]+s.mass[j]))
+			a := math.Hypot(ax, ay)
+			if r == 0 || a == 0 {
+				continue
+			}
+			t := math.Sqrt(r / a)
+			if v := math.Hypot(s.velX[j]-s.velX[i], s.velY[j]-s.velY[i]); v > 0 {
+				t = math.Min(t, r/v)
+			}
+			if t *= 2 * math.Pi; t < best.Timescale {
+				best = StepAdvice{Timescale: t, A: s.info[i].ID, B: s.info[j].ID}
+			}
+		}
+	}
+	return best, !math.IsInf(best.Timescale, 1)
+}
+
+// Step returns the recommended integration step.
+func (a StepAdvice) Step() float64 {
+	return a.Timescale / RecommendedStepsPerOrbit
+}
+
+// Substeps returns how many substeps an Update of dt needs to take steps
+// no longer than the recommended one.
+func (a StepAdvice) Substeps(dt float64) int {
+	return max(1, int(math.Ceil(dt/a.Step())))
+}
+
+// Tune raises s.Substeps towards the recommended number, but not above
+// limit, and reports whether the steps then resolve the advice.
+func (a StepAdvice) Tune(s *Simulation, limit int) bool {
+	s.Substeps = max(s.Substeps, min(a.Substeps(s.StepSize()), limit))
+	return a.Resolves(s.SubstepSize())
+}
+
+// Resolves reports whether integration steps of dt are short enough not to
+// visibly corrupt the fastest orbits.
+func (a StepAdvice) Resolves(dt float64) bool {
+	return dt <= a.Timescale/MinStepsPerOrbit
+}
+
+// CheckStep measures the fastest orbit in s, raising its substeps up to
+// limit if that is too fast for them, and reports through logf, such as
+// log.Printf, the substeps it chose and, if the steps are still too long,
+// a warning.
+func (s *Simulation) CheckStep(limit int, logf func(format string, args ...any)) {
+	advice, ok := s.AdviseStep()
+	if !ok {
+		return
+	}
+	before := max(s.Substeps, 1)
+	resolved := advice.Tune(s, limit)
+	if s.Substeps > before {
+		logf("using %d substeps for bodies %d and %d, whose orbit takes %.3gs", s.Substeps, advice.A, advice.B, advice.Timescale)
+	}
+	if !resolved {
+		logf("warning: steps of %.3gs are too long for bodies %d and %d, whose orbit takes %.3gs, and will corrupt it; %d substeps are recommended",
+			s.SubstepSize(), advice.A, advice.B, advice.Timescale, advice.Substeps(s.StepSize()))
+	}
+}
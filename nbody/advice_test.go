@@ -0,0 +1,115 @@
+package nbody
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestAdviseStep(t *testing.T) {
+	s := NewSimulation(1000, 1000)
+	if _, ok := s.AdviseStep(); ok {
+		t.Error("advice for an empty simulation")
+	}
+	a := s.AddBody(Body{Position: Vector2D{X: 100, Y: 500}, Mass: 1e30})
+	if _, ok := s.AdviseStep(); ok {
+		t.Error("advice for a single body")
+	}
+	b := s.AddBody(Body{Position: Vector2D{X: 900, Y: 500}, Mass: 1e30})
+	ax, ay := PairAcceleration(800, 0, G*2e30)
+	want := 2 * math.Pi * math.Sqrt(800/math.Hypot(ax, ay))
+	advice, ok := s.AdviseStep()
+	if !ok || advice.A != a || advice.B != b || math.Abs(advice.Timescale-want) > 1e-9*want {
+		t.Errorf("advice for two bodies at rest = %+v, %v; want %v for %d and %d", advice, ok, want, a, b)
+	}
+
+	// A fast enough approach sets the timescale instead of the orbit.
+	s.SetBody(1, Body{ID: b, Position: Vector2D{X: 900, Y: 500}, Velocity: Vector2D{X: -8e6}, Mass: 1e30})
+	want = 2 * math.Pi * 800 / 8e6
+	if advice, _ := s.AdviseStep(); math.Abs(advice.Timescale-want) > 1e-9*want {
+		t.Errorf("timescale of a fast approach = %v, want %v", advice.Timescale, want)
+	}
+
+	// The closest of three bodies at rest sets the timescale.
+	s.SetBody(1, Body{ID: b, Position: Vector2D{X: 900, Y: 500}, Mass: 1e30})
+	c := s.AddBody(Body{Position: Vector2D{X: 150, Y: 500}, Mass: 1e30})
+	if advice, _ := s.AdviseStep(); advice.A != a || advice.B != c {
+		t.Errorf("advice for three bodies names %d and %d, want %d and %d", advice.A, advice.B, a, c)
+	}
+}
+
+func TestTune(t *testing.T) {
+	advice := StepAdvice{Timescale: 1000} // a step of 10 recommended
+	for _, tt := range []struct {
+		dt              float64
+		substeps, limit int
+		want            int
+		resolved        bool
+	}{
+		{dt: 100, limit: 16, want: 10, resolved: true},
+		{dt: 100, limit: 2, want: 2, resolved: true},
+		{dt: 100, limit: 1, want: 1, resolved: false},
+		{dt: 100, substeps: 12, limit: 1, want: 12, resolved: true},
+		{dt: 10, limit: 16, want: 1, resolved: true},
+		{dt: 50, limit: 1, want: 1, resolved: true},
+	} {
+		s := NewSimulation(1000, 1000)
+		s.Dt, s.Substeps = tt.dt, tt.substeps
+		resolved := advice.Tune(s, tt.limit)
+		if s.Substeps != tt.want || resolved != tt.resolved {
+			t.Errorf("Tune(dt %v, substeps %d, limit %d) = %d substeps, %v; want %d, %v",
+				tt.dt, tt.substeps, tt.limit, s.Substeps, resolved, tt.want, tt.resolved)
+		}
+	}
+}
+
+func TestResolves(t *testing.T) {
+	advice := StepAdvice{Timescale: 1000}
+	limit := advice.Timescale / MinStepsPerOrbit
+	if !advice.Resolves(limit) {
+		t.Errorf("steps of %v do not resolve a timescale of %v", limit, advice.Timescale)
+	}
+	if advice.Resolves(math.Nextafter(limit, math.Inf(1))) {
+		t.Errorf("steps just over %v resolve a timescale of %v", limit, advice.Timescale)
+	}
+	if got := advice.Substeps(advice.Step() * 2.5); got != 3 {
+		t.Errorf("Substeps(2.5 recommended steps) = %d, want 3", got)
+	}
+}
+
+func TestCheckStep(t *testing.T) {
+	newSim := func(dt float64) *Simulation {
+		s := NewSimulation(1000, 1000)
+		s.AddBody(Body{Position: Vector2D{X: 100, Y: 500}, Mass: 1e30})
+		s.AddBody(Body{Position: Vector2D{X: 900, Y: 500}, Mass: 1e30})
+		s.Dt = dt
+		return s
+	}
+	advice, _ := newSim(1).AdviseStep()
+	for _, tt := range []struct {
+		name  string
+		dt    float64
+		limit int
+		logs  []string
+	}{
+		{"fine steps", advice.Step(), 64, nil},
+		{"substepped", 4 * advice.Step(), 64, []string{"using 4 substeps"}},
+		{"limited", 100 * advice.Step(), 2, []string{"using 2 substeps", "warning: steps of"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs []string
+			newSim(tt.dt).CheckStep(tt.limit, func(format string, args ...any) {
+				logs = append(logs, fmt.Sprintf(format, args...))
+			})
+			if len(logs) != len(tt.logs) {
+				t.Fatalf("logged %q, want %d lines", logs, len(tt.logs))
+			}
+			for i, prefix := range tt.logs {
+				if !strings.HasPrefix(logs[i], prefix) {
+					t.Errorf("line %d = %q, want prefix %q", i, logs[i], prefix)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package nbody
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testDisk returns a simulation of n bodies on circular orbits in a disk.
+func testDisk(n int) *Simulation {
+	s := NewSimulation(1000, 800)
+	r := rand.New(rand.NewSource(1))
+	for range n {
+		a := r.Float64() * 2 * math.Pi
+		d := 300 * math.Sqrt(r.Float64())
+		s.AddBody(Body{
+			Position: Vector2D{X: 500 + d*math.Cos(a), Y: 400 + d*math.Sin(a)},
+			Velocity: Vector2D{X: -20 * math.Sin(a), Y: 20 * math.Cos(a)},
+			Mass:     1e24 * (1 + r.Float64()),
+			Radius:   0.01,
+		})
+	}
+	return s
+}
+
+// stepConfigs are the configurations whose steady-state step must not
+// allocate.
+var stepConfigs = []struct {
+	name  string
+	n     int
+	setup func(*Simulation)
+}{
+	{"direct", 100, func(*Simulation) {}},
+	{"parallel", 2000, func(*Simulation) {}},
+	{"float32", 2000, func(s *Simulation) { s.Precision = Float32 }},
+	{"fmm", 5000, func(s *Simulation) { s.Solver = NewFMM(2, 0.5) }},
+	{"pm", 5000, func(s *Simulation) { s.Solver = NewPM(64) }},
+	{"constraints", 100, func(s *Simulation) {
+		s.AddConstraint(Spring{A: 1, B: 2, RestLength: 10, Stiffness: 1})
+		s.AddConstraint(Tether{A: 3, B: 4, MaxLength: 5})
+	}},
+	{"collisions", 100, func(s *Simulation) { s.Collisions = true }},
+}
+
+func TestStepDoesNotAllocate(t *testing.T) {
+	for _, c := range stepConfigs {
+		t.Run(c.name, func(t *testing.T) {
+			s := testDisk(c.n)
+			c.setup(s)
+			for range 5 { // let the scratch buffers grow
+				s.Update()
+			}
+			if allocs := testing.AllocsPerRun(20, s.Update); allocs != 0 {
+				t.Errorf("%.1f allocations per step, want 0", allocs)
+			}
+		})
+	}
+}
+
+// BenchmarkStepAllocs reports the allocations of a steady-state step. Unlike
+// TestStepDoesNotAllocate, it runs with GOMAXPROCS workers, so it also
+// covers the parallel force pass.
+func BenchmarkStepAllocs(b *testing.B) {
+	for _, c := range stepConfigs {
+		b.Run(c.name, func(b *testing.B) {
+			s := testDisk(c.n)
+			c.setup(s)
+			for range 5 {
+				s.Update()
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				s.Update()
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package nbody
+
+import (
+	"fmt"
+	"testing"
+)
+
+var benchSizes = []int{10, 100, 1000, 10000}
+
+// benchSolvers are the gravity backends compared by BenchmarkForces.
+var benchSolvers = []struct {
+	name  string
+	setup func(*Simulation)
+}{
+	{"direct", func(*Simulation) {}},
+	{"float32", func(s *Simulation) { s.Precision = Float32 }},
+	{"fmm", func(s *Simulation) { s.Solver = NewFMM(2, 0.5) }},
+	{"pm", func(s *Simulation) { s.Solver = NewPM(128) }},
+}
+
+// BenchmarkForces measures the force pass alone.
+func BenchmarkForces(b *testing.B) {
+	for _, solver := range benchSolvers {
+		for _, n := range benchSizes {
+			b.Run(fmt.Sprintf("%s/N=%d", solver.name, n), func(b *testing.B) {
+				s := testDisk(n)
+				solver.setup(s)
+				s.computeAccelerations(nil)
+				b.ResetTimer()
+				for range b.N {
+					s.computeAccelerations(nil)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkStep measures a whole step with the direct sum: forces,
+// integration and wrapping.
+func BenchmarkStep(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("N=%d", n), func(b *testing.B) {
+			s := testDisk(n)
+			s.Update()
+			b.ResetTimer()
+			for range b.N {
+				s.Update()
+			}
+		})
+	}
+}
+
+// BenchmarkTreeBuild measures building the FMM quadtree from scratch.
+func BenchmarkTreeBuild(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("N=%d", n), func(b *testing.B) {
+			f := treeFor(testDisk(n))
+			b.ResetTimer()
+			for range b.N {
+				f.build()
+			}
+		})
+	}
+}
+
+// BenchmarkTreeUpdate measures updating the FMM quadtree after a step in
+// which bodies moved, the per-step cost when Rebuild is off.
+func BenchmarkTreeUpdate(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("N=%d", n), func(b *testing.B) {
+			s := testDisk(n)
+			f := treeFor(s)
+			b.ResetTimer()
+			for range b.N {
+				b.StopTimer()
+				for i := range s.posX { // drift without paying for forces
+					s.posX[i] += s.velX[i] * TimeStep
+					s.posY[i] += s.velY[i] * TimeStep
+				}
+				b.StartTimer()
+				if !f.update() {
+					f.build()
+				}
+			}
+		})
+	}
+}
+
+// treeFor returns an FMM solver whose quadtree holds s's bodies.
+func treeFor(s *Simulation) *FMM {
+	f := NewFMM(2, 0.5)
+	f.x, f.y = s.Positions()
+	for _, m := range s.Masses() {
+		f.gm = append(f.gm, G*m)
+	}
+	f.build()
+	return f
+}
+
+// BenchmarkCollisions measures the collision pass over bodies that don't
+// touch, which is the common case.
+func BenchmarkCollisions(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("N=%d", n), func(b *testing.B) {
+			s := testDisk(n)
+			s.mergeCollisions()
+			b.ResetTimer()
+			for range b.N {
+				s.mergeCollisions()
+			}
+		})
+	}
+}
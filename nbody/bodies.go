@@ -0,0 +1,86 @@
+package nbody
+
+import "math"
+
+// NoBody is the ID used where no body is referenced.
+const NoBody = -1
+
+// Barycenter returns the mass-weighted mean position of bodies, or the zero
+// vector if they have no mass.
+func Barycenter(bodies []Body) Vector2D {
+	var sum Vector2D
+	mass := 0.0
+	for _, b := range bodies {
+		sum = AddVectors(sum, ScaleVector(b.Position, b.Mass))
+		mass += b.Mass
+	}
+	if mass == 0 {
+		return Vector2D{}
+	}
+	return ScaleVector(sum, 1/mass)
+}
+
+// BarycenterVelocity returns the mass-weighted mean velocity of bodies, or
+// the zero vector if they have no mass.
+func BarycenterVelocity(bodies []Body) Vector2D {
+	var sum Vector2D
+	mass := 0.0
+	for _, b := range bodies {
+		sum = AddVectors(sum, ScaleVector(b.Velocity, b.Mass))
+		mass += b.Mass
+	}
+	if mass == 0 {
+		return Vector2D{}
+	}
+	return ScaleVector(sum, 1/mass)
+}
+
+// FindBody returns the body with the given ID.
+func FindBody(bodies []Body, id int) (Body, bool) {
+	for _, b := range bodies {
+		if b.ID == id {
+			return b, true
+		}
+	}
+	return Body{}, false
+}
+
+// HeaviestBody returns the ID of the most massive body, or NoBody if there
+// are no bodies.
+func HeaviestBody(bodies []Body) int {
+	id, mass := NoBody, math.Inf(-1)
+	for _, b := range bodies {
+		if b.Mass > mass {
+			id, mass = b.ID, b.Mass
+		}
+	}
+	return id
+}
+
+// NextBodyID returns the ID of the body after id in bodies, the first body
+// when id is NoBody, and NoBody after the last body.
+func NextBodyID(bodies []Body, id int) int {
+	for i, b := range bodies {
+		if id == NoBody {
+			return b.ID
+		}
+		if b.ID == id && i+1 < len(bodies) {
+			return bodies[i+1].ID
+		}
+	}
+	return NoBody
+}
+
+// PrevBodyID returns the ID of the body before id in bodies, the last body
+// when id is NoBody, and NoBody before the first body.
+func PrevBodyID(bodies []Body, id int) int {
+	for i := len(bodies) - 1; i >= 0; i-- {
+		if id == NoBody {
+			return bodies[i].ID
+		}
+		if bodies[i].ID == id && i > 0 {
+			return bodies[i-1].ID
+		}
+	}
+	return NoBody
+}
@@ -0,0 +1,88 @@
+package nbody
+
+import "math"
+
+// collisionGrid is the broad phase of collision detection: a uniform grid
+// of square cells, at least as wide as the largest body, hashed into a
+// table of linked lists so its memory follows the number of bodies rather
+// than the size of the world. Bodies can only overlap if they lie in the
+// same or neighbouring cells, so each body is tested against a handful of
+// others instead of all of them.
+type collisionGrid struct {
+	cell  float64 // cell width
+	reach float64 // radius of the largest body when the grid was built
+	head  []int32 // first body of each bucket, or -1
+	next  []int32 // next body in the same bucket, or -1
+}
+
+// build hashes bodies into the grid.
+func (g *collisionGrid) build(s *Simulation) {
+	g.reach = 0
+	for i := range s.info {
+		g.reach = math.Max(g.reach, s.info[i].Radius)
+	}
+	// Cells about one body apiece when bodies are small, so a query
+	// mostly touches a single bucket.
+	g.cell = math.Max(2*g.reach, math.Sqrt(s.Width*s.Height/float64(s.Len())))
+	if !(g.cell > 0) {
+		g.cell = 1
+	}
+
+	size := 16
+	for size < s.Len() {
+		size *= 2
+	}
+	g.head = grow(g.head, size)
+	g.next = grow(g.next, s.Len())
+	for i := range g.head {
+		g.head[i] = -1
+	}
+	for i := range s.posX {
+		b := g.bucket(g.coord(s.posX[i]), g.coord(s.posY[i]))
+		g.next[i] = g.head[b]
+		g.head[b] = int32(i)
+	}
+}
+
+func (g *collisionGrid) coord(v float64) int {
+	return int(math.Floor(v / g.cell))
+}
+
+func (g *collisionGrid) bucket(cx, cy int) int {
+	h := uint(cx)*73856093 ^ uint(cy)*19349663
+	return int(h & uint(len(g.head)-1))
+}
+
+// firstOverlap returns the lowest index above i of a live body overlapping
+// body i, or -1. Body i may have moved and grown since the grid was built;
+// the bodies above it must not have.
+func (g *collisionGrid) firstOverlap(s *Simulation, i int, dead []bool) int {
+	x, y, r := s.posX[i], s.posY[i], s.info[i].Radius
+	reach := r + g.reach
+	x0, x1 := g.coord(x-reach), g.coord(x+reach)
+	y0, y1 := g.coord(y-reach), g.coord(y+reach)
+	first := -1
+	for cy := y0; cy <= y1; cy++ {
+		for cx := x0; cx <= x1; cx++ {
+			for j := g.head[g.bucket(cx, cy)]; j >= 0; j = g.next[j] {
+				if int(j) <= i || dead[j] || (first >= 0 && int(j) >= first) {
+					continue
+				}
+				dx, dy := s.posX[j]-x, s.posY[j]-y
+				rr := r + s.info[j].Radius
+				if dx*dx+dy*dy <= rr*rr {
+					first = int(j)
+				}
+			}
+		}
+	}
+	return first
+}
+
+// grow returns s resized to n, reusing its storage when it is large enough.
+func grow[T any](s []T, n int) []T {
+	if cap(s) < n {
+		return make([]T, n)
+	}
+	return s[:n]
+}
@@ -0,0 +1,118 @@
+package nbody
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// crowd returns n bodies of varied size packed closely enough that many
+// of them overlap.
+func crowd(n int) *Simulation {
+	s := NewSimulation(1000, 800)
+	r := rand.New(rand.NewSource(2))
+	for range n {
+		s.AddBody(Body{
+			Position: Vector2D{X: r.Float64() * 1000, Y: r.Float64() * 800},
+			Mass:     1e24 * (1 + r.Float64()),
+			Radius:   math.Sqrt(1000*800/float64(n)) * r.Float64() * r.Float64(),
+		})
+	}
+	s.Mergers = &MergerTree{}
+	return s
+}
+
+// mergeAllPairs is the quadratic merge pass the spatial hash replaced.
+func (s *Simulation) mergeAllPairs() {
+	for i := 0; i < s.Len(); i++ {
+		for j := i + 1; j < s.Len(); j++ {
+			dx := s.posX[j] - s.posX[i]
+			dy := s.posY[j] - s.posY[i]
+			r := s.info[i].Radius + s.info[j].Radius
+			if dx*dx+dy*dy > r*r {
+				continue
+			}
+			s.merge(i, j)
+			s.RemoveBody(j)
+			j = i
+		}
+	}
+}
+
+func TestCollisionsMatchAllPairs(t *testing.T) {
+	for _, n := range []int{50, 500, 2000} {
+		got, want := crowd(n), crowd(n)
+		got.mergeCollisions()
+		want.mergeAllPairs()
+		if len(want.Mergers.Events) == 0 {
+			t.Fatalf("N=%d: no mergers to compare", n)
+		}
+		if got.Len() != want.Len() {
+			t.Fatalf("N=%d: %d bodies left, want %d", n, got.Len(), want.Len())
+		}
+		for i := range want.posX {
+			if g, w := got.Body(i), want.Body(i); g != w {
+				t.Fatalf("N=%d: body %d is %+v, want %+v", n, i, g, w)
+			}
+		}
+		for i, e := range want.Mergers.Events {
+			if got.Mergers.Events[i] != e {
+				t.Fatalf("N=%d: merger %d is %+v, want %+v", n, i, got.Mergers.Events[i], e)
+			}
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name           string
+		a, b           Body
+		radius         float64
+		physicalRadius float64
+	}{
+		{
+			name:   "drawn radius by volume",
+			a:      Body{Mass: 3, Radius: 3},
+			b:      Body{Mass: 1, Radius: 4, Position: Vector2D{X: 4}, Velocity: Vector2D{Y: 4}},
+			radius: math.Cbrt(27 + 64),
+		},
+		{
+			name:           "physical radius by volume",
+			a:              Body{Mass: 3, Radius: 1, PhysicalRadius: 6e6},
+			b:              Body{Mass: 1, Radius: 1, PhysicalRadius: 2e6, Position: Vector2D{X: 4}, Velocity: Vector2D{Y: 4}},
+			radius:         math.Cbrt(2),
+			physicalRadius: math.Cbrt(216e18 + 8e18),
+		},
+		{
+			name:           "unknown physical radius",
+			a:              Body{Mass: 3, Radius: 1, PhysicalRadius: 6e6},
+			b:              Body{Mass: 1, Radius: 1, Position: Vector2D{X: 4}, Velocity: Vector2D{Y: 4}},
+			radius:         math.Cbrt(2),
+			physicalRadius: 6e6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSimulation(1000, 800)
+			heavier := s.AddBody(tt.a)
+			s.AddBody(tt.b)
+			s.merge(0, 1)
+			got := s.Body(0)
+			if got.ID != heavier || got.Mass != 4 {
+				t.Errorf("merged body %d of mass %g, want %d, the heavier, of mass 4", got.ID, got.Mass, heavier)
+			}
+			if want := (Vector2D{X: 1}); got.Position != want {
+				t.Errorf("position %v, want the center of mass %v", got.Position, want)
+			}
+			if want := (Vector2D{Y: 1}); got.Velocity != want {
+				t.Errorf("velocity %v, want %v, conserving momentum", got.Velocity, want)
+			}
+			if math.Abs(got.Radius-tt.radius) > 1e-12*tt.radius {
+				t.Errorf("radius %g, want %g", got.Radius, tt.radius)
+			}
+			if math.Abs(got.PhysicalRadius-tt.physicalRadius) > 1e-12*tt.physicalRadius {
+				t.Errorf("physical radius %g, want %g", got.PhysicalRadius, tt.physicalRadius)
+			}
+		})
+	}
+}
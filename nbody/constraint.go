@@ -0,0 +1,149 @@
+package nbody
+
+import "math"
+
+// Constraint couples a pair of bodies, identified by their IDs. Constraints
+// may apply a force during the force pass, correct positions and velocities
+// after integration, or both.
+type Constraint interface {
+	// Endpoints returns the IDs of the two constrained bodies.
+	Endpoints() (a, b int)
+	// Force returns the force exerted on a; b receives the opposite force.
+	Force(a, b *Body) Vector2D
+	// Resolve corrects the bodies' state after they have been integrated.
+	Resolve(a, b *Body)
+}
+
+// Spring is a damped Hookean spring between two bodies. Stiffness is the
+// force per unit of extension and Damping the force per unit of relative
+// speed along the spring, both in the units gravity forces are computed in.
+type Spring struct {
+	A          int     `json:"a"`
+	B          int     `json:"b"`
+	RestLength float64 `json:"restLength"`
+	Stiffness  float64 `json:"stiffness"`
+	Damping    float64 `json:"damping,omitempty"`
+}
+
+func (s Spring) Endpoints() (int, int) { return s.A, s.B }
+
+func (s Spring) Force(a, b *Body) Vector2D {
+	dx := b.Position.X - a.Position.X
+	dy := b.Position.Y - a.Position.Y
+	dist := math.Hypot(dx, dy)
+	if dist == 0 {
+		return Vector2D{}
+	}
+	nx, ny := dx/dist, dy/dist
+	relSpeed := (b.Velocity.X-a.Velocity.X)*nx + (b.Velocity.Y-a.Velocity.Y)*ny
+	f := s.Stiffness*(dist-s.RestLength) + s.Damping*relSpeed
+	return Vector2D{X: f * nx, Y: f * ny}
+}
+
+func (s Spring) Resolve(a, b *Body) {}
+
+// Tether is an inextensible, massless rope: the bodies move freely while
+// closer than MaxLength and are held at MaxLength when the rope is taut.
+type Tether struct {
+	A         int     `json:"a"`
+	B         int     `json:"b"`
+	MaxLength float64 `json:"maxLength"`
+}
+
+func (t Tether) Endpoints() (int, int) { return t.A, t.B }
+
+func (t Tether) Force(a, b *Body) Vector2D { return Vector2D{} }
+
+// Resolve pulls a taut tether's bodies back to MaxLength apart and removes
+// their separating velocity, weighting both by inverse mass so momentum is
+// conserved.
+func (t Tether) Resolve(a, b *Body) {
+	dx := b.Position.X - a.Position.X
+	dy := b.Position.Y - a.Position.Y
+	dist := math.Hypot(dx, dy)
+	if dist <= t.MaxLength || dist == 0 {
+		return
+	}
+	nx, ny := dx/dist, dy/dist
+	wa := b.Mass / (a.Mass + b.Mass)
+	wb := a.Mass / (a.Mass + b.Mass)
+
+	excess := dist - t.MaxLength
+	a.Position = AddVectors(a.Position, Vector2D{X: nx * excess * wa, Y: ny * excess * wa})
+	b.Position = AddVectors(b.Position, Vector2D{X: -nx * excess * wb, Y: -ny * excess * wb})
+
+	relSpeed := (b.Velocity.X-a.Velocity.X)*nx + (b.Velocity.Y-a.Velocity.Y)*ny
+	if relSpeed > 0 {
+		a.Velocity = AddVectors(a.Velocity, Vector2D{X: nx * relSpeed * wa, Y: ny * relSpeed * wa})
+		b.Velocity = AddVectors(b.Velocity, Vector2D{X: -nx * relSpeed * wb, Y: -ny * relSpeed * wb})
+	}
+}
+
+// AddConstraint attaches c to the simulation.
+func (s *Simulation) AddConstraint(c Constraint) {
+	s.Constraints = append(s.Constraints, c)
+}
+
+// constraintForces returns the net constraint force on every body, indexed
+// like the simulation's bodies. Constraints whose bodies no longer exist are
+// ignored.
+func (s *Simulation) constraintForces() []Vector2D {
+	if len(s.Constraints) == 0 {
+		return nil
+	}
+	n := s.Len()
+	if cap(s.forces) < n {
+		s.forces = make([]Vector2D, n)
+	}
+	forces := s.forces[:n]
+	clear(forces)
+	index := s.indexByID()
+	for _, c := range s.Constraints {
+		ia, ib, ok := endpointIndices(c, index)
+		if !ok {
+			continue
+		}
+		s.pairA, s.pairB = s.Body(ia), s.Body(ib)
+		f := c.Force(&s.pairA, &s.pairB)
+		forces[ia] = AddVectors(forces[ia], f)
+		forces[ib] = AddVectors(forces[ib], ScaleVector(f, -1))
+	}
+	return forces
+}
+
+// resolveConstraints applies the post-integration correction of every
+// constraint.
+func (s *Simulation) resolveConstraints() {
+	if len(s.Constraints) == 0 {
+		return
+	}
+	index := s.indexByID()
+	for _, c := range s.Constraints {
+		if ia, ib, ok := endpointIndices(c, index); ok {
+			s.pairA, s.pairB = s.Body(ia), s.Body(ib)
+			c.Resolve(&s.pairA, &s.pairB)
+			s.SetBody(ia, s.pairA)
+			s.SetBody(ib, s.pairB)
+		}
+	}
+}
+
+func endpointIndices(c Constraint, index map[int]int) (ia, ib int, ok bool) {
+	a, b := c.Endpoints()
+	ia, okA := index[a]
+	ib, okB := index[b]
+	return ia, ib, okA && okB && ia != ib
+}
+
+// indexByID maps body IDs to their current index. The map is reused by the
+// next call.
+func (s *Simulation) indexByID() map[int]int {
+	if s.index == nil {
+		s.index = make(map[int]int, s.Len())
+	}
+	clear(s.index)
+	for i, info := range s.info {
+		s.index[info.ID] = i
+	}
+	return s.index
+}
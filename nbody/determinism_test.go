@@ -0,0 +1,102 @@
+package nbody
+
+import (
+	"math"
+	"runtime"
+	"testing"
+)
+
+// TestForcesIndependentOfWorkers checks that a run gives bit-identical
+// results however many workers share the force pass.
+func TestForcesIndependentOfWorkers(t *testing.T) {
+	configs := []struct {
+		name  string
+		setup func(*Simulation)
+	}{
+		{"direct", func(*Simulation) {}},
+		{"float32", func(s *Simulation) { s.Precision = Float32 }},
+		{"charged", func(s *Simulation) {
+			for i := 0; i < s.Len(); i += 3 {
+				s.charge[i] = float64(i%2*2-1) * 1e9
+			}
+			s.MOND = MOND{Enabled: true, A0: DefaultMONDA0}
+		}},
+	}
+	procs := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(procs)
+	for _, c := range configs {
+		t.Run(c.name, func(t *testing.T) {
+			var want []Body
+			for _, workers := range []int{1, procs, 2, 3} { // the pool starts with the most
+				runtime.GOMAXPROCS(workers)
+				s := testDisk(1000)
+				c.setup(s)
+				for range 20 {
+					s.Update()
+				}
+				got := s.AppendBodies(nil)
+				if want == nil {
+					want = got
+					continue
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Fatalf("%d workers: body %d is %+v, want %+v as with 1", workers, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestParallelTreeBuild checks that building the FMM tree concurrently gives
+// the same accelerations, bit for bit, as building it serially.
+func TestParallelTreeBuild(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(procs)
+	s := testDisk(2 * fmmParallelBuild)
+	f := NewFMM(2, 0.5)
+	f.Rebuild = true
+	accelerations := func(workers int) []Vector2D {
+		runtime.GOMAXPROCS(workers)
+		acc := make([]Vector2D, s.Len())
+		f.Accelerations(s, acc)
+		return acc
+	}
+	want := accelerations(1)
+	for _, workers := range []int{2, 4} {
+		got := accelerations(workers)
+		if len(f.subtrees) == 0 {
+			t.Fatalf("%d workers: the tree was built serially", workers)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%d workers: body %d accelerates at %v, want %v", workers, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+// TestReverseRetracesSteps checks that running a simulation backwards for
+// as many steps as it ran forwards brings every body back to its start.
+func TestReverseRetracesSteps(t *testing.T) {
+	s := testDisk(200)
+	start := s.AppendBodies(nil)
+	for range 50 {
+		s.Update()
+	}
+	s.Reverse = true
+	for range 50 {
+		s.Update()
+	}
+	if math.Abs(s.Time) > 1e-12 {
+		t.Errorf("time is %g after retracing, want 0", s.Time)
+	}
+	for i, b := range s.AppendBodies(nil) {
+		want := start[i]
+		if math.Abs(b.Position.X-want.Position.X) > 1e-6 || math.Abs(b.Position.Y-want.Position.Y) > 1e-6 ||
+			math.Abs(b.Velocity.X-want.Velocity.X) > 1e-6 || math.Abs(b.Velocity.Y-want.Velocity.Y) > 1e-6 {
+			t.Fatalf("body %d is at %v moving %v, want %v moving %v", i, b.Position, b.Velocity, want.Position, want.Velocity)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package nbody
+
+import "math"
+
+// Diagnostics are the conserved quantities of a simulation, used to judge
+// integration accuracy.
+type Diagnostics struct {
+	Kinetic         float64
+	Potential       float64 // gravitational plus electrostatic
+	Energy          float64 // Kinetic + Potential
+	Momentum        Vector2D
+	AngularMomentum float64 // about the barycenter
+	Barycenter      Vector2D
+}
+
+// Diagnostics computes the simulation's current conserved quantities. The
+// potential matches the softened force law, as given by PairPotential.
+func (s *Simulation) Diagnostics() Diagnostics {
+	var d Diagnostics
+	bodies := s.AppendBodies(nil)
+	d.Barycenter = Barycenter(bodies)
+	for i, b := range bodies {
+		v2 := b.Velocity.X*b.Velocity.X + b.Velocity.Y*b.Velocity.Y
+		d.Kinetic += 0.5 * b.Mass * v2
+		d.Momentum = AddVectors(d.Momentum, ScaleVector(b.Velocity, b.Mass))
+		rx, ry := b.Position.X-d.Barycenter.X, b.Position.Y-d.Barycenter.Y
+		d.AngularMomentum += b.Mass * (rx*b.Velocity.Y - ry*b.Velocity.X)
+
+		for _, o := range bodies[i+1:] {
+			r := math.Hypot(o.Position.X-b.Position.X, o.Position.Y-b.Position.Y)
+			k := G*b.Mass*o.Mass - Ke*b.Charge*o.Charge
+			d.Potential += PairPotential(r, k)
+		}
+	}
+	d.Energy = d.Kinetic + d.Potential
+	return d
+}
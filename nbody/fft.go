@@ -0,0 +1,59 @@
+package nbody
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// fft transforms a in place with an iterative radix-2 Cooley–Tukey FFT. The
+// length of a must be a power of two. The inverse transform is not
+// normalized; callers divide by the length themselves.
+func fft(a []complex128, inverse bool) {
+	n := len(a)
+	if n < 2 {
+		return
+	}
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range a {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		half := size / 2
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := range half {
+				u, v := a[start+k], a[start+k+half]*w
+				a[start+k], a[start+k+half] = u+v, u-v
+				w *= step
+			}
+		}
+	}
+}
+
+// fft2 transforms the row-major rows×cols grid a in place, using col as
+// scratch space of at least rows elements.
+func fft2(a []complex128, rows, cols int, col []complex128, inverse bool) {
+	for r := range rows {
+		fft(a[r*cols:(r+1)*cols], inverse)
+	}
+	col = col[:rows]
+	for c := range cols {
+		for r := range rows {
+			col[r] = a[r*cols+c]
+		}
+		fft(col, inverse)
+		for r := range rows {
+			a[r*cols+c] = col[r]
+		}
+	}
+}
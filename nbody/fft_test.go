@@ -0,0 +1,72 @@
+package nbody
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// dft transforms a with the naive O(n²) sum, signed like fft.
+func dft(a []complex128, inverse bool) []complex128 {
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	n := len(a)
+	out := make([]complex128, n)
+	for k := range n {
+		for j, v := range a {
+			out[k] += v * cmplx.Rect(1, sign*2*math.Pi*float64(j*k)/float64(n))
+		}
+	}
+	return out
+}
+
+func TestFFT(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 8, 64} {
+		in := make([]complex128, n)
+		for i := range in {
+			in[i] = complex(r.NormFloat64(), r.NormFloat64())
+		}
+		a := append([]complex128(nil), in...)
+		fft(a, false)
+		for k, want := range dft(in, false) {
+			if cmplx.Abs(a[k]-want) > 1e-9 {
+				t.Errorf("n = %d: fft[%d] = %v, want %v", n, k, a[k], want)
+			}
+		}
+		fft(a, true)
+		for i := range a {
+			if got := a[i] / complex(float64(n), 0); cmplx.Abs(got-in[i]) > 1e-12 {
+				t.Errorf("n = %d: round trip [%d] = %v, want %v", n, i, got, in[i])
+			}
+		}
+	}
+}
+
+func TestFFT2(t *testing.T) {
+	const rows, cols = 4, 8
+	r := rand.New(rand.NewSource(2))
+	in := make([]complex128, rows*cols)
+	for i := range in {
+		in[i] = complex(r.NormFloat64(), 0)
+	}
+	a := append([]complex128(nil), in...)
+	fft2(a, rows, cols, make([]complex128, rows), false)
+	for v := range rows {
+		for u := range cols {
+			var want complex128
+			for y := range rows {
+				for x := range cols {
+					phase := -2 * math.Pi * (float64(u*x)/cols + float64(v*y)/rows)
+					want += in[y*cols+x] * cmplx.Rect(1, phase)
+				}
+			}
+			if got := a[v*cols+u]; cmplx.Abs(got-want) > 1e-9 {
+				t.Errorf("fft2[%d][%d] = %v, want %v", v, u, got, want)
+			}
+		}
+	}
+}
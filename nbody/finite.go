@@ -0,0 +1,43 @@
+package nbody
+
+import (
+	"fmt"
+	"math"
+)
+
+// NonFiniteError reports a body whose state has become NaN or infinite,
+// which every later step would spread to the bodies around it.
+type NonFiniteError struct {
+	ID       int     // the first body found
+	Step     int     // the Update that produced it, counting from 1
+	Time     float64 // simulated time after that Update
+	Position Vector2D
+	Velocity Vector2D
+}
+
+func (e *NonFiniteError) Error() string {
+	return fmt.Sprintf("step %d (t=%.2fs): body %d is not finite: position %v, velocity %v",
+		e.Step, e.Time, e.ID, e.Position, e.Velocity)
+}
+
+// CheckFinite returns a *NonFiniteError for the first body with a NaN or
+// infinite position or velocity, or nil if there is none.
+func (s *Simulation) CheckFinite() error {
+	for i := range s.posX {
+		if isFinite(s.posX[i]) && isFinite(s.posY[i]) && isFinite(s.velX[i]) && isFinite(s.velY[i]) {
+			continue
+		}
+		return &NonFiniteError{
+			ID:       s.info[i].ID,
+			Step:     s.Steps,
+			Time:     s.Time,
+			Position: s.Position(i),
+			Velocity: s.Velocity(i),
+		}
+	}
+	return nil
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
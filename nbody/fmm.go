@@ -0,0 +1,564 @@
+package nbody
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	fmmLeafSize = 16 // maximum bodies in a leaf cell
+	fmmMaxDepth = 24 // guards against coincident bodies splitting forever
+	// fmmRootMargin pads the root cell beyond the bodies' bounding box, so
+	// the tree survives bodies drifting outward for a while.
+	fmmRootMargin = 1.0 / 16
+	// Trees of at least fmmParallelBuild bodies are built concurrently, one
+	// subtree per cell fmmParallelDepth levels below the root.
+	fmmParallelBuild = 4096
+	fmmParallelDepth = 2
+)
+
+// FMM is a fast multipole gravity solver on a quadtree. Cells carry a
+// multipole expansion of their mass about its center of mass; well
+// separated cell pairs interact through it and accumulate a local expansion
+// (field and field gradient) about the target cell's center, which is
+// passed down the tree and evaluated at each body. Nearby leaves interact
+// directly. The expansions are Cartesian Taylor series of the simulation's
+// softened force law, so FMM and the direct sum agree up to truncation
+// error.
+type FMM struct {
+	// Order selects the expansion accuracy: 0 uses monopoles and a constant
+	// local field per cell, 1 adds the field gradient, and 2 adds the
+	// quadrupole moment of each cell and the field's second derivatives.
+	Order int
+	// Theta is the opening criterion: two cells interact through their
+	// expansions when the sum of their sizes is below Theta times their
+	// separation. Smaller is more accurate and slower.
+	Theta float64
+	// Rebuild discards the tree every step. By default the previous step's
+	// tree is kept and only bodies that left their leaf are reinserted,
+	// which is much cheaper when most bodies stay put.
+	Rebuild bool
+
+	nodes  []fmmNode
+	order  []int // body indices, grouped so every cell owns a contiguous run
+	movers []int // bodies being reinserted
+	// slab backs the leaves' body lists: each leaf owns a chunk with room
+	// for fmmLeafSize bodies, and spare chunks are kept for leaves created
+	// by update, so the tree can change without allocating.
+	slab     []int
+	slabUsed int
+	subtrees []fmmSubtree // built concurrently by build
+	x, y     []float64
+	gm       []float64
+	acc      []Vector2D
+}
+
+// fmmSubtree is a subtree left by the serial top of a parallel build, to be
+// built on its own node list and grafted onto the tree afterwards.
+type fmmSubtree struct {
+	parent, quadrant  int
+	center            Vector2D
+	half              float64
+	start, end, depth int
+	nodes             []fmmNode
+}
+
+type fmmNode struct {
+	center   Vector2D // geometric center of the cell
+	half     float64  // half the cell's side length
+	children [4]int   // indices into nodes, or -1
+	leaf     bool
+	depth    int
+	bodies   []int // indices of the bodies in a leaf
+
+	// Multipole about the center of mass; masses are G·m.
+	gm            float64
+	com           Vector2D
+	qxx, qxy, qyy float64
+
+	// Local expansion about center: field, its Jacobian and its second
+	// derivatives (hXab is ∂a∂b of the field's X component).
+	field              Vector2D
+	jxx, jxy, jyx, jyy float64
+	hxxx, hxxy, hxyy   float64
+	hyxx, hyxy, hyyy   float64
+}
+
+// NewFMM returns an FMM solver with the given expansion order (0–2) and
+// opening angle.
+func NewFMM(order int, theta float64) *FMM {
+	return &FMM{Order: order, Theta: theta}
+}
+
+func (f *FMM) Accelerations(s *Simulation, acc []Vector2D) {
+	f.x, f.y = s.Positions()
+	f.gm = f.gm[:0]
+	for _, m := range s.Masses() {
+		f.gm = append(f.gm, G*m)
+	}
+	f.acc = acc
+	clear(acc)
+	if len(f.gm) == 0 {
+		return
+	}
+
+	setPhase(phaseTree)
+	if f.Rebuild || len(f.order) != len(f.gm) || !f.update() {
+		f.build()
+	}
+	setPhase(phaseForces)
+	f.upward(0)
+	f.interact(0, 0)
+	f.downward(0)
+}
+
+// build sorts the bodies into a fresh quadtree covering their bounding box.
+func (f *FMM) build() {
+	n := len(f.gm)
+	f.order = f.order[:0]
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i := range n {
+		f.order = append(f.order, i)
+		minX, maxX = math.Min(minX, f.x[i]), math.Max(maxX, f.x[i])
+		minY, maxY = math.Min(minY, f.y[i]), math.Max(maxY, f.y[i])
+	}
+	half := math.Max(maxX-minX, maxY-minY)/2*(1+fmmRootMargin) + 1e-9
+	center := Vector2D{X: (minX + maxX) / 2, Y: (minY + maxY) / 2}
+
+	f.nodes = f.nodes[:0]
+	f.subtrees = f.subtrees[:0]
+	parallel := n >= fmmParallelBuild && runtime.GOMAXPROCS(0) > 1
+	f.split(&f.nodes, center, half, 0, n, 0, parallel)
+	if len(f.subtrees) > 0 {
+		f.buildSubtrees()
+	}
+	f.allocLeaves()
+}
+
+// buildSubtrees builds the subtrees split left for workers, then appends
+// them to the tree in order. The cells and the bodies they own come out
+// exactly as a serial build makes them, only numbered differently.
+func (f *FMM) buildSubtrees() {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(f.subtrees)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				k := int(next.Add(1)) - 1
+				if k >= len(f.subtrees) {
+					return
+				}
+				t := &f.subtrees[k]
+				t.nodes = t.nodes[:0]
+				f.split(&t.nodes, t.center, t.half, t.start, t.end, t.depth, false)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for k := range f.subtrees {
+		t := &f.subtrees[k]
+		offset := len(f.nodes)
+		for _, n := range t.nodes {
+			for q, c := range n.children {
+				if c >= 0 {
+					n.children[q] = c + offset
+				}
+			}
+			f.nodes = append(f.nodes, n)
+		}
+		f.nodes[t.parent].children[t.quadrant] = offset
+	}
+}
+
+// allocLeaves copies every leaf's run of order into its own chunk of the
+// slab, growing the slab if it can't hold them plus as many spare chunks.
+func (f *FMM) allocLeaves() {
+	size := 0
+	for idx := range f.nodes {
+		if n := &f.nodes[idx]; n.leaf {
+			size += max(len(n.bodies), fmmLeafSize) + fmmLeafSize
+		}
+	}
+	if cap(f.slab) < size {
+		f.slab = make([]int, size)
+	}
+	f.slab = f.slab[:cap(f.slab)]
+	f.slabUsed = 0
+	for idx := range f.nodes {
+		if n := &f.nodes[idx]; n.leaf {
+			c, _ := f.chunk(max(len(n.bodies), fmmLeafSize))
+			n.bodies = append(c, n.bodies...)
+		}
+	}
+}
+
+// chunk takes an empty body list with room for size bodies from the slab.
+// It reports false when the slab is used up.
+func (f *FMM) chunk(size int) ([]int, bool) {
+	if f.slabUsed+size > len(f.slab) {
+		return nil, false
+	}
+	c := f.slab[f.slabUsed : f.slabUsed : f.slabUsed+size]
+	f.slabUsed += size
+	return c, true
+}
+
+// update keeps the tree from the previous step, moving the bodies that
+// left their leaf into the leaf now containing them. It reports false, after
+// which the tree must be rebuilt, when a body left the root cell, when the
+// slab ran out of chunks for new leaves, or when so many leaves have
+// emptied that the tree no longer fits the bodies.
+func (f *FMM) update() bool {
+	if len(f.nodes) == 0 {
+		return false
+	}
+	root := f.nodes[0]
+	f.movers = f.movers[:0]
+	for idx := range f.nodes {
+		n := &f.nodes[idx]
+		if !n.leaf {
+			continue
+		}
+		for k := 0; k < len(n.bodies); {
+			i := n.bodies[k]
+			if n.contains(f.x[i], f.y[i]) {
+				k++
+				continue
+			}
+			if !root.contains(f.x[i], f.y[i]) {
+				return false
+			}
+			last := len(n.bodies) - 1
+			n.bodies[k] = n.bodies[last]
+			n.bodies = n.bodies[:last]
+			f.movers = append(f.movers, i)
+		}
+	}
+	for _, i := range f.movers {
+		if !f.insert(0, i) {
+			return false
+		}
+	}
+
+	var leaves, empty int
+	for idx := range f.nodes {
+		if f.nodes[idx].leaf {
+			leaves++
+			if len(f.nodes[idx].bodies) == 0 {
+				empty++
+			}
+		}
+	}
+	return empty*4 <= leaves
+}
+
+// insert adds body i to the subtree at idx, creating the cells on its path
+// and splitting a full leaf it lands in. It reports false when the slab has
+// no chunk left for a new leaf.
+func (f *FMM) insert(idx, i int) bool {
+	for {
+		n := &f.nodes[idx]
+		if n.leaf {
+			if len(n.bodies) < cap(n.bodies) || n.depth >= fmmMaxDepth {
+				n.bodies = append(n.bodies, i)
+				return true
+			}
+			// The leaf is full: make it an internal cell, push its bodies
+			// down and try again.
+			bodies := n.bodies
+			n.leaf, n.bodies = false, nil
+			for _, j := range bodies {
+				if !f.insert(idx, j) {
+					return false
+				}
+			}
+			continue
+		}
+
+		q := quadrant(n.center, f.x[i], f.y[i])
+		if n.children[q] < 0 {
+			bodies, ok := f.chunk(fmmLeafSize)
+			if !ok {
+				return false
+			}
+			quarter := n.half / 2
+			c := Vector2D{X: n.center.X - quarter, Y: n.center.Y - quarter}
+			if q&1 != 0 {
+				c.X += n.half
+			}
+			if q&2 != 0 {
+				c.Y += n.half
+			}
+			child := fmmNode{center: c, half: quarter, leaf: true, depth: n.depth + 1, bodies: bodies, children: [4]int{-1, -1, -1, -1}}
+			n.children[q] = len(f.nodes)
+			f.nodes = append(f.nodes, child)
+		}
+		idx = f.nodes[idx].children[q]
+	}
+}
+
+// contains reports whether a position lies in the cell. Cells are
+// half-open, matching the quadrant split.
+func (n *fmmNode) contains(x, y float64) bool {
+	return x >= n.center.X-n.half && x < n.center.X+n.half &&
+		y >= n.center.Y-n.half && y < n.center.Y+n.half
+}
+
+// quadrant returns the quadrant of center containing a position: bit 0 set
+// for the right half, bit 1 for the bottom half.
+func quadrant(center Vector2D, x, y float64) int {
+	q := 0
+	if x >= center.X {
+		q |= 1
+	}
+	if y >= center.Y {
+		q |= 2
+	}
+	return q
+}
+
+// split appends to nodes the cell owning order[start:end] and, unless it
+// is small enough to be a leaf, its children. It returns the new cell's
+// index. When parallel is set, cells fmmParallelDepth levels down are left
+// out and queued in f.subtrees instead.
+func (f *FMM) split(nodes *[]fmmNode, center Vector2D, half float64, start, end, depth int, parallel bool) int {
+	idx := len(*nodes)
+	*nodes = append(*nodes, fmmNode{center: center, half: half, depth: depth, children: [4]int{-1, -1, -1, -1}})
+	if end-start <= fmmLeafSize || depth >= fmmMaxDepth {
+		(*nodes)[idx].leaf = true
+		(*nodes)[idx].bodies = f.order[start:end] // moved to the slab by allocLeaves
+		return idx
+	}
+
+	bounds := [5]int{start, start, start, start, end}
+	for q := 0; q < 3; q++ {
+		lo := bounds[q]
+		for k := lo; k < end; k++ {
+			if quadrant(center, f.x[f.order[k]], f.y[f.order[k]]) == q {
+				f.order[lo], f.order[k] = f.order[k], f.order[lo]
+				lo++
+			}
+		}
+		bounds[q+1] = lo
+	}
+
+	quarter := half / 2
+	for q := 0; q < 4; q++ {
+		if bounds[q] == bounds[q+1] {
+			continue
+		}
+		c := Vector2D{X: center.X - quarter, Y: center.Y - quarter}
+		if q&1 != 0 {
+			c.X += half
+		}
+		if q&2 != 0 {
+			c.Y += half
+		}
+		if parallel && depth+1 == fmmParallelDepth {
+			if len(f.subtrees) == cap(f.subtrees) {
+				f.subtrees = append(f.subtrees, fmmSubtree{})
+			} else {
+				f.subtrees = f.subtrees[:len(f.subtrees)+1] // keep its nodes
+			}
+			t := &f.subtrees[len(f.subtrees)-1]
+			t.parent, t.quadrant = idx, q
+			t.center, t.half = c, quarter
+			t.start, t.end, t.depth = bounds[q], bounds[q+1], depth+1
+			continue
+		}
+		child := f.split(nodes, c, quarter, bounds[q], bounds[q+1], depth+1, parallel)
+		(*nodes)[idx].children[q] = child
+	}
+	return idx
+}
+
+// upward computes the multipole of cell idx and all cells below it.
+func (f *FMM) upward(idx int) {
+	n := &f.nodes[idx]
+	n.field, n.jxx, n.jxy, n.jyx, n.jyy = Vector2D{}, 0, 0, 0, 0
+	n.hxxx, n.hxxy, n.hxyy, n.hyxx, n.hyxy, n.hyyy = 0, 0, 0, 0, 0, 0
+	var gm, sx, sy float64
+	if n.leaf {
+		for _, i := range n.bodies {
+			gm += f.gm[i]
+			sx += f.gm[i] * f.x[i]
+			sy += f.gm[i] * f.y[i]
+		}
+	} else {
+		for _, c := range n.children {
+			if c < 0 {
+				continue
+			}
+			f.upward(c)
+			child := &f.nodes[c]
+			gm += child.gm
+			sx += child.gm * child.com.X
+			sy += child.gm * child.com.Y
+		}
+	}
+	n.gm = gm
+	n.com = n.center
+	if gm != 0 {
+		n.com = Vector2D{X: sx / gm, Y: sy / gm}
+	}
+	if f.Order < 2 {
+		return
+	}
+
+	n.qxx, n.qxy, n.qyy = 0, 0, 0
+	if n.leaf {
+		for _, i := range n.bodies {
+			dx, dy := f.x[i]-n.com.X, f.y[i]-n.com.Y
+			n.qxx += f.gm[i] * dx * dx
+			n.qxy += f.gm[i] * dx * dy
+			n.qyy += f.gm[i] * dy * dy
+		}
+		return
+	}
+	for _, c := range n.children {
+		if c < 0 {
+			continue
+		}
+		child := &f.nodes[c]
+		dx, dy := child.com.X-n.com.X, child.com.Y-n.com.Y
+		n.qxx += child.qxx + child.gm*dx*dx
+		n.qxy += child.qxy + child.gm*dx*dy
+		n.qyy += child.qyy + child.gm*dy*dy
+	}
+}
+
+// interact accumulates the field of cell b onto cell a.
+func (f *FMM) interact(a, b int) {
+	A, B := &f.nodes[a], &f.nodes[b]
+	if B.gm == 0 {
+		return
+	}
+	dx, dy := B.com.X-A.center.X, B.com.Y-A.center.Y
+	dist := math.Hypot(dx, dy)
+	if a != b && (A.half+B.half)*math.Sqrt2 < f.Theta*dist {
+		f.multipoleToLocal(A, B, dx, dy, dist)
+		return
+	}
+
+	if A.leaf && B.leaf {
+		for _, i := range A.bodies {
+			var ax, ay float64
+			for _, j := range B.bodies {
+				if i != j {
+					gx, gy := PairAcceleration(f.x[j]-f.x[i], f.y[j]-f.y[i], f.gm[j])
+					ax += gx
+					ay += gy
+				}
+			}
+			f.acc[i].X += ax
+			f.acc[i].Y += ay
+		}
+		return
+	}
+
+	if A.leaf || (!B.leaf && B.half >= A.half) {
+		for _, c := range B.children {
+			if c >= 0 {
+				f.interact(a, c)
+			}
+		}
+		return
+	}
+	for _, c := range A.children {
+		if c >= 0 {
+			f.interact(c, b)
+		}
+	}
+}
+
+// multipoleToLocal adds the expansion of B, seen from offset (dx, dy) at
+// distance r, to A's local expansion.
+func (f *FMM) multipoleToLocal(A, B *fmmNode, dx, dy, r float64) {
+	const s2 = Softening * Softening
+	d := r*r*r + r*s2
+	dd := 3*r*r + s2
+	g := ScaleFactor / d
+	h := -ScaleFactor * dd / (r * d * d) // g'(r)/r
+
+	A.field.X += B.gm * dx * g
+	A.field.Y += B.gm * dy * g
+
+	if f.Order >= 1 {
+		// The field at x is K(com - x), so its Jacobian is minus the
+		// kernel's: -(δij g + di dj h).
+		A.jxx -= B.gm * (g + dx*dx*h)
+		A.jxy -= B.gm * dx * dy * h
+		A.jyx -= B.gm * dy * dx * h
+		A.jyy -= B.gm * (g + dy*dy*h)
+	}
+
+	if f.Order >= 2 {
+		// ½ Σjk Qjk ∂j∂k Ki = ½ [h (2 (Q d)i + tr(Q) di) + di (dᵀQd) h'/r]
+		hp := -ScaleFactor * (6/(d*d) - dd/(r*r*d*d) - 2*dd*dd/(r*d*d*d))
+		qdx := B.qxx*dx + B.qxy*dy
+		qdy := B.qxy*dx + B.qyy*dy
+		tr := B.qxx + B.qyy
+		dqd := dx*qdx + dy*qdy
+		A.field.X += 0.5 * (h*(2*qdx+tr*dx) + dx*dqd*hp/r)
+		A.field.Y += 0.5 * (h*(2*qdy+tr*dy) + dy*dqd*hp/r)
+
+		// ∂j∂k Ki = (δij dk + δik dj + δjk di) h + di dj dk h'/r; the two
+		// sign flips from differentiating K(com - x) cancel.
+		p := B.gm * hp / r
+		gh := B.gm * h
+		A.hxxx += 3*dx*gh + dx*dx*dx*p
+		A.hxxy += dy*gh + dx*dx*dy*p
+		A.hxyy += dx*gh + dx*dy*dy*p
+		A.hyxx += dy*gh + dy*dx*dx*p
+		A.hyxy += dx*gh + dy*dy*dx*p
+		A.hyyy += 3*dy*gh + dy*dy*dy*p
+	}
+}
+
+// downward shifts each cell's local expansion into its children and
+// evaluates it at the bodies of the leaves.
+func (f *FMM) downward(idx int) {
+	n := &f.nodes[idx]
+	if n.leaf {
+		for _, i := range n.bodies {
+			a := n.evaluate(f.x[i]-n.center.X, f.y[i]-n.center.Y)
+			f.acc[i].X += a.X
+			f.acc[i].Y += a.Y
+		}
+		return
+	}
+	for _, c := range n.children {
+		if c < 0 {
+			continue
+		}
+		child := &f.nodes[c]
+		dx, dy := child.center.X-n.center.X, child.center.Y-n.center.Y
+		child.field = AddVectors(child.field, n.evaluate(dx, dy))
+		child.jxx += n.jxx + n.hxxx*dx + n.hxxy*dy
+		child.jxy += n.jxy + n.hxxy*dx + n.hxyy*dy
+		child.jyx += n.jyx + n.hyxx*dx + n.hyxy*dy
+		child.jyy += n.jyy + n.hyxy*dx + n.hyyy*dy
+		child.hxxx += n.hxxx
+		child.hxxy += n.hxxy
+		child.hxyy += n.hxyy
+		child.hyxx += n.hyxx
+		child.hyxy += n.hyxy
+		child.hyyy += n.hyyy
+		f.downward(c)
+	}
+}
+
+// evaluate returns the cell's local expansion at offset (dx, dy) from its
+// center.
+func (n *fmmNode) evaluate(dx, dy float64) Vector2D {
+	return Vector2D{
+		X: n.field.X + n.jxx*dx + n.jxy*dy + 0.5*(n.hxxx*dx*dx+2*n.hxxy*dx*dy+n.hxyy*dy*dy),
+		Y: n.field.Y + n.jyx*dx + n.jyy*dy + 0.5*(n.hyxx*dx*dx+2*n.hyxy*dx*dy+n.hyyy*dy*dy),
+	}
+}
@@ -0,0 +1,103 @@
+package nbody
+
+import (
+	"math"
+	"testing"
+)
+
+// directAccelerations returns s's accelerations from the direct sum.
+func directAccelerations(s *Simulation) []Vector2D {
+	solver := s.Solver
+	s.Solver = nil
+	s.computeAccelerations(nil)
+	s.Solver = solver
+	return append([]Vector2D(nil), s.accelerations...)
+}
+
+// relativeError returns the RMS difference between got and want relative to
+// the RMS magnitude of want.
+func relativeError(got, want []Vector2D) float64 {
+	var diff, norm float64
+	for i := range want {
+		dx, dy := got[i].X-want[i].X, got[i].Y-want[i].Y
+		diff += dx*dx + dy*dy
+		norm += want[i].X*want[i].X + want[i].Y*want[i].Y
+	}
+	return math.Sqrt(diff / norm)
+}
+
+// TestFMMAccuracy compares FMM with the direct sum on a random disk: each
+// order must bring the error within a bound tighter than the last.
+func TestFMMAccuracy(t *testing.T) {
+	s := testDisk(500)
+	want := directAccelerations(s)
+	bounds := []float64{0.15, 0.02, 3e-3}
+	last := math.Inf(1)
+	for order, bound := range bounds {
+		got := make([]Vector2D, s.Len())
+		NewFMM(order, 0.5).Accelerations(s, got)
+		err := relativeError(got, want)
+		if err > bound {
+			t.Errorf("order %d: relative error %.2g, want below %g", order, err, bound)
+		}
+		if err >= last {
+			t.Errorf("order %d: relative error %.2g, no better than order %d's %.2g", order, err, order-1, last)
+		}
+		last = err
+	}
+}
+
+// worstError returns the largest difference between got and want for any
+// body, relative to the RMS magnitude of want, so that one body missing
+// from the tree stands out however many others are right.
+func worstError(got, want []Vector2D) float64 {
+	var worst, norm float64
+	for i := range want {
+		worst = max(worst, math.Hypot(got[i].X-want[i].X, got[i].Y-want[i].Y))
+		norm += want[i].X*want[i].X + want[i].Y*want[i].Y
+	}
+	return worst / math.Sqrt(norm/float64(len(want)))
+}
+
+// TestFMMUpdate steps a disk, with fast bodies crossing cells and wrapping
+// around the world's edges, and checks every step that the tree kept and
+// updated from the step before gives the accelerations of a tree built
+// afresh. The two trees' cells differ once bodies move, so they agree to
+// the expansions' truncation error rather than to rounding.
+func TestFMMUpdate(t *testing.T) {
+	s := testDisk(400)
+	for i := range 16 {
+		y := 50 + 700*float64(i)/16
+		s.AddBody(Body{Position: Vector2D{X: 2, Y: y}, Velocity: Vector2D{X: -300, Y: 40}, Mass: 1e24, Radius: 0.01})
+		s.AddBody(Body{Position: Vector2D{X: 998, Y: y}, Velocity: Vector2D{X: 300, Y: -40}, Mass: 1e24, Radius: 0.01})
+	}
+	incremental := NewFMM(2, 0.5)
+	fresh := &FMM{Order: 2, Theta: 0.5, Rebuild: true}
+	got, want := make([]Vector2D, s.Len()), make([]Vector2D, s.Len())
+	moved, wrapped := 0, 0
+	for step := range 300 {
+		incremental.Accelerations(s, got)
+		fresh.Accelerations(s, want)
+		if err := relativeError(got, want); err > 3e-3 {
+			t.Fatalf("step %d: updated tree differs from a fresh one by %.2g overall", step, err)
+		}
+		if err := worstError(got, want); err > 0.05 {
+			t.Fatalf("step %d: updated tree differs from a fresh one by %.2g for some body", step, err)
+		}
+		moved += len(incremental.movers)
+		x, _ := s.Positions()
+		before := append([]float64(nil), x...)
+		s.Update()
+		for i := range before {
+			if math.Abs(x[i]-before[i]) > s.Width/2 {
+				wrapped++
+			}
+		}
+	}
+	if moved == 0 {
+		t.Error("no body changed leaves")
+	}
+	if wrapped == 0 {
+		t.Error("no body wrapped around the world")
+	}
+}
@@ -0,0 +1,42 @@
+package nbody
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMONDConvertsA0(t *testing.T) {
+	m := MOND{Enabled: true, A0: DefaultMONDA0}
+	// At aN = a0 the simple interpolation function boosts gravity by
+	// 1/2 + sqrt(5/4), once a0 is in the simulation's units.
+	aN := DefaultMONDA0 * AccelScale
+	got := m.apply(Vector2D{X: aN}).X / aN
+	if want := 0.5 + math.Sqrt(1.25); math.Abs(got-want) > 1e-12 {
+		t.Errorf("boost at a0 = %v, want %v", got, want)
+	}
+	// Far above a0 gravity is Newtonian.
+	if got := m.apply(Vector2D{X: 1e6 * aN}).X / (1e6 * aN); math.Abs(got-1) > 1e-3 {
+		t.Errorf("boost far above a0 = %v, want 1", got)
+	}
+}
+
+// TestMONDLeavesCoulombAlone checks that MOND boosts only gravity: two
+// charged bodies too light to attract each other noticeably accelerate the
+// same with it on as off. MOND used to boost the total, Coulomb force
+// included.
+func TestMONDLeavesCoulombAlone(t *testing.T) {
+	accelerations := func(mond bool) []Vector2D {
+		s := NewSimulation(1000, 1000)
+		s.AddBody(Body{Position: Vector2D{X: 400, Y: 500}, Mass: 1, Charge: 1e3})
+		s.AddBody(Body{Position: Vector2D{X: 600, Y: 500}, Mass: 1, Charge: -1e3})
+		s.MOND = MOND{Enabled: mond, A0: DefaultMONDA0}
+		s.computeAccelerations(nil)
+		return append([]Vector2D(nil), s.accelerations...)
+	}
+	off, on := accelerations(false), accelerations(true)
+	for i := range off {
+		if d := math.Hypot(on[i].X-off[i].X, on[i].Y-off[i].Y); d > 1e-9*math.Hypot(off[i].X, off[i].Y) {
+			t.Errorf("body %d accelerates at %v with MOND, %v without", i, on[i], off[i])
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package nbody
+
+import (
+	"slices"
+	"unsafe"
+)
+
+// Snapshot is a copy of a simulation's bodies and clock, taken by
+// Simulation.Snapshot and put back by Simulation.Restore. Solvers,
+// precision and other options are not part of it.
+type Snapshot struct {
+	posX, posY []float64
+	velX, velY []float64
+	mass       []float64
+	charge     []float64
+	info       []bodyInfo
+
+	constraints []Constraint
+	time        float64
+	steps       int
+	nextID      int
+	mergers     int // length of the merger tree
+}
+
+// Time returns the simulated time the snapshot was taken at.
+func (snap *Snapshot) Time() float64 { return snap.time }
+
+// Snapshot copies the state of s into snap, reusing its storage.
+func (s *Simulation) Snapshot(snap *Snapshot) {
+	snap.posX = append(snap.posX[:0], s.posX...)
+	snap.posY = append(snap.posY[:0], s.posY...)
+	snap.velX = append(snap.velX[:0], s.velX...)
+	snap.velY = append(snap.velY[:0], s.velY...)
+	snap.mass = append(snap.mass[:0], s.mass...)
+	snap.charge = append(snap.charge[:0], s.charge...)
+	snap.info = append(snap.info[:0], s.info...)
+	snap.constraints = append(snap.constraints[:0], s.Constraints...)
+	snap.time, snap.steps, snap.nextID = s.Time, s.Steps, s.nextID
+	snap.mergers = 0
+	if s.Mergers != nil {
+		snap.mergers = len(s.Mergers.Events)
+	}
+}
+
+// SnapshotSize returns roughly how many bytes a snapshot of s takes, for
+// fitting a history into a memory budget.
+func (s *Simulation) SnapshotSize() int {
+	return int(unsafe.Sizeof(Snapshot{})) + s.Len()*bodySnapshotSize + len(s.Constraints)*int(unsafe.Sizeof(Constraint(nil)))
+}
+
+// bodySnapshotSize is the number of bytes a snapshot takes per body.
+const bodySnapshotSize = 6*int(unsafe.Sizeof(float64(0))) + int(unsafe.Sizeof(bodyInfo{}))
+
+// size returns the bytes snap holds, including storage kept for reuse.
+func (snap *Snapshot) size() int {
+	floats := cap(snap.posX) + cap(snap.posY) + cap(snap.velX) + cap(snap.velY) + cap(snap.mass) + cap(snap.charge)
+	return int(unsafe.Sizeof(*snap)) + floats*int(unsafe.Sizeof(float64(0))) +
+		cap(snap.info)*int(unsafe.Sizeof(bodyInfo{})) + cap(snap.constraints)*int(unsafe.Sizeof(Constraint(nil)))
+}
+
+// Restore returns s to the state in snap, forgetting the mergers recorded
+// since.
+func (s *Simulation) Restore(snap *Snapshot) {
+	s.posX = append(s.posX[:0], snap.posX...)
+	s.posY = append(s.posY[:0], snap.posY...)
+	s.velX = append(s.velX[:0], snap.velX...)
+	s.velY = append(s.velY[:0], snap.velY...)
+	s.mass = append(s.mass[:0], snap.mass...)
+	s.charge = append(s.charge[:0], snap.charge...)
+	s.info = append(s.info[:0], snap.info...)
+	s.Constraints = append(s.Constraints[:0], snap.constraints...)
+	s.Time, s.Steps, s.nextID = snap.time, snap.steps, snap.nextID
+	s.accelerations = s.accelerations[:0]
+	if s.Mergers != nil && len(s.Mergers.Events) > snap.mergers {
+		s.Mergers.Events = s.Mergers.Events[:snap.mergers]
+	}
+}
+
+// History is a ring buffer of the most recent snapshots of a simulation,
+// taken every Every updates, to roll back to when something goes wrong.
+// Slots are allocated as snapshots are taken, so a large size costs
+// nothing until it is used.
+type History struct {
+	Every int // updates between snapshots; zero means every update
+	// Budget, if positive, is the most bytes the snapshots may take. When
+	// a snapshot would exceed it, as when bodies are added, the oldest are
+	// dropped to make room.
+	Budget int
+
+	size  int // most snapshots held
+	snaps []Snapshot
+	next  int // slot the next snapshot goes into
+	n     int // snapshots held
+}
+
+// NewHistory returns a history holding up to size snapshots, one every
+// every updates.
+func NewHistory(size, every int) *History {
+	return &History{Every: every, size: size}
+}
+
+// Record snapshots s if it is due. Call it after every Update.
+func (h *History) Record(s *Simulation) {
+	if h.size <= 0 || s.Steps%max(h.Every, 1) != 0 {
+		return
+	}
+	if h.n == len(h.snaps) && len(h.snaps) < h.size {
+		// Every slot is in use: add one where the newest goes, before the
+		// oldest.
+		h.snaps = slices.Insert(h.snaps, h.next, Snapshot{})
+	}
+	s.Snapshot(&h.snaps[h.next])
+	h.next = (h.next + 1) % len(h.snaps)
+	h.n = min(h.n+1, len(h.snaps))
+	if h.Budget > 0 {
+		h.trim()
+	}
+}
+
+// trim drops the oldest snapshots, freeing their storage, until the
+// history fits its budget.
+func (h *History) trim() {
+	bytes := 0
+	for i := range h.snaps {
+		bytes += h.snaps[i].size()
+	}
+	for bytes > h.Budget && h.n > 0 {
+		oldest := &h.snaps[(h.next-h.n+len(h.snaps))%len(h.snaps)]
+		bytes -= oldest.size()
+		*oldest = Snapshot{}
+		bytes += oldest.size()
+		h.n--
+	}
+}
+
+// Clear drops every snapshot, as when the simulation is replaced.
+func (h *History) Clear() {
+	h.next, h.n = 0, 0
+}
+
+// Len returns the number of snapshots held.
+func (h *History) Len() int { return h.n }
+
+// Rollback restores s to the newest snapshot and drops it, so rolling
+// back again goes further into the past. It reports false when there is
+// none left.
+func (h *History) Rollback(s *Simulation) bool {
+	if h.n == 0 {
+		return false
+	}
+	h.next = (h.next - 1 + len(h.snaps)) % len(h.snaps)
+	h.n--
+	s.Restore(&h.snaps[h.next])
+	return true
+}
+
+// At returns the i-th oldest snapshot held, for i from 0 to Len()-1.
+func (h *History) At(i int) *Snapshot {
+	return &h.snaps[(h.next-h.n+i+2*len(h.snaps))%len(h.snaps)]
+}
+
+// Rewind restores s to the i-th oldest snapshot and drops the newer ones,
+// so the simulation carries on from there.
+func (h *History) Rewind(s *Simulation, i int) {
+	s.Restore(h.At(i))
+	h.next = (h.next - h.n + i + 1 + 2*len(h.snaps)) % len(h.snaps)
+	h.n = i + 1
+}
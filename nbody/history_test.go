@@ -0,0 +1,141 @@
+package nbody
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistoryRollback(t *testing.T) {
+	s := testDisk(50)
+	h := NewHistory(3, 10)
+	var want []Body
+	for range 50 {
+		s.Update()
+		h.Record(s)
+		if s.Steps == 30 {
+			want = s.AppendBodies(nil)
+		}
+	}
+	if h.Len() != 3 {
+		t.Fatalf("%d snapshots held, want 3", h.Len())
+	}
+
+	s.SetPosition(7, Vector2D{X: math.NaN()})
+	err := s.CheckFinite()
+	nf, ok := err.(*NonFiniteError)
+	if !ok {
+		t.Fatalf("CheckFinite returned %v, want a *NonFiniteError", err)
+	}
+	if nf.ID != s.ID(7) || nf.Step != 50 {
+		t.Fatalf("CheckFinite reported body %d at step %d, want body %d at step 50", nf.ID, nf.Step, s.ID(7))
+	}
+
+	for _, steps := range []int{50, 40, 30} {
+		if !h.Rollback(s) {
+			t.Fatalf("no snapshot for step %d", steps)
+		}
+		if s.Steps != steps {
+			t.Fatalf("rolled back to step %d, want %d", s.Steps, steps)
+		}
+	}
+	if h.Rollback(s) {
+		t.Fatal("rolled back past the oldest snapshot")
+	}
+	if err := s.CheckFinite(); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range s.AppendBodies(nil) {
+		if b != want[i] {
+			t.Fatalf("body %d is %+v after rollback, want %+v", i, b, want[i])
+		}
+	}
+}
+
+func TestHistoryRewind(t *testing.T) {
+	s := testDisk(50)
+	h := NewHistory(4, 10)
+	for range 70 {
+		s.Update()
+		h.Record(s)
+	}
+	for i, steps := range []int{40, 50, 60, 70} {
+		if got := h.At(i).Time(); math.Abs(got-float64(steps)*s.StepSize()) > 1e-9 {
+			t.Fatalf("snapshot %d taken at t=%g, want step %d", i, got, steps)
+		}
+	}
+	h.Rewind(s, 1)
+	if s.Steps != 50 || h.Len() != 2 {
+		t.Fatalf("rewound to step %d with %d snapshots left, want step 50 with 2", s.Steps, h.Len())
+	}
+	for range 10 {
+		s.Update()
+		h.Record(s)
+	}
+	if h.Len() != 3 || h.At(2).Time() != s.Time {
+		t.Fatalf("%d snapshots after resuming, newest at t=%g; want 3, newest at t=%g", h.Len(), h.At(2).Time(), s.Time)
+	}
+}
+
+func TestHistoryAllocatesLazily(t *testing.T) {
+	s := testDisk(10)
+	h := NewHistory(1<<28, 1)
+	for range 5 {
+		s.Update()
+		h.Record(s)
+	}
+	if h.Len() != 5 || len(h.snaps) != 5 {
+		t.Fatalf("%d snapshots in %d slots, want 5 in 5", h.Len(), len(h.snaps))
+	}
+	for i := range 5 {
+		if got, want := h.At(i).steps, i+1; got != want {
+			t.Errorf("snapshot %d taken at step %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestHistoryBudget(t *testing.T) {
+	s := testDisk(50)
+	h := NewHistory(100, 1)
+	h.Budget = 4*s.SnapshotSize() + s.SnapshotSize()/2
+	for range 10 {
+		s.Update()
+		h.Record(s)
+	}
+	if h.Len() != 4 {
+		t.Fatalf("%d snapshots held within a budget of 4.5, want 4", h.Len())
+	}
+	if got := h.At(3).steps; got != s.Steps {
+		t.Fatalf("newest snapshot taken at step %d, want %d", got, s.Steps)
+	}
+
+	// A snapshot of twice the bodies takes the room of two, so two of the
+	// older ones make way for it.
+	for _, b := range s.AppendBodies(nil) {
+		b.Position.X += 1
+		s.AddBody(b)
+	}
+	s.Update()
+	h.Record(s)
+	bytes := 0
+	for i := range h.snaps {
+		bytes += h.snaps[i].size()
+	}
+	if bytes > h.Budget {
+		t.Errorf("snapshots take %d bytes, over the budget of %d", bytes, h.Budget)
+	}
+	if h.Len() != 3 || h.At(2).steps != s.Steps {
+		t.Errorf("%d snapshots held after doubling the bodies, want 3 ending with the newest", h.Len())
+	}
+}
+
+func TestSnapshotSize(t *testing.T) {
+	s := testDisk(50)
+	var snap Snapshot
+	s.Snapshot(&snap)
+	if got, want := s.SnapshotSize(), snap.size(); got > want || got < want*9/10 {
+		t.Errorf("SnapshotSize() = %d, but a snapshot takes %d bytes", got, want)
+	}
+	if got := NewSimulation(10, 10).SnapshotSize(); got == 0 {
+		t.Error("a snapshot of an empty simulation takes no bytes")
+	}
+}
@@ -0,0 +1,79 @@
+package nbody
+
+import "math"
+
+// LagrangePoints returns the five Lagrange points of a pair of bodies as
+// they are placed now: the points where a test particle would keep its
+// place relative to the pair if the pair moved on a circular orbit. L1 lies
+// between the bodies, L2 beyond the lighter one and L3 beyond the heavier;
+// L4 leads the lighter body in its orbit and L5 trails it. The collinear
+// points are solved for the softened force law, which moves them from
+// their Newtonian places when the bodies are closer than a few Softening
+// lengths; the triangular points are equilateral under any central force.
+// ok is false if the bodies coincide or have no mass.
+func LagrangePoints(a, b Body) (points [5]Vector2D, ok bool) {
+	primary, secondary := a, b
+	if b.Mass > a.Mass {
+		primary, secondary = b, a
+	}
+	dx, dy := secondary.Position.X-primary.Position.X, secondary.Position.Y-primary.Position.Y
+	d := math.Hypot(dx, dy)
+	total := primary.Mass + secondary.Mass
+	if !(d > 0) || !(total > 0) {
+		return points, false
+	}
+
+	// Solve along the x axis of a frame centered on the barycenter with
+	// the secondary on the positive side.
+	mu := secondary.Mass / total
+	x1, x2 := -mu*d, (1-mu)*d
+	rel, _ := PairAcceleration(d, 0, G*total)
+	spin2 := rel / d // squared angular velocity of a circular orbit
+	force := func(x float64) float64 {
+		a1, _ := PairAcceleration(x1-x, 0, G*primary.Mass)
+		a2, _ := PairAcceleration(x2-x, 0, G*secondary.Mass)
+		return a1 + a2 + spin2*x
+	}
+	eps := d * 1e-9
+	l1 := bisect(force, x1+eps, x2-eps)
+	l2 := bisect(force, x2+eps, x2+d)
+	l3 := bisect(force, x1-d, x1-eps)
+
+	// Orient the frame: x toward the secondary, y toward where it is going.
+	ex, ey := dx/d, dy/d
+	nx, ny := -ey, ex
+	rvx, rvy := secondary.Velocity.X-primary.Velocity.X, secondary.Velocity.Y-primary.Velocity.Y
+	if dx*rvy-dy*rvx < 0 {
+		nx, ny = -nx, -ny
+	}
+	bary := Barycenter([]Body{primary, secondary})
+	at := func(x, y float64) Vector2D {
+		return Vector2D{X: bary.X + x*ex + y*nx, Y: bary.Y + x*ey + y*ny}
+	}
+	mid, h := (x1+x2)/2, d*math.Sqrt(3)/2
+	return [5]Vector2D{at(l1, 0), at(l2, 0), at(l3, 0), at(mid, h), at(mid, -h)}, true
+}
+
+// bisect finds a root of f between lo and hi, widening the interval away
+// from lo until f changes sign across it.
+func bisect(f func(float64) float64, lo, hi float64) float64 {
+	flo := f(lo)
+	for range 64 {
+		if (f(hi) < 0) != (flo < 0) {
+			break
+		}
+		hi = lo + 2*(hi-lo)
+	}
+	for range 200 {
+		mid := (lo + hi) / 2
+		if mid == lo || mid == hi {
+			break
+		}
+		if (f(mid) < 0) == (flo < 0) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,61 @@
+package nbody
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLagrangePointsSunEarth(t *testing.T) {
+	const d = 1.496e11 // far beyond Softening, so the force is Newtonian
+	sun := Body{Mass: 1.989e30}
+	// Earth orbits counterclockwise, so L4 is above the x axis.
+	earth := Body{Position: Vector2D{X: d}, Velocity: Vector2D{Y: 1}, Mass: 5.972e24}
+	mu := earth.Mass / (sun.Mass + earth.Mass)
+
+	points, ok := LagrangePoints(earth, sun)
+	if !ok {
+		t.Fatal("no Lagrange points for the Sun and Earth")
+	}
+	// Distances from the barycenter in units of d for mu = 3.0025e-6,
+	// putting L1 and L2 about 1.5 million km from Earth and L3 just
+	// beyond Earth's orbit on the far side of the Sun, 1 - 7mu/12 from it.
+	bary := mu * d
+	want := [5]Vector2D{
+		{X: bary + 0.9900277*d},
+		{X: bary + 1.0100330*d},
+		{X: bary - 1.0000013*d},
+		{X: d / 2, Y: d * math.Sqrt(3) / 2},
+		{X: d / 2, Y: -d * math.Sqrt(3) / 2},
+	}
+	for i, p := range points {
+		if e := math.Hypot(p.X-want[i].X, p.Y-want[i].Y); e > 1e-6*d {
+			t.Errorf("L%d = %v, want %v (off by %.2g d)", i+1, p, want[i], e/d)
+		}
+	}
+
+	// The order of the bodies doesn't matter.
+	if swapped, _ := LagrangePoints(sun, earth); swapped != points {
+		t.Errorf("LagrangePoints(sun, earth) = %v, want %v", swapped, points)
+	}
+	// Orbiting the other way swaps L4 and L5.
+	earth.Velocity.Y = -1
+	if retro, _ := LagrangePoints(earth, sun); retro[3] != points[4] || retro[4] != points[3] {
+		t.Errorf("L4 and L5 of a retrograde orbit = %v, %v; want %v, %v", retro[3], retro[4], points[4], points[3])
+	}
+	if _, ok := LagrangePoints(sun, sun); ok {
+		t.Error("Lagrange points for coincident bodies")
+	}
+}
+
+func TestBisect(t *testing.T) {
+	f := func(x float64) float64 { return x*x - 2 }
+	// The interval is widened until it brackets the root.
+	for _, hi := range []float64{2, 1.5, 0.1} {
+		if got := bisect(f, 0, hi); math.Abs(got-math.Sqrt2) > 1e-12 {
+			t.Errorf("bisect(x²-2, 0, %v) = %v, want √2", hi, got)
+		}
+	}
+	if got := bisect(f, 0, -0.5); math.Abs(got+math.Sqrt2) > 1e-12 {
+		t.Errorf("bisect(x²-2, 0, -0.5) = %v, want -√2", got)
+	}
+}
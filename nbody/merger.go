@@ -0,0 +1,166 @@
+package nbody
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MergerEvent records a single collision in which the body From was absorbed
+// into the body Into.
+type MergerEvent struct {
+	Time       float64 `json:"time"`
+	Into       int     `json:"into"`
+	From       int     `json:"from"`
+	IntoMass   float64 `json:"intoMass"`
+	FromMass   float64 `json:"fromMass"`
+	ResultMass float64 `json:"resultMass"`
+}
+
+// MergerTree is the lineage of every body that took part in a merger, in the
+// order the mergers happened.
+type MergerTree struct {
+	Events []MergerEvent `json:"events"`
+}
+
+func (t *MergerTree) record(e MergerEvent) {
+	t.Events = append(t.Events, e)
+}
+
+// WriteJSON writes the merger tree as indented JSON.
+func (t *MergerTree) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// WriteDOT writes the merger tree as a Graphviz digraph with an edge from
+// each absorbed body to the body it merged into.
+func (t *MergerTree) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph mergers {"); err != nil {
+		return err
+	}
+	for _, e := range t.Events {
+		_, err := fmt.Fprintf(w, "\tb%d -> b%d [label=\"t=%.2f\\n%.3g + %.3g = %.3g\"];\n",
+			e.From, e.Into, e.Time, e.IntoMass, e.FromMass, e.ResultMass)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// Save writes the merger tree to path, as DOT if the extension is .dot or
+// .gv and as JSON otherwise.
+func (t *MergerTree) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dot", ".gv":
+		err = t.WriteDOT(f)
+	default:
+		err = t.WriteJSON(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// mergeCollisions merges every pair of overlapping bodies into one,
+// conserving mass and momentum. The more massive body survives and keeps its
+// ID; the merged body keeps the combined volume, drawn and physical. Candidate pairs come from
+// a spatial hash, so the cost grows with the number of bodies rather than
+// the number of pairs.
+func (s *Simulation) mergeCollisions() {
+	s.grid.build(s)
+	s.dead = grow(s.dead, s.Len())
+	clear(s.dead)
+	merged := false
+	for i := range s.posX {
+		if s.dead[i] {
+			continue
+		}
+		for {
+			j := s.grid.firstOverlap(s, i, s.dead)
+			if j < 0 {
+				break
+			}
+			// The merged body may now overlap bodies already checked, so
+			// look again.
+			s.merge(i, j)
+			s.dead[j] = true
+			merged = true
+		}
+	}
+	if merged {
+		s.removeDead()
+	}
+}
+
+// merge replaces body i with the merger of bodies i and j.
+func (s *Simulation) merge(i, j int) {
+	a, b := s.Body(i), s.Body(j)
+	into, from := &a, &b
+	if b.Mass > a.Mass {
+		into, from = &b, &a
+	}
+	mass := into.Mass + from.Mass
+	if s.Mergers != nil {
+		s.Mergers.record(MergerEvent{
+			Time:       s.Time,
+			Into:       into.ID,
+			From:       from.ID,
+			IntoMass:   into.Mass,
+			FromMass:   from.Mass,
+			ResultMass: mass,
+		})
+	}
+
+	merged := *into
+	merged.Position = ScaleVector(AddVectors(ScaleVector(into.Position, into.Mass), ScaleVector(from.Position, from.Mass)), 1/mass)
+	merged.Velocity = ScaleVector(AddVectors(ScaleVector(into.Velocity, into.Mass), ScaleVector(from.Velocity, from.Mass)), 1/mass)
+	merged.Mass = mass
+	merged.Charge = into.Charge + from.Charge
+	merged.Radius = math.Cbrt(math.Pow(into.Radius, 3) + math.Pow(from.Radius, 3))
+	// An unknown physical radius is zero, so it adds no volume.
+	merged.PhysicalRadius = math.Cbrt(math.Pow(into.PhysicalRadius, 3) + math.Pow(from.PhysicalRadius, 3))
+	s.SetBody(i, merged)
+}
+
+// removeDead removes the bodies marked in s.dead, keeping the order of the
+// rest.
+func (s *Simulation) removeDead() {
+	// The accelerations stay aligned with the survivors for
+	// AppendAccelerations.
+	accelerated := len(s.accelerations) == len(s.posX)
+	n := 0
+	for i := range s.posX {
+		if s.dead[i] {
+			continue
+		}
+		s.posX[n], s.posY[n] = s.posX[i], s.posY[i]
+		s.velX[n], s.velY[n] = s.velX[i], s.velY[i]
+		s.mass[n], s.charge[n] = s.mass[i], s.charge[i]
+		s.info[n] = s.info[i]
+		if accelerated {
+			s.accelerations[n] = s.accelerations[i]
+		}
+		n++
+	}
+	s.posX, s.posY = s.posX[:n], s.posY[:n]
+	s.velX, s.velY = s.velX[:n], s.velY[:n]
+	s.mass, s.charge = s.mass[:n], s.charge[:n]
+	clear(s.info[n:])
+	s.info = s.info[:n]
+	if accelerated {
+		s.accelerations = s.accelerations[:n]
+	}
+}
@@ -0,0 +1,102 @@
+package nbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mergedPair returns the merger tree of two overlapping bodies merging.
+func mergedPair(t *testing.T) *MergerTree {
+	t.Helper()
+	s := NewSimulation(1000, 800)
+	s.Mergers = &MergerTree{}
+	s.Time = 12.5
+	s.AddBody(Body{Position: Vector2D{X: 100, Y: 100}, Mass: 1e24, Radius: 5})
+	s.AddBody(Body{Position: Vector2D{X: 103, Y: 100}, Mass: 3e24, Radius: 5})
+	s.mergeCollisions()
+	if s.Len() != 1 {
+		t.Fatalf("%d bodies after merging, want 1", s.Len())
+	}
+	return s.Mergers
+}
+
+func TestMergerTreeJSON(t *testing.T) {
+	tree := mergedPair(t)
+	var buf bytes.Buffer
+	if err := tree.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Events []map[string]float64 `json:"events"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"time":       12.5,
+		"into":       1,
+		"from":       0,
+		"intoMass":   3e24,
+		"fromMass":   1e24,
+		"resultMass": 4e24,
+	}
+	if len(got.Events) != 1 {
+		t.Fatalf("%d events in %s, want 1", len(got.Events), buf.Bytes())
+	}
+	for key, v := range want {
+		if got.Events[0][key] != v {
+			t.Errorf("event %s = %g, want %g", key, got.Events[0][key], v)
+		}
+	}
+	if len(got.Events[0]) != len(want) {
+		t.Errorf("event has fields %v, want exactly %v", got.Events[0], want)
+	}
+}
+
+func TestMergerTreeDOT(t *testing.T) {
+	tree := mergedPair(t)
+	tree.record(MergerEvent{Time: 20, Into: 1, From: 2, IntoMass: 4e24, FromMass: 1e24, ResultMass: 5e24})
+	var buf bytes.Buffer
+	if err := tree.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "digraph mergers {\n" +
+		"\tb0 -> b1 [label=\"t=12.50\\n3e+24 + 1e+24 = 4e+24\"];\n" +
+		"\tb2 -> b1 [label=\"t=20.00\\n4e+24 + 1e+24 = 5e+24\"];\n" +
+		"}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("DOT =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMergerTreeSave(t *testing.T) {
+	tree := mergedPair(t)
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name, prefix string
+	}{
+		{"tree.json", "{"},
+		{"tree.dot", "digraph"},
+		{"tree.GV", "digraph"},
+		{"tree", "{"},
+	} {
+		path := filepath.Join(dir, tt.name)
+		if err := tree.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), tt.prefix) {
+			t.Errorf("%s starts %.20q, want %q", tt.name, data, tt.prefix)
+		}
+	}
+	if err := tree.Save(filepath.Join(dir, "missing", "tree.json")); err == nil {
+		t.Error("saved into a directory that doesn't exist")
+	}
+}
@@ -0,0 +1,319 @@
+// Package nbody implements the gravitational N-body simulation: body
+// storage, force computation and integration, independent of any renderer.
+package nbody
+
+import (
+	"image/color"
+	"math"
+)
+
+const (
+	G             = 6.67430e-11 // gravitational constant
+	Ke            = 8.98755e9   // Coulomb constant
+	TimeStep      = 1.0 / 60    // default simulation time step
+	ScaleFactor   = 1e-9        // scale factor to make the simulation visible
+	OrbitScale    = 1e-9        // scale down the orbit sizes to fit on screen
+	SpeedScale    = 300000
+	DefaultMONDA0 = 1.2e-10 // default MOND acceleration scale (Milgrom's a0, m/s²)
+	Softening     = 1e7     // Softening length to prevent extreme forces at small distances
+)
+
+// AccelScale converts accelerations in m/s² to the simulation's world units
+// per second²: lengths are scaled by OrbitScale and speeds by
+// SpeedScale·ScaleFactor, so accelerations by the square of the speed scale
+// over the length scale.
+const AccelScale = SpeedScale * SpeedScale * ScaleFactor * ScaleFactor / OrbitScale
+
+type Vector2D struct {
+	X, Y float64
+}
+
+type Body struct {
+	ID             int
+	Name           string // for display; may be empty
+	Position       Vector2D
+	Velocity       Vector2D
+	Mass           float64
+	Charge         float64 // electric charge in coulombs
+	Radius         float64 // drawn radius, in world units
+	PhysicalRadius float64 // true radius in meters, for densities and Roche limits; zero if unknown
+	Color          color.Color
+	Texture        string // image drawn in place of the body's disc, or empty
+	Luminous       bool   // whether the body shines, like a star
+	Comet          bool   // whether the body is drawn with a tail pointing away from the nearest luminous body
+	Tags           string // space-separated groups, such as "moons", for display; a string keeps Body comparable
+}
+
+// MOND configures the optional modified-gravity mode. When enabled, the
+// Newtonian acceleration of each body is boosted by the "simple" MOND
+// interpolation function, so accelerations well below A0 fall off as 1/r
+// instead of 1/r² and rotation curves flatten out.
+type MOND struct {
+	Enabled bool
+	A0      float64 // acceleration scale below which gravity is boosted, in m/s²
+}
+
+// Simulation stores its bodies as a structure of arrays: the fields read by
+// the force loop (positions, velocities, masses and charges) each live in
+// their own contiguous slice, and the rest in info. Bodies are accessed
+// through the methods in storage.go.
+type Simulation struct {
+	// Width and Height are the size of the world. It wraps around at its
+	// edges, so every position lies in [0, Width) × [0, Height).
+	Width, Height float64
+
+	posX, posY []float64
+	velX, velY []float64
+	mass       []float64
+	charge     []float64
+	info       []bodyInfo
+
+	MOND  MOND
+	Time  float64 // elapsed simulation time
+	Steps int     // Updates taken
+
+	// Dt is the simulated time an Update covers. Zero means TimeStep.
+	Dt float64
+
+	// Reverse runs time backwards: each Update undoes one forward Update,
+	// retracing the way the bodies came up to rounding. Collisions are not
+	// undone, and constraints are kept only by their forces.
+	Reverse bool
+
+	// Substeps splits every Update into this many integration steps of
+	// Dt/Substeps each, for accuracy without changing how much time an
+	// Update covers. Zero means one.
+	Substeps int
+
+	// Collisions merges bodies whose discs overlap. Every merger is
+	// reported to Mergers when it is non-nil.
+	Collisions bool
+	Mergers    *MergerTree
+
+	Constraints []Constraint
+
+	// Solver computes gravity instead of the direct O(n²) sum when non-nil.
+	Solver GravitySolver
+	// Precision of the direct gravity sum.
+	Precision Precision
+
+	nextID int
+
+	// Scratch space reused from step to step, so that once the buffers
+	// have grown to fit, a step performs no heap allocations.
+	accelerations []Vector2D  // force pass output
+	forces        []Vector2D  // constraint forces
+	index         map[int]int // body index by ID
+	pairA, pairB  Body        // constraint endpoints
+	grid          collisionGrid
+	dead          []bool // bodies merged away this step
+
+	posX32, posY32, gm32 []float32 // single-precision copies for Float32
+}
+
+// NewSimulation returns an empty simulation of a width × height world.
+func NewSimulation(width, height float64) *Simulation {
+	return &Simulation{Width: width, Height: height}
+}
+
+// Center returns the middle of the world.
+func (s *Simulation) Center() Vector2D {
+	return Vector2D{X: s.Width / 2, Y: s.Height / 2}
+}
+
+// StepSize returns the simulated time an Update covers.
+func (s *Simulation) StepSize() float64 {
+	if s.Dt > 0 {
+		return s.Dt
+	}
+	return TimeStep
+}
+
+// SubstepSize returns the length of the integration steps an Update is
+// split into.
+func (s *Simulation) SubstepSize() float64 {
+	return s.StepSize() / float64(max(s.Substeps, 1))
+}
+
+// Update advances the simulation by StepSize, or takes it back if Reverse
+// is set.
+func (s *Simulation) Update() {
+	dt := s.SubstepSize()
+	for range max(s.Substeps, 1) {
+		if s.Reverse {
+			s.unstep(dt)
+		} else {
+			s.step(dt)
+		}
+	}
+	s.Steps++
+	setPhase(phaseNone)
+}
+
+// step integrates forward by dt.
+func (s *Simulation) step(dt float64) {
+	setPhase(phaseConstraints)
+	extra := s.constraintForces()
+	s.computeAccelerations(extra)
+
+	setPhase(phaseIntegrate)
+	for i := range s.posX {
+		s.velX[i] += s.accelerations[i].X * dt
+		s.velY[i] += s.accelerations[i].Y * dt
+		s.posX[i] += s.velX[i] * dt
+		s.posY[i] += s.velY[i] * dt
+
+		// Keep bodies within the world
+		s.posX[i] = math.Mod(s.posX[i]+s.Width, s.Width)
+		s.posY[i] = math.Mod(s.posY[i]+s.Height, s.Height)
+	}
+
+	setPhase(phaseConstraints)
+	s.resolveConstraints()
+
+	if s.Collisions {
+		setPhase(phaseCollisions)
+		s.mergeCollisions()
+	}
+	s.Time += dt
+}
+
+// unstep integrates backward by dt, undoing a step of dt: the drift and the
+// kick of step are taken back in the opposite order, so the accelerations
+// come from the same positions as they did going forward.
+func (s *Simulation) unstep(dt float64) {
+	setPhase(phaseIntegrate)
+	for i := range s.posX {
+		s.posX[i] = math.Mod(s.posX[i]-s.velX[i]*dt+s.Width, s.Width)
+		s.posY[i] = math.Mod(s.posY[i]-s.velY[i]*dt+s.Height, s.Height)
+	}
+
+	setPhase(phaseConstraints)
+	extra := s.constraintForces()
+	s.computeAccelerations(extra)
+
+	setPhase(phaseIntegrate)
+	for i := range s.velX {
+		s.velX[i] -= s.accelerations[i].X * dt
+		s.velY[i] -= s.accelerations[i].Y * dt
+	}
+	s.Time -= dt
+}
+
+// GravitySolver computes the gravitational acceleration of every body,
+// replacing the direct pairwise sum. acc has one entry per body.
+type GravitySolver interface {
+	Accelerations(s *Simulation, acc []Vector2D)
+}
+
+// accelerateRange computes the acceleration of bodies lo..hi-1 into
+// s.accelerations. extra holds additional per-body forces and may be nil.
+// When a GravitySolver is set, s.accelerations already holds its result.
+func (s *Simulation) accelerateRange(lo, hi int, extra []Vector2D) {
+	charged := s.anyCharged()
+	for i := lo; i < hi; i++ {
+		xi, yi := s.posX[i], s.posY[i]
+		var ax, ay float64 // gravity
+		var fx, fy float64 // non-gravitational forces
+		if extra != nil {
+			fx, fy = extra[i].X, extra[i].Y
+		}
+		switch {
+		case s.Solver != nil:
+			ax, ay = s.accelerations[i].X, s.accelerations[i].Y
+		case s.Precision == Float32:
+			ax, ay = s.gravity32(i)
+		default:
+			for j := range s.posX {
+				if i != j {
+					gx, gy := PairAcceleration(s.posX[j]-xi, s.posY[j]-yi, G*s.mass[j])
+					ax += gx
+					ay += gy
+				}
+			}
+		}
+		if charged && s.charge[i] != 0 {
+			for j := range s.posX {
+				if i != j && s.charge[j] != 0 {
+					ex, ey := PairAcceleration(s.posX[j]-xi, s.posY[j]-yi, -Ke*s.charge[i]*s.charge[j])
+					fx += ex
+					fy += ey
+				}
+			}
+		}
+
+		acceleration := Vector2D{X: ax, Y: ay}
+		if s.MOND.Enabled {
+			acceleration = s.MOND.apply(acceleration)
+		}
+		s.accelerations[i] = AddVectors(acceleration, Vector2D{X: fx / s.mass[i], Y: fy / s.mass[i]})
+	}
+}
+
+// PairAcceleration is the softened inverse-square kernel shared by gravity
+// and electrostatics: the pull toward a source at offset (dx, dy) with
+// strength k, which is G times its mass for gravity. It matches
+// GravitationalForce divided by the attracted body's mass.
+func PairAcceleration(dx, dy, k float64) (float64, float64) {
+	distSq := dx*dx + dy*dy
+	dist := math.Sqrt(distSq)
+	a := k / (distSq + Softening*Softening) / dist * ScaleFactor
+	return a * dx, a * dy
+}
+
+// PairPotential is the potential energy matching PairAcceleration for a pair
+// at distance r with strength k: the softened force k/(r²+ε²) integrates to
+// -k/ε·(π/2 - atan(r/ε)), which vanishes at infinity.
+func PairPotential(r, k float64) float64 {
+	return -k * ScaleFactor / Softening * (math.Pi/2 - math.Atan(r/Softening))
+}
+
+func GravitationalForce(b1, b2 *Body) Vector2D {
+	dx := b2.Position.X - b1.Position.X
+	dy := b2.Position.Y - b1.Position.Y
+	distSq := dx*dx + dy*dy
+	dist := math.Sqrt(distSq)
+
+	force := G * b1.Mass * b2.Mass / (distSq + Softening*Softening)
+
+	return Vector2D{
+		X: force * dx / dist * ScaleFactor,
+		Y: force * dy / dist * ScaleFactor,
+	}
+}
+
+// apply converts a Newtonian acceleration into its MOND equivalent using the
+// simple interpolation function nu(y) = 1/2 + sqrt(1/4 + 1/y), y = |aN|/A0.
+// a is in world units and A0 in m/s², so A0 is converted with AccelScale.
+func (m MOND) apply(a Vector2D) Vector2D {
+	aN := math.Hypot(a.X, a.Y)
+	if aN == 0 || m.A0 <= 0 {
+		return a
+	}
+	nu := 0.5 + math.Sqrt(0.25+m.A0*AccelScale/aN)
+	return ScaleVector(a, nu)
+}
+
+// ElectrostaticForce returns the Coulomb force exerted on b1 by b2,
+// softened and scaled the same way as gravity. Like charges repel.
+func ElectrostaticForce(b1, b2 *Body) Vector2D {
+	dx := b2.Position.X - b1.Position.X
+	dy := b2.Position.Y - b1.Position.Y
+	distSq := dx*dx + dy*dy
+	dist := math.Sqrt(distSq)
+
+	force := -Ke * b1.Charge * b2.Charge / (distSq + Softening*Softening)
+
+	return Vector2D{
+		X: force * dx / dist * ScaleFactor,
+		Y: force * dy / dist * ScaleFactor,
+	}
+}
+
+func AddVectors(v1, v2 Vector2D) Vector2D {
+	return Vector2D{X: v1.X + v2.X, Y: v1.Y + v2.Y}
+}
+
+func ScaleVector(v Vector2D, scalar float64) Vector2D {
+	return Vector2D{X: v.X * scalar, Y: v.Y * scalar}
+}
@@ -0,0 +1,115 @@
+package nbody
+
+import "math"
+
+// OrbitalElements are the osculating two-body elements of a body around its
+// primary, treating the pair as an isolated inverse-square system.
+type OrbitalElements struct {
+	Primary       int     `json:"primary"`       // ID of the primary body
+	Distance      float64 `json:"distance"`      // current separation
+	RelSpeed      float64 `json:"relSpeed"`      // speed relative to the primary
+	SemiMajorAxis float64 `json:"semiMajorAxis"` // negative for hyperbolic orbits
+	Eccentricity  float64 `json:"eccentricity"`
+	ArgPeriapsis  float64 `json:"argPeriapsis"` // radians, from the +X axis
+	TrueAnomaly   float64 `json:"trueAnomaly"`  // radians
+	Periapsis     float64 `json:"periapsis"`
+	Apoapsis      float64 `json:"apoapsis"` // zero for unbound orbits
+	Period        float64 `json:"period"`   // zero for unbound orbits
+	Bound         bool    `json:"bound"`
+}
+
+// DominantBody returns the index of the body exerting the strongest
+// gravitational pull on bodies[i].
+func DominantBody(bodies []Body, i int) (int, bool) {
+	best, strongest := -1, 0.0
+	for j := range bodies {
+		if j == i {
+			continue
+		}
+		f := GravitationalForce(&bodies[i], &bodies[j])
+		if m := math.Hypot(f.X, f.Y); m > strongest {
+			best, strongest = j, m
+		}
+	}
+	return best, best >= 0
+}
+
+// OsculatingElements computes the elements of b's orbit around primary.
+func OsculatingElements(b, primary Body) OrbitalElements {
+	mu := G * (b.Mass + primary.Mass) * ScaleFactor
+	rx, ry := b.Position.X-primary.Position.X, b.Position.Y-primary.Position.Y
+	vx, vy := b.Velocity.X-primary.Velocity.X, b.Velocity.Y-primary.Velocity.Y
+	r := math.Hypot(rx, ry)
+	v2 := vx*vx + vy*vy
+	rv := rx*vx + ry*vy
+
+	el := OrbitalElements{Primary: primary.ID, Distance: r, RelSpeed: math.Sqrt(v2)}
+	if r == 0 || mu == 0 {
+		return el
+	}
+
+	ex := ((v2-mu/r)*rx - rv*vx) / mu
+	ey := ((v2-mu/r)*ry - rv*vy) / mu
+	el.Eccentricity = math.Hypot(ex, ey)
+	el.ArgPeriapsis = math.Atan2(ey, ex)
+	el.TrueAnomaly = math.Atan2(ry, rx) - el.ArgPeriapsis
+
+	energy := v2/2 - mu/r
+	el.SemiMajorAxis = -mu / (2 * energy)
+	el.Bound = energy < 0
+	if el.Bound {
+		el.Periapsis = el.SemiMajorAxis * (1 - el.Eccentricity)
+		el.Apoapsis = el.SemiMajorAxis * (1 + el.Eccentricity)
+		el.Period = 2 * math.Pi * math.Sqrt(math.Pow(el.SemiMajorAxis, 3)/mu)
+	} else {
+		h := rx*vy - ry*vx
+		el.Periapsis = h * h / mu / (1 + el.Eccentricity)
+	}
+	return el
+}
+
+// PositionAt returns the point of the orbit at the given true anomaly,
+// relative to the primary.
+func (el OrbitalElements) PositionAt(trueAnomaly float64) Vector2D {
+	p := el.SemiMajorAxis * (1 - el.Eccentricity*el.Eccentricity) // semi-latus rectum
+	r := p / (1 + el.Eccentricity*math.Cos(trueAnomaly))
+	sin, cos := math.Sincos(el.ArgPeriapsis + trueAnomaly)
+	return Vector2D{X: r * cos, Y: r * sin}
+}
+
+// HillRadius returns the radius of b's Hill sphere around primary: roughly
+// the region where b's gravity outweighs the primary's tidal pull, so that
+// satellites within it can stay bound to b. It is taken at periapsis for a
+// bound orbit, where the sphere is smallest, and at the current distance
+// otherwise.
+func HillRadius(b, primary Body) float64 {
+	el := OsculatingElements(b, primary)
+	d := el.Distance
+	if el.Bound {
+		d = el.Periapsis
+	}
+	return d * math.Cbrt(b.Mass/(3*primary.Mass))
+}
+
+// Density returns b's mass per cubic meter, treating it as a sphere of its
+// physical radius, or zero if that is unknown.
+func (b Body) Density() float64 {
+	r := b.PhysicalRadius
+	if !(r > 0) {
+		return 0
+	}
+	return b.Mass / (4 * math.Pi / 3 * r * r * r)
+}
+
+// RocheLimits returns the distances from the center of primary, in world
+// units, within which tides would tear apart a satellite of the given
+// density, in kg/m³, held together only by its own gravity: rigid for a
+// solid satellite and fluid for one that deforms, which breaks up further
+// out. Both are zero if primary's physical radius is unknown.
+func RocheLimits(primary Body, density float64) (rigid, fluid float64) {
+	if !(primary.PhysicalRadius > 0) {
+		return 0, 0
+	}
+	r := primary.PhysicalRadius * OrbitScale * math.Cbrt(primary.Density()/density)
+	return 1.26 * r, 2.44 * r
+}
@@ -0,0 +1,105 @@
+package nbody
+
+import (
+	"math"
+	"testing"
+)
+
+// orbiting returns a body of mass m at distance a from primary, on the +X
+// side, moving at speed times the circular orbital speed, counterclockwise.
+func orbiting(primary Body, m, a, speed float64) Body {
+	v := math.Sqrt(G * (primary.Mass + m) * ScaleFactor / a)
+	return Body{
+		ID:       1,
+		Position: Vector2D{X: primary.Position.X + a, Y: primary.Position.Y},
+		Velocity: Vector2D{X: primary.Velocity.X, Y: primary.Velocity.Y + speed*v},
+		Mass:     m,
+	}
+}
+
+func TestHillRadius(t *testing.T) {
+	sun := Body{Position: Vector2D{X: 500, Y: 500}, Mass: 1.989e30}
+	const m, a = 5.972e24, 1000.0
+	hill := a * math.Cbrt(m/(3*sun.Mass))
+	// A bound orbit's sphere is taken at periapsis. Moving at √(1-e) of
+	// the circular speed, a body is at the apoapsis of an orbit of
+	// eccentricity e, whose periapsis is a(1-e)/(1+e), and at √(1+e), at
+	// the periapsis. An unbound body's sphere is taken where it is.
+	for _, tt := range []struct {
+		name  string
+		speed float64
+		want  float64
+	}{
+		{"circular", 1, hill},
+		{"eccentric, at apoapsis", math.Sqrt(0.5), hill * (1 - 0.5) / (1 + 0.5)},
+		{"eccentric, at periapsis", math.Sqrt(1.5), hill},
+		{"unbound", 2, hill},
+	} {
+		if got := HillRadius(orbiting(sun, m, a, tt.speed), sun); math.Abs(got-tt.want) > 1e-9*tt.want {
+			t.Errorf("%s: HillRadius = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPositionAtCircular(t *testing.T) {
+	sun := Body{Mass: 1.989e30}
+	const a = 1000.0
+	earth := orbiting(sun, 5.972e24, a, 1)
+	el := OsculatingElements(earth, sun)
+	if el.Eccentricity > 1e-9 || math.Abs(el.SemiMajorAxis-a) > 1e-9*a {
+		t.Fatalf("elements of a circular orbit: e = %v, a = %v; want 0, %v", el.Eccentricity, el.SemiMajorAxis, a)
+	}
+	// Kepler propagation of a circular orbit: the body turns at the mean
+	// motion 2π/Period from where it starts.
+	n := 2 * math.Pi / el.Period
+	for _, time := range []float64{0, el.Period / 8, el.Period / 3, el.Period / 2, 0.9 * el.Period, 2.25 * el.Period} {
+		sin, cos := math.Sincos(n * time)
+		want := Vector2D{X: a * cos, Y: a * sin}
+		got := el.PositionAt(el.TrueAnomaly + n*time)
+		if math.Hypot(got.X-want.X, got.Y-want.Y) > 1e-9*a {
+			t.Errorf("position after %.3g of a period = %v, want %v", time/el.Period, got, want)
+		}
+	}
+}
+
+func TestPositionAtApsides(t *testing.T) {
+	sun := Body{Mass: 1.989e30}
+	el := OsculatingElements(orbiting(sun, 5.972e24, 1000, math.Sqrt(0.5)), sun)
+	for _, tt := range []struct {
+		anomaly, r float64
+	}{
+		{0, el.Periapsis},
+		{math.Pi, el.Apoapsis},
+		{el.TrueAnomaly, 1000},
+	} {
+		if p := el.PositionAt(tt.anomaly); math.Abs(math.Hypot(p.X, p.Y)-tt.r) > 1e-9*tt.r {
+			t.Errorf("distance at true anomaly %.3g = %v, want %v", tt.anomaly, math.Hypot(p.X, p.Y), tt.r)
+		}
+	}
+}
+
+func TestRocheLimits(t *testing.T) {
+	earth := Body{Mass: 5.972e24, Radius: 5, PhysicalRadius: 6.371e6}
+	moon := Body{Mass: 7.342e22, Radius: 2, PhysicalRadius: 1.7374e6}
+	if got := earth.Density(); math.Abs(got-5513) > 1 {
+		t.Errorf("Earth's density = %v kg/m³, want 5513", got)
+	}
+	// The Moon would break up within about 9,500 km of Earth if rigid and
+	// 18,400 km if fluid, whatever size they are drawn at.
+	rigid, fluid := RocheLimits(earth, moon.Density())
+	if want := 9.49e6 * OrbitScale; math.Abs(rigid-want) > 0.01*want {
+		t.Errorf("rigid Roche limit = %v, want %v", rigid, want)
+	}
+	if want := 18.38e6 * OrbitScale; math.Abs(fluid-want) > 0.01*want {
+		t.Errorf("fluid Roche limit = %v, want %v", fluid, want)
+	}
+
+	// Without a physical radius there is no density or limit.
+	earth.PhysicalRadius = 0
+	if d := earth.Density(); d != 0 {
+		t.Errorf("density without a physical radius = %v, want 0", d)
+	}
+	if rigid, fluid := RocheLimits(earth, moon.Density()); rigid != 0 || fluid != 0 {
+		t.Errorf("Roche limits without a physical radius = %v, %v; want 0", rigid, fluid)
+	}
+}
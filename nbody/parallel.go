@@ -0,0 +1,104 @@
+package nbody
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// parallelThreshold is the body count below which the force pass stays
+	// on the calling goroutine; small systems don't amortize the overhead.
+	parallelThreshold = 256
+	// forceChunk is the number of bodies a worker claims at a time.
+	forceChunk = 64
+)
+
+// computeAccelerations fills s.accelerations for every body. Above
+// parallelThreshold bodies the work is shared by the workers of forces,
+// each repeatedly claiming the next chunk of bodies. There is no reduction
+// across workers: every body's sum is accumulated by a single worker over
+// the other bodies in index order, exactly as the serial loop does, so
+// results are bit-identical whatever the number of workers and however
+// the chunks fall to them.
+func (s *Simulation) computeAccelerations(extra []Vector2D) {
+	n := s.Len()
+	if cap(s.accelerations) < n {
+		s.accelerations = make([]Vector2D, n)
+	}
+	s.accelerations = s.accelerations[:n]
+	setPhase(phaseForces)
+	if s.Solver != nil {
+		s.Solver.Accelerations(s, s.accelerations)
+	} else if s.Precision == Float32 {
+		s.prepareFloat32()
+	}
+
+	if n < parallelThreshold || runtime.GOMAXPROCS(0) == 1 || !forces.run(s, extra) {
+		s.accelerateRange(0, n, extra)
+	}
+}
+
+// forces is the worker pool shared by every simulation's force pass. Its
+// goroutines are started once and live for the rest of the program, so a
+// step spawns nothing and allocates nothing.
+var forces forcePool
+
+type forcePool struct {
+	mu      sync.Mutex // held for the duration of a pass
+	start   sync.Once
+	workers int
+	wake    chan struct{}
+	done    sync.WaitGroup
+
+	// The pass being run.
+	sim   *Simulation
+	extra []Vector2D
+	next  atomic.Int64
+}
+
+// run computes s's accelerations on the pool. It reports false, leaving the
+// work to the caller, when another simulation's pass is already running.
+func (p *forcePool) run(s *Simulation, extra []Vector2D) bool {
+	if !p.mu.TryLock() {
+		return false
+	}
+	defer p.mu.Unlock()
+	p.start.Do(func() {
+		p.workers = runtime.GOMAXPROCS(0)
+		p.wake = make(chan struct{})
+		for range p.workers - 1 {
+			go func() {
+				for range p.wake {
+					p.work()
+					p.done.Done()
+				}
+			}()
+		}
+	})
+
+	// Follow GOMAXPROCS if it was lowered after the pool started.
+	helpers := min(p.workers, runtime.GOMAXPROCS(0)) - 1
+	p.sim, p.extra = s, extra
+	p.next.Store(0)
+	p.done.Add(helpers)
+	for range helpers {
+		p.wake <- struct{}{}
+	}
+	p.work() // the caller is a worker too
+	p.done.Wait()
+	p.sim, p.extra = nil, nil
+	return true
+}
+
+func (p *forcePool) work() {
+	setPhase(phaseForces)
+	n := p.sim.Len()
+	for {
+		lo := int(p.next.Add(forceChunk)) - forceChunk
+		if lo >= n {
+			return
+		}
+		p.sim.accelerateRange(lo, min(lo+forceChunk, n), p.extra)
+	}
+}
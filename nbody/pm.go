@@ -0,0 +1,131 @@
+package nbody
+
+import (
+	"math"
+	"math/bits"
+)
+
+// PM is a particle-mesh gravity solver. Masses are deposited on a regular
+// grid spanning the world with cloud-in-cell weights, convolved with the
+// softened force law by FFT, and the resulting field is interpolated back to
+// the bodies with the same weights. The grid is zero-padded to twice its
+// size, so the convolution is not periodic and matches the direct sum's
+// isolated boundary.
+//
+// The cost is O(N + G² log G) for a G×G grid regardless of how bodies are
+// distributed, but forces are smoothed over about a cell: PM suits diffuse,
+// collisionless systems such as galaxy disks, not close encounters.
+type PM struct {
+	// Grid is the number of grid nodes along each axis, rounded up to a
+	// power of two.
+	Grid int
+
+	n             int     // grid nodes per axis
+	hx, hy        float64 // node spacing
+	width, height float64 // world size the kernel was built for
+	kernel        []complex128
+	rho           []complex128
+	col           []complex128
+	cells         []pmCell
+}
+
+// pmCell is a body's cloud-in-cell footprint: the lower-left node and the
+// fractional offset from it along each axis.
+type pmCell struct {
+	i, j   int
+	tx, ty float64
+}
+
+// NewPM returns a particle-mesh solver with a grid×grid mesh.
+func NewPM(grid int) *PM {
+	return &PM{Grid: grid}
+}
+
+func (p *PM) Accelerations(s *Simulation, acc []Vector2D) {
+	clear(acc)
+	if s.Len() == 0 {
+		return
+	}
+	p.prepare(s.Width, s.Height)
+	m := 2 * p.n
+
+	clear(p.rho)
+	x, y := s.Positions()
+	p.cells = p.cells[:0]
+	for i, mass := range s.Masses() {
+		c := p.cell(x[i], y[i])
+		p.cells = append(p.cells, c)
+		gm := G * mass
+		k := c.j*m + c.i
+		p.rho[k] += complex(gm*(1-c.tx)*(1-c.ty), 0)
+		p.rho[k+1] += complex(gm*c.tx*(1-c.ty), 0)
+		p.rho[k+m] += complex(gm*(1-c.tx)*c.ty, 0)
+		p.rho[k+m+1] += complex(gm*c.tx*c.ty, 0)
+	}
+
+	// The kernel holds the x component of the force law in its real part and
+	// the y component in its imaginary part; since the density is real, one
+	// product yields both components of the field.
+	fft2(p.rho, m, m, p.col, false)
+	for k := range p.rho {
+		p.rho[k] *= p.kernel[k]
+	}
+	fft2(p.rho, m, m, p.col, true)
+
+	norm := 1 / float64(m*m)
+	for i, c := range p.cells {
+		k := c.j*m + c.i
+		f := p.rho[k]*complex((1-c.tx)*(1-c.ty), 0) +
+			p.rho[k+1]*complex(c.tx*(1-c.ty), 0) +
+			p.rho[k+m]*complex((1-c.tx)*c.ty, 0) +
+			p.rho[k+m+1]*complex(c.tx*c.ty, 0)
+		acc[i] = Vector2D{X: real(f) * norm, Y: imag(f) * norm}
+	}
+}
+
+// cell returns the cloud-in-cell footprint of a position, clamped so all
+// four nodes lie on the grid.
+func (p *PM) cell(x, y float64) pmCell {
+	fx, fy := x/p.hx, y/p.hy
+	i := min(max(int(math.Floor(fx)), 0), p.n-2)
+	j := min(max(int(math.Floor(fy)), 0), p.n-2)
+	return pmCell{i: i, j: j, tx: fx - float64(i), ty: fy - float64(j)}
+}
+
+// prepare sizes the grid and, when the grid or world size changed,
+// transforms the force kernel. Nodes sit at multiples of the spacing from 0
+// to the world's far edge, so every wrapped position has a full footprint.
+func (p *PM) prepare(width, height float64) {
+	n := 1 << bits.Len(uint(max(p.Grid, 2)-1))
+	if n == p.n && width == p.width && height == p.height {
+		return
+	}
+	p.n, p.width, p.height = n, width, height
+	p.hx, p.hy = width/float64(n-1), height/float64(n-1)
+
+	m := 2 * n
+	p.kernel = make([]complex128, m*m)
+	p.rho = make([]complex128, m*m)
+	p.col = make([]complex128, m)
+	for j := range m {
+		for i := range m {
+			// Offsets past n-1 nodes wrap to negative displacements; the
+			// offset of exactly n nodes is never needed and stays zero.
+			di, dj := i, j
+			if di > n {
+				di -= m
+			}
+			if dj > n {
+				dj -= m
+			}
+			if di == n || dj == n || (di == 0 && dj == 0) {
+				continue
+			}
+			// The field at a node points back toward the source, which
+			// lies at minus the offset.
+			ax, ay := PairAcceleration(-float64(di)*p.hx, -float64(dj)*p.hy, 1)
+			p.kernel[j*m+i] = complex(ax, ay)
+		}
+	}
+	fft2(p.kernel, m, m, p.col, false)
+}
@@ -0,0 +1,28 @@
+package nbody
+
+import (
+	"math"
+	"testing"
+)
+
+// TestPMAccuracy compares PM with the direct sum for light bodies around a
+// heavy one, at least a dozen cells of the 128-node grid away, where the
+// mesh's smoothing is negligible.
+func TestPMAccuracy(t *testing.T) {
+	s := NewSimulation(1000, 800)
+	s.AddBody(Body{Position: Vector2D{X: 500, Y: 400}, Mass: 1e30, Radius: 1})
+	for i := range 8 {
+		a := float64(i) * math.Pi / 4
+		d := 100 + 25*float64(i)
+		s.AddBody(Body{Position: Vector2D{X: 500 + d*math.Cos(a), Y: 400 + d*math.Sin(a)}, Mass: 1e20, Radius: 1})
+	}
+	want := directAccelerations(s)
+	got := make([]Vector2D, s.Len())
+	NewPM(128).Accelerations(s, got)
+	for i := 1; i < s.Len(); i++ {
+		err := math.Hypot(got[i].X-want[i].X, got[i].Y-want[i].Y) / math.Hypot(want[i].X, want[i].Y)
+		if err > 5e-3 {
+			t.Errorf("body %d: acceleration %v, want %v (relative error %.2g)", i, got[i], want[i], err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package nbody
+
+import (
+	"context"
+	"runtime/pprof"
+	"sync/atomic"
+)
+
+// phase is a stage of Update, used to label CPU profiles.
+type phase int
+
+const (
+	phaseNone phase = iota
+	phaseConstraints
+	phaseTree
+	phaseForces
+	phaseIntegrate
+	phaseCollisions
+	numPhases
+)
+
+var phaseNames = [numPhases]string{"", "constraints", "tree", "forces", "integrate", "collisions"}
+
+var (
+	profileLabels atomic.Bool
+	// phaseContexts carry each phase's labels, built once so labelling a
+	// step doesn't allocate.
+	phaseContexts [numPhases]context.Context
+)
+
+func init() {
+	for p := range numPhases {
+		ctx := context.Background()
+		if p != phaseNone {
+			ctx = pprof.WithLabels(ctx, pprof.Labels("phase", phaseNames[p]))
+		}
+		phaseContexts[p] = ctx
+	}
+}
+
+// SetProfileLabels turns on or off the pprof label "phase" that Update puts
+// on the goroutines working on each stage of a step (constraints, tree,
+// forces, integrate, collisions), so a CPU profile can be broken down by
+// stage. Update replaces the calling goroutine's labels while it runs and
+// clears them when it returns.
+func SetProfileLabels(on bool) {
+	profileLabels.Store(on)
+}
+
+// setPhase labels the calling goroutine with p when labels are on.
+func setPhase(p phase) {
+	if profileLabels.Load() {
+		pprof.SetGoroutineLabels(phaseContexts[p])
+	}
+}
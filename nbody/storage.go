@@ -0,0 +1,169 @@
+package nbody
+
+import (
+	"image/color"
+	"slices"
+)
+
+// bodyInfo holds the per-body fields the force loop never reads.
+type bodyInfo struct {
+	ID             int
+	Name           string
+	Radius         float64
+	PhysicalRadius float64
+	Color          color.Color
+	Texture        string
+	Luminous       bool
+	Comet          bool
+	Tags           string
+}
+
+// Len returns the number of bodies.
+func (s *Simulation) Len() int { return len(s.posX) }
+
+// Body assembles the i-th body from the simulation's arrays.
+func (s *Simulation) Body(i int) Body {
+	return Body{
+		ID:             s.info[i].ID,
+		Name:           s.info[i].Name,
+		Position:       Vector2D{X: s.posX[i], Y: s.posY[i]},
+		Velocity:       Vector2D{X: s.velX[i], Y: s.velY[i]},
+		Mass:           s.mass[i],
+		Charge:         s.charge[i],
+		Radius:         s.info[i].Radius,
+		PhysicalRadius: s.info[i].PhysicalRadius,
+		Color:          s.info[i].Color,
+		Texture:        s.info[i].Texture,
+		Luminous:       s.info[i].Luminous,
+		Comet:          s.info[i].Comet,
+		Tags:           s.info[i].Tags,
+	}
+}
+
+// SetBody overwrites the i-th body, including its ID.
+func (s *Simulation) SetBody(i int, b Body) {
+	s.posX[i], s.posY[i] = b.Position.X, b.Position.Y
+	s.velX[i], s.velY[i] = b.Velocity.X, b.Velocity.Y
+	s.mass[i] = b.Mass
+	s.charge[i] = b.Charge
+	s.info[i] = bodyInfo{ID: b.ID, Name: b.Name, Radius: b.Radius, PhysicalRadius: b.PhysicalRadius, Color: b.Color, Texture: b.Texture, Luminous: b.Luminous, Comet: b.Comet, Tags: b.Tags}
+}
+
+// AddBody adds b to the simulation and returns the unique ID assigned to it.
+func (s *Simulation) AddBody(b Body) int {
+	b.ID = s.nextID
+	s.nextID++
+	s.posX = append(s.posX, 0)
+	s.posY = append(s.posY, 0)
+	s.velX = append(s.velX, 0)
+	s.velY = append(s.velY, 0)
+	s.mass = append(s.mass, 0)
+	s.charge = append(s.charge, 0)
+	s.info = append(s.info, bodyInfo{})
+	s.SetBody(s.Len()-1, b)
+	s.accelerations = s.accelerations[:0]
+	return b.ID
+}
+
+// RemoveBody removes the i-th body, preserving the order of the others.
+func (s *Simulation) RemoveBody(i int) {
+	s.posX = append(s.posX[:i], s.posX[i+1:]...)
+	s.posY = append(s.posY[:i], s.posY[i+1:]...)
+	s.velX = append(s.velX[:i], s.velX[i+1:]...)
+	s.velY = append(s.velY[:i], s.velY[i+1:]...)
+	s.mass = append(s.mass[:i], s.mass[i+1:]...)
+	s.charge = append(s.charge[:i], s.charge[i+1:]...)
+	s.info = append(s.info[:i], s.info[i+1:]...)
+	s.accelerations = s.accelerations[:0]
+}
+
+// KeepBodies removes every body but those at the given indices, which must
+// be in increasing order, preserving their order. It takes one pass
+// however many bodies go, unlike repeated calls to RemoveBody.
+func (s *Simulation) KeepBodies(indices []int) {
+	for k, i := range indices {
+		s.posX[k], s.posY[k] = s.posX[i], s.posY[i]
+		s.velX[k], s.velY[k] = s.velX[i], s.velY[i]
+		s.mass[k], s.charge[k] = s.mass[i], s.charge[i]
+		s.info[k] = s.info[i]
+	}
+	n := len(indices)
+	clear(s.info[n:]) // let go of names, colors and textures
+	s.posX, s.posY = s.posX[:n], s.posY[:n]
+	s.velX, s.velY = s.velX[:n], s.velY[:n]
+	s.mass, s.charge = s.mass[:n], s.charge[:n]
+	s.info = s.info[:n]
+	s.accelerations = s.accelerations[:0]
+}
+
+// InsertBody inserts b as the i-th body, keeping its ID, to put back a
+// body taken out with RemoveBody. The ID must not be in use.
+func (s *Simulation) InsertBody(i int, b Body) {
+	s.posX = slices.Insert(s.posX, i, 0)
+	s.posY = slices.Insert(s.posY, i, 0)
+	s.velX = slices.Insert(s.velX, i, 0)
+	s.velY = slices.Insert(s.velY, i, 0)
+	s.mass = slices.Insert(s.mass, i, 0)
+	s.charge = slices.Insert(s.charge, i, 0)
+	s.info = slices.Insert(s.info, i, bodyInfo{})
+	s.SetBody(i, b)
+	s.accelerations = s.accelerations[:0]
+}
+
+// AppendBodies appends every body to dst and returns the extended slice.
+func (s *Simulation) AppendBodies(dst []Body) []Body {
+	for i := range s.posX {
+		dst = append(dst, s.Body(i))
+	}
+	return dst
+}
+
+// AppendAccelerations appends the acceleration of every body in the last
+// substep Update took to dst and returns the extended slice. It appends
+// nothing if bodies have been added, removed or restored since, other than
+// by merging.
+func (s *Simulation) AppendAccelerations(dst []Vector2D) []Vector2D {
+	if len(s.accelerations) != s.Len() {
+		return dst
+	}
+	return append(dst, s.accelerations...)
+}
+
+// ID returns the ID of the i-th body.
+func (s *Simulation) ID(i int) int { return s.info[i].ID }
+
+// IndexOf returns the index of the body with the given ID.
+func (s *Simulation) IndexOf(id int) (int, bool) {
+	for i := range s.info {
+		if s.info[i].ID == id {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (s *Simulation) Position(i int) Vector2D { return Vector2D{X: s.posX[i], Y: s.posY[i]} }
+func (s *Simulation) Velocity(i int) Vector2D { return Vector2D{X: s.velX[i], Y: s.velY[i]} }
+func (s *Simulation) Mass(i int) float64      { return s.mass[i] }
+
+func (s *Simulation) SetPosition(i int, p Vector2D) { s.posX[i], s.posY[i] = p.X, p.Y }
+func (s *Simulation) SetVelocity(i int, v Vector2D) { s.velX[i], s.velY[i] = v.X, v.Y }
+
+// Positions returns the position arrays backing the simulation. They are
+// meant for read-only use in hot loops and are invalidated by adding or
+// removing bodies.
+func (s *Simulation) Positions() (x, y []float64) { return s.posX, s.posY }
+
+// Masses returns the mass array backing the simulation, with the same
+// caveats as Positions.
+func (s *Simulation) Masses() []float64 { return s.mass }
+
+// anyCharged reports whether any body carries a charge.
+func (s *Simulation) anyCharged() bool {
+	for _, q := range s.charge {
+		if q != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package nbody
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestInsertBodyUndoesRemove(t *testing.T) {
+	s := testDisk(20)
+	want := s.AppendBodies(nil)
+	b := s.Body(7)
+	s.RemoveBody(7)
+	if _, ok := s.IndexOf(b.ID); ok {
+		t.Fatalf("body %d still present after RemoveBody", b.ID)
+	}
+	s.InsertBody(7, b)
+	if got := s.AppendBodies(nil); !slices.Equal(got, want) {
+		t.Fatalf("bodies after InsertBody differ from before RemoveBody")
+	}
+	if id := s.AddBody(Body{Mass: 1}); slices.ContainsFunc(want, func(b Body) bool { return b.ID == id }) {
+		t.Fatalf("AddBody reused ID %d", id)
+	}
+}
+
+func TestKeepBodies(t *testing.T) {
+	s := testDisk(20)
+	all := s.AppendBodies(nil)
+	s.KeepBodies([]int{0, 2, 5, 19})
+	want := []Body{all[0], all[2], all[5], all[19]}
+	if got := s.AppendBodies(nil); !slices.Equal(got, want) {
+		t.Fatalf("bodies kept = %v, want %v", got, want)
+	}
+	s.KeepBodies(nil)
+	if s.Len() != 0 {
+		t.Fatalf("%d bodies left after keeping none", s.Len())
+	}
+}
+
+func TestAppendAccelerations(t *testing.T) {
+	s := testDisk(20)
+	if acc := s.AppendAccelerations(nil); len(acc) != 0 {
+		t.Fatalf("%d accelerations before the first step", len(acc))
+	}
+	before := s.AppendBodies(nil)
+	s.Update()
+	acc := s.AppendAccelerations(nil)
+	if len(acc) != s.Len() {
+		t.Fatalf("%d accelerations for %d bodies", len(acc), s.Len())
+	}
+	dt := s.SubstepSize()
+	for i, b := range s.AppendBodies(nil) {
+		dv := Vector2D{X: b.Velocity.X - before[i].Velocity.X, Y: b.Velocity.Y - before[i].Velocity.Y}
+		want := Vector2D{X: acc[i].X * dt, Y: acc[i].Y * dt}
+		// Up to rounding in the velocity the kick was added to.
+		if math.Hypot(dv.X-want.X, dv.Y-want.Y) > 1e-15*math.Hypot(b.Velocity.X, b.Velocity.Y) {
+			t.Errorf("body %d changed velocity by %v, want its acceleration times dt, %v", i, dv, want)
+		}
+	}
+	s.AddBody(Body{Mass: 1})
+	if acc := s.AppendAccelerations(nil); len(acc) != 0 {
+		t.Errorf("%d accelerations after adding a body, want none until the next step", len(acc))
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// nightOpacity is how dark the night side of a body is drawn.
+const nightOpacity = 0.7
+
+// shadeBatch darkens the night side of bodies with half-disc triangle fans.
+type shadeBatch struct {
+	batch
+}
+
+// add queues a dark half-disc of radius pixels at (x, y), on the side the
+// unit vector (ux, uy) points to.
+func (s *shadeBatch) add(dst *ebiten.Image, x, y, radius, ux, uy float64) {
+	if s.src == nil {
+		s.src, s.antiAlias = whiteImage(), true
+	}
+	segments := int(math.Pi * radius / circleSegmentLength)
+	segments = min(max(segments, minCircleSegments/2), maxCircleSegments/2)
+	base := s.reserve(dst, segments+2, 3*segments)
+
+	var v ebiten.Vertex
+	v.SrcX, v.SrcY = 1.5, 1.5
+	v.ColorA = nightOpacity
+	v.DstX, v.DstY = float32(x), float32(y)
+	s.vertices = append(s.vertices, v)
+	// From the terminator on one side, round the night side to the other.
+	start := math.Atan2(uy, ux) - math.Pi/2
+	for k := range segments + 1 {
+		a := start + math.Pi*float64(k)/float64(segments)
+		v.DstX = float32(x + radius*math.Cos(a))
+		v.DstY = float32(y + radius*math.Sin(a))
+		s.vertices = append(s.vertices, v)
+		if k > 0 {
+			s.indices = append(s.indices, base, base+uint16(k), base+uint16(k)+1)
+		}
+	}
+}
+
+// drawNightSides shades the half of each body drawn as a disc in vp that
+// faces away from the nearest luminous body, so phases and eclipses read at
+// a glance. center is the camera center.
+func (g *Game) drawNightSides(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	for _, i := range g.discs {
+		body := g.drawBodies[i]
+		if body.Luminous {
+			continue
+		}
+		ux, uy, _, ok := g.awayFromLight(i)
+		if !ok {
+			continue
+		}
+		pos := vp.toScreen(g.view(g.framePositions[i]), center)
+		g.shades.add(dst, pos.X, pos.Y, g.sizes.radius(body, vp.Camera.Zoom), ux, uy)
+	}
+	g.shades.flush(dst)
+}
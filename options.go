@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"n-body/nbody"
+	"n-body/scenario"
+	"n-body/server"
+)
+
+// options are the command-line flags.
+type options struct {
+	mond         bool
+	a0           float64
+	charge       string
+	charges      map[string]float64 // charge, parsed
+	merge        bool
+	mergerTree   string
+	solver       string
+	fmmOrder     int
+	fmmTheta     float64
+	pmGrid       int
+	dt           float64
+	tps          int
+	substeps     int
+	autoSubsteps int
+	single       bool
+
+	split       int
+	stars       int64
+	render      string
+	colorBy     string
+	cmap        string
+	radii       string
+	showHUD     bool
+	themeName   string
+	labels      bool
+	predict     int
+	trailLength int
+	govern      bool
+
+	historySize  int
+	historyEvery int
+	rewindMB     int
+	rewindEvery  int
+
+	worksheet    string
+	scenarioName string
+	scenarioDir  string
+
+	httpAddr     string
+	readToken    string
+	controlToken string
+	public       bool
+	pprofAddr    string
+
+	editLogFile    string
+	bookmarksFile  string
+	cameraPathFile string
+
+	record      string
+	recordFPS   float64
+	recordEvery int
+	recordFor   float64
+	shotDir     string
+	shotEvery   float64
+
+	renderer    string
+	headlessFPS float64
+	soak        bool
+	soakFor     time.Duration
+	soakEvery   time.Duration
+}
+
+// configure applies the physics flags to sim, built from a scenario: those
+// given on the command line override the scenario.
+func (o *options) configure(sim *nbody.Simulation) {
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "mond":
+			sim.MOND.Enabled = o.mond
+		case "mond-a0":
+			sim.MOND.A0 = o.a0
+		case "charge":
+			setCharges(sim, o.charges)
+		case "merge":
+			sim.Collisions = o.merge
+		case "dt":
+			sim.Dt = o.dt
+		}
+	})
+	sim.Substeps = o.substeps
+	sim.CheckStep(o.autoSubsteps, log.Printf)
+}
+
+// newGame sets a game up to run sc as o says.
+func newGame(sc *scenario.Scenario, o *options) (*Game, error) {
+	sim := sc.Build()
+	o.configure(sim)
+	if o.single {
+		sim.Precision = nbody.Float32
+	}
+	if o.mergerTree != "" {
+		sim.Mergers = &nbody.MergerTree{}
+	}
+	solvers := solverOptions{fmmOrder: o.fmmOrder, fmmTheta: o.fmmTheta, pmGrid: o.pmGrid}
+	var err error
+	if sim.Solver, err = newSolver(o.solver, solvers); err != nil {
+		return nil, err
+	}
+
+	game := &Game{
+		sim:         sim,
+		states:      newStateBuffer(),
+		selected:    nbody.NoBody,
+		partner:     nbody.NoBody,
+		moving:      bodyDrag{id: nbody.NoBody},
+		solver:      o.solver,
+		solvers:     solvers,
+		editor:      propertyEditor{id: nbody.NoBody, dragging: -1},
+		width:       screenWidth,
+		height:      screenHeight,
+		insetOn:     true,
+		eclipses:    eclipses{on: true},
+		hud:         hud{on: o.showHUD},
+		dragging:    -1,
+		labelsOn:    o.labels,
+		trailLength: max(o.trailLength, 2),
+		annotations: sc.Annotations,
+		shots:       screenshots{dir: o.shotDir, every: o.shotEvery},
+	}
+	game.window.g = game
+	game.renderer = &game.window
+	game.predictor.steps = o.predict
+	if sc.Epoch != "" {
+		if game.hud.epoch, err = scenario.ParseEpoch(sc.Epoch); err != nil {
+			return nil, err
+		}
+	}
+	if o.stars != 0 {
+		game.stars = newStarfield(o.stars)
+	}
+	if game.render, err = parseRenderMode(o.render); err != nil {
+		return nil, err
+	}
+	if game.sizes.scale, err = parseRadiusScale(o.radii); err != nil {
+		return nil, err
+	}
+	if game.colors.mode, err = parseColorMode(o.colorBy); err != nil {
+		return nil, err
+	}
+	if game.colors.cmap, err = parseColormap(o.cmap); err != nil {
+		return nil, err
+	}
+	if o.bookmarksFile != "" {
+		if game.bookmarks, err = loadBookmarks(o.bookmarksFile); err != nil {
+			return nil, err
+		}
+	}
+	if o.cameraPathFile != "" {
+		if game.path, err = loadCameraPath(o.cameraPathFile); err != nil {
+			return nil, err
+		}
+	}
+	if o.worksheet != "" {
+		ws, err := loadWorksheet(o.worksheet)
+		if err != nil {
+			return nil, err
+		}
+		game.worksheet = newWorksheetMode(ws)
+	}
+	game.quality = newGovernor(o.govern, o.tps)
+	game.history = nbody.NewHistory(o.historySize, o.historyEvery)
+	game.history.Record(sim)
+	game.timeline = timeline{history: newRewindHistory(sim, o.rewindMB<<20, o.rewindEvery), current: -1}
+	if game.timeline.history != nil {
+		game.timeline.history.Record(sim)
+	}
+	game.setViewportCount(min(max(o.split, 1), maxViewport))
+	game.refresh()
+	game.recording = recordings{
+		dir:         o.shotDir,
+		gif:         strings.EqualFold(filepath.Ext(o.record), ".gif"),
+		fps:         o.recordFPS,
+		every:       o.recordEvery,
+		length:      o.recordFor,
+		annotations: sc.Annotations,
+	}
+	if o.pprofAddr != "" {
+		game.renderLabels = pprof.WithLabels(context.Background(), pprof.Labels("phase", "render"))
+	}
+
+	// Last, what holds on to files or an address, let go of if a later
+	// step fails.
+	var ln net.Listener
+	if o.httpAddr != "" {
+		if ln, err = net.Listen("tcp", o.httpAddr); err != nil {
+			return nil, err
+		}
+		game.server = newGameServer(sc, o)
+	}
+	if game.edits, err = openEditLog(o.editLogFile); err != nil {
+		if ln != nil {
+			ln.Close()
+		}
+		return nil, err
+	}
+	if o.record != "" {
+		if game.recorder, err = game.recording.start(o.record); err != nil {
+			if ln != nil {
+				ln.Close()
+			}
+			game.edits.Close()
+			return nil, err
+		}
+	}
+	if ln != nil {
+		go func() {
+			log.Printf("HTTP API stopped: %v", http.Serve(ln, game.server.Handler()))
+		}()
+	}
+	return game, nil
+}
+
+// newGameServer returns the HTTP API for a game running sc.
+func newGameServer(sc *scenario.Scenario, o *options) *server.Server {
+	srv := server.New()
+	srv.SetScenario(sc)
+	srv.Configure = o.configure
+	if o.readToken != "" || o.controlToken != "" {
+		srv.Anonymous = server.RoleNone
+		if o.public {
+			srv.Anonymous = server.RoleRead
+		}
+	}
+	if o.readToken != "" {
+		srv.AddToken(o.readToken, server.RoleRead)
+	}
+	if o.controlToken != "" {
+		srv.AddToken(o.controlToken, server.RoleControl)
+	} else {
+		log.Print("the HTTP API is read-only without -http-control-token")
+	}
+	return srv
+}
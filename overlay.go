@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+const (
+	metersPerUnit = 1 / nbody.OrbitScale // world distance unit in metres
+	au            = 149.597870700e9      // astronomical unit in metres
+
+	// scaleBarMax is the longest the scale bar gets, in pixels.
+	scaleBarMax = 120
+	// maxGridLines bounds the grid lines drawn along each axis.
+	maxGridLines = 100
+)
+
+// scaleStep returns the longest round distance, in world units, that spans
+// at most scaleBarMax pixels at zoom, with its label: whole or fractional
+// AU from a tenth of an AU up, and kilometres below.
+func scaleStep(zoom float64) (float64, string) {
+	meters := scaleBarMax * uiScale / zoom * metersPerUnit
+	if meters >= au/10 {
+		n := roundDown(meters / au)
+		return n * au / metersPerUnit, formatRound(n) + " AU"
+	}
+	n := roundDown(meters / 1e3)
+	return n * 1e3 / metersPerUnit, formatRound(n) + " km"
+}
+
+// formatDistance formats a distance in world units in AU from a tenth of
+// an AU up, and in kilometres below.
+func formatDistance(d float64) string {
+	m := d * metersPerUnit
+	if m >= au/10 {
+		return fmt.Sprintf("%.3f AU", m/au)
+	}
+	return fmt.Sprintf("%.0f km", m/1e3)
+}
+
+// roundDown returns the largest 1, 2 or 5 times a power of ten not above x.
+func roundDown(x float64) float64 {
+	p := math.Pow(10, math.Floor(math.Log10(x)))
+	for _, m := range []float64{5, 2} {
+		if m*p <= x {
+			return m * p
+		}
+	}
+	return p
+}
+
+// formatRound formats a number with one significant digit, as returned
+// by roundDown, without an exponent.
+func formatRound(x float64) string {
+	return fmt.Sprintf("%.*f", max(0, int(-math.Floor(math.Log10(x)))), x)
+}
+
+// drawScaleBar draws a bar of the current scale step in the bottom right
+// corner of vp.
+func drawScaleBar(dst *ebiten.Image, vp *Viewport) {
+	step, label := scaleStep(vp.Camera.Zoom)
+	length := float32(step * vp.Camera.Zoom)
+	x := float32(vp.Bounds.Max.X) - 12 - length
+	y := float32(vp.Bounds.Max.Y) - 12
+	vector.StrokeLine(dst, x, y, x+length, y, 1, theme.ScaleBar, false)
+	vector.StrokeLine(dst, x, y-4, x, y+1, 1, theme.ScaleBar, false)
+	vector.StrokeLine(dst, x+length, y-4, x+length, y+1, 1, theme.ScaleBar, false)
+	drawText(dst, label, int(x+length)-len(label)*debugCharWidth, int(y)-debugLineHeight-2)
+}
+
+// drawGrid queues lines of a grid spaced at the current scale step, fixed
+// in the viewport's frame, on lines. center is the camera center.
+func drawGrid(dst *ebiten.Image, lines *lineBatch, vp *Viewport, center nbody.Vector2D) {
+	step, _ := scaleStep(vp.Camera.Zoom)
+	halfW := float64(vp.Bounds.Dx()) / 2 / vp.Camera.Zoom
+	halfH := float64(vp.Bounds.Dy()) / 2 / vp.Camera.Zoom
+	x0, x1 := center.X-halfW, center.X+halfW
+	y0, y1 := center.Y-halfH, center.Y+halfH
+	if (x1-x0)/step > maxGridLines || (y1-y0)/step > maxGridLines {
+		return
+	}
+	for x := math.Ceil(x0/step) * step; x <= x1; x += step {
+		lines.add(dst, vp.toScreen(nbody.Vector2D{X: x, Y: y0}, center), vp.toScreen(nbody.Vector2D{X: x, Y: y1}, center), theme.Grid)
+	}
+	for y := math.Ceil(y0/step) * step; y <= y1; y += step {
+		lines.add(dst, vp.toScreen(nbody.Vector2D{X: x0, Y: y}, center), vp.toScreen(nbody.Vector2D{X: x1, Y: y}, center), theme.Grid)
+	}
+	lines.flush(dst)
+}
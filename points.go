@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"runtime"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// renderMode selects how bodies are drawn.
+type renderMode int
+
+const (
+	renderAuto    renderMode = iota // points for large systems, circles otherwise
+	renderCircles                   // batched triangle-fan circles
+	renderPoints                    // batched point sprites
+)
+
+func parseRenderMode(s string) (renderMode, error) {
+	switch s {
+	case "auto":
+		return renderAuto, nil
+	case "circles":
+		return renderCircles, nil
+	case "points":
+		return renderPoints, nil
+	}
+	return 0, fmt.Errorf("unknown render mode %q", s)
+}
+
+// Body counts from which renderAuto switches to point sprites. Circles cost
+// dozens of vertices each, which WebGL in the browser tolerates far less
+// well than native graphics APIs.
+const (
+	autoPointsNative = 5000
+	autoPointsWasm   = 1000
+)
+
+// points reports whether n bodies are drawn as point sprites.
+func (m renderMode) points(n int) bool {
+	switch m {
+	case renderCircles:
+		return false
+	case renderPoints:
+		return true
+	}
+	if runtime.GOOS == "js" {
+		return n >= autoPointsWasm
+	}
+	return n >= autoPointsNative
+}
+
+// spriteSize is the side in pixels of the disc texture point sprites are
+// scaled from.
+const spriteSize = 16
+
+// minPointRadius keeps bodies smaller than a pixel visible.
+const minPointRadius = 1
+
+// pointRadius is the radius on screen, in pixels, below which a body is a
+// point sprite whatever the render mode: a circle that small looks the
+// same and costs a draw call of its own.
+const pointRadius = 1.5
+
+// pointBatch draws bodies as quads textured with a disc, which is cheaper
+// than a circle's fan of triangles and looks the same at small sizes.
+type pointBatch struct {
+	batch
+}
+
+// add queues a disc of radius pixels at (x, y).
+func (p *pointBatch) add(dst *ebiten.Image, x, y, radius float64, c color.Color) {
+	if p.src == nil {
+		p.src = ebiten.NewImage(spriteSize, spriteSize)
+		vector.DrawFilledCircle(p.src, spriteSize/2, spriteSize/2, spriteSize/2, color.White, true)
+	}
+	base := p.reserve(dst, 4, 6)
+	r := float32(max(radius, minPointRadius*uiScale))
+	v := vertexColor(c)
+	for _, corner := range [4][2]float32{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		v.DstX = float32(x) + (2*corner[0]-1)*r
+		v.DstY = float32(y) + (2*corner[1]-1)*r
+		v.SrcX = corner[0] * spriteSize
+		v.SrcY = corner[1] * spriteSize
+		p.vertices = append(p.vertices, v)
+	}
+	p.indices = append(p.indices, base, base+1, base+2, base+1, base+3, base+2)
+}
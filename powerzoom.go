@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// powerZoom is a nonlinear radial view transform around a fixed center. The
+// screen radius of a point is a blend of its log distance and the rank of
+// that log distance among all bodies (a histogram-equalized log scale), so
+// orbits spanning several orders of magnitude share the screen evenly while
+// inner structure stays visible.
+type powerZoom struct {
+	center    nbody.Vector2D
+	maxRadius float64   // screen radius of the outermost body
+	knots     []float64 // sorted log distances, knots[0] is always 0
+}
+
+// update rebuilds the equalization knots from the current body positions.
+func (z *powerZoom) update(positions []nbody.Vector2D, center nbody.Vector2D, maxRadius float64) {
+	z.center = center
+	z.maxRadius = maxRadius
+	z.knots = append(z.knots[:0], 0)
+	for _, p := range positions {
+		if l := z.logDistance(p); l > 0 {
+			z.knots = append(z.knots, l)
+		}
+	}
+	sort.Float64s(z.knots)
+}
+
+// logDistance returns log(r) of p's distance from the center, clamped so that
+// everything within one world unit maps to zero.
+func (z *powerZoom) logDistance(p nbody.Vector2D) float64 {
+	r := math.Hypot(p.X-z.center.X, p.Y-z.center.Y)
+	return math.Log(math.Max(r, 1))
+}
+
+// radius maps a log distance to a screen radius.
+func (z *powerZoom) radius(l float64) float64 {
+	n := len(z.knots)
+	if n < 2 || z.knots[n-1] <= 0 {
+		return 0
+	}
+	last := z.knots[n-1]
+
+	uLog := l / last
+	uEq := 1.0
+	if i := sort.SearchFloat64s(z.knots, l); i < n {
+		uEq = float64(i) / float64(n-1)
+		if i > 0 && z.knots[i] > z.knots[i-1] {
+			frac := (l - z.knots[i-1]) / (z.knots[i] - z.knots[i-1])
+			uEq = (float64(i-1) + frac) / float64(n-1)
+		}
+	}
+	return (uLog + uEq) / 2 * z.maxRadius
+}
+
+// apply maps a world position to its power-zoomed position.
+func (z *powerZoom) apply(p nbody.Vector2D) nbody.Vector2D {
+	dx, dy := p.X-z.center.X, p.Y-z.center.Y
+	r := math.Hypot(dx, dy)
+	if r == 0 {
+		return z.center
+	}
+	s := z.radius(z.logDistance(p)) / r
+	return nbody.Vector2D{X: z.center.X + dx*s, Y: z.center.Y + dy*s}
+}
+
+// drawScale draws reference rings at powers of ten of the world distance
+// unit, labelled in kilometres, so it is obvious the radial scale is not
+// linear. center is the viewport's camera center in power-zoomed space.
+func (z *powerZoom) drawScale(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	kmPerUnit := 1 / nbody.OrbitScale / 1e3
+	c := vp.toScreen(z.center, center)
+	for d := 1.0; d <= 1e4; d *= 10 {
+		r := z.radius(math.Log(d)) * vp.Camera.Zoom
+		if r <= 0 || r > 2*z.maxRadius*vp.Camera.Zoom {
+			continue
+		}
+		vector.StrokeCircle(dst, float32(c.X), float32(c.Y), float32(r), 1, theme.ScaleRings, true)
+		drawText(dst, fmt.Sprintf("%.0e km", d*kmPerUnit), int(c.X+r)+2, int(c.Y))
+	}
+	drawText(dst, "POWER ZOOM: log-equalized radial scale", vp.Bounds.Min.X+4, vp.Bounds.Min.Y+4)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"cmp"
+	"image/color"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+const (
+	// predictEvery is the number of physics steps between predictions of
+	// the same body, so the preview follows changing conditions.
+	predictEvery = 30
+	// predictPerturbers is how many of the bodies pulling hardest on the
+	// predicted one are integrated with it; the rest are left out, so a
+	// prediction costs the same however large the system.
+	predictPerturbers = 32
+	// predictMaxPairs bounds the force evaluations of one prediction, which
+	// shortens it for many substeps.
+	predictMaxPairs = 2e7
+	// predictMinSteps is the shortest prediction worth drawing.
+	predictMinSteps = 10
+	// predictMaxPoints bounds the dots drawn along a predicted path.
+	predictMaxPoints = 400
+)
+
+// predictor previews where the selected body is heading by integrating a
+// copy of it and its major perturbers ahead on a background goroutine.
+// The other bodies pull on it too weakly to change its path visibly over
+// the preview, but would make it cost as much as the simulation. The path
+// is kept relative to the body dominating the selected one's motion when
+// the prediction starts, so an orbit shows as an orbit around its primary
+// rather than smeared along the primary's own path.
+type predictor struct {
+	steps int // physics steps looked ahead; zero turns predictions off
+
+	busy   atomic.Bool       // a prediction is running
+	snap   nbody.Snapshot    // state the running prediction started from
+	fork   *nbody.Simulation // integrated by the running prediction
+	bodies []nbody.Body
+	keep   []int      // indices of the bodies the fork keeps
+	last   int        // body predicted last
+	due    int        // sim.Steps from which it is predicted again
+	spawn  nbody.Body // added to the fork for a spawn preview
+
+	mu   sync.Mutex
+	path prediction // the latest finished prediction
+	next prediction // filled by the running prediction
+}
+
+type prediction struct {
+	spawn  bool             // of a body about to be spawned rather than one that exists
+	id     int              // body predicted
+	anchor int              // body the points are relative to, or nbody.NoBody
+	points []nbody.Vector2D // positions, one every stride steps
+}
+
+// start begins predicting body id from the current state of sim, unless a
+// prediction is running or the last one of the same body is recent. It must
+// be called from the goroutine that updates sim.
+func (p *predictor) start(sim *nbody.Simulation, id int) {
+	if p.steps <= 0 || id == nbody.NoBody || p.busy.Load() {
+		return
+	}
+	if p.last == id && sim.Steps < p.due {
+		return
+	}
+	steps := p.budget(sim, sim.Len())
+	if steps < predictMinSteps {
+		return
+	}
+	p.last, p.due = id, sim.Steps+predictEvery
+	p.next.spawn, p.next.id = false, id
+	p.prepareFork(sim)
+	p.busy.Store(true)
+	go p.run(steps)
+}
+
+// invalidate makes the next start predict afresh, as after the selected
+// body was moved.
+func (p *predictor) invalidate() {
+	p.due = 0
+}
+
+// startSpawn begins predicting the path of b as if it were added to sim,
+// unless a prediction is running. It must be called from the goroutine that
+// updates sim.
+func (p *predictor) startSpawn(sim *nbody.Simulation, b nbody.Body) {
+	if p.steps <= 0 || p.busy.Load() {
+		return
+	}
+	steps := p.budget(sim, sim.Len()+1)
+	if steps < predictMinSteps {
+		return
+	}
+	p.spawn, p.next.spawn = b, true
+	p.due = 0 // predict the selected body afresh afterwards
+	p.prepareFork(sim)
+	p.busy.Store(true)
+	go p.run(steps)
+}
+
+// budget returns how many steps to predict for n bodies in sim.
+func (p *predictor) budget(sim *nbody.Simulation, n int) int {
+	n = min(n, predictPerturbers+1)
+	return min(p.steps, int(predictMaxPairs/float64(n*n*max(sim.Substeps, 1))))
+}
+
+// prepareFork snapshots sim for the fork the next prediction integrates.
+func (p *predictor) prepareFork(sim *nbody.Simulation) {
+	// The copy keeps the physics that decide where bodies go, but sums
+	// gravity directly: solvers keep state that isn't safe to share.
+	if p.fork == nil {
+		p.fork = nbody.NewSimulation(sim.Width, sim.Height)
+	}
+	p.fork.Width, p.fork.Height = sim.Width, sim.Height
+	p.fork.MOND, p.fork.Dt, p.fork.Substeps = sim.MOND, sim.Dt, sim.Substeps
+	p.fork.Collisions, p.fork.Precision = sim.Collisions, sim.Precision
+	sim.Snapshot(&p.snap)
+}
+
+// run integrates the fork and publishes the predicted path.
+func (p *predictor) run(steps int) {
+	defer p.busy.Store(false)
+	s := p.fork
+	s.Restore(&p.snap)
+	next := &p.next
+	next.points = next.points[:0]
+	if next.spawn {
+		next.id = s.AddBody(p.spawn)
+	}
+	i, ok := s.IndexOf(next.id)
+	if !ok {
+		return
+	}
+	p.bodies = s.AppendBodies(p.bodies[:0])
+	if len(p.bodies) > predictPerturbers+1 {
+		p.keepPerturbers(s, i)
+		i, _ = s.IndexOf(next.id)
+		p.bodies = s.AppendBodies(p.bodies[:0])
+	}
+	next.anchor = nbody.NoBody
+	a := -1
+	if j, ok := nbody.DominantBody(p.bodies, i); ok && p.bodies[j].Mass > p.bodies[i].Mass {
+		next.anchor, a = p.bodies[j].ID, j
+	}
+
+	stride := max(1, (steps+predictMaxPoints-1)/predictMaxPoints)
+	for k := 1; k <= steps; k++ {
+		s.Update()
+		// Mergers shift indices, and may take either body.
+		if i >= s.Len() || s.ID(i) != next.id {
+			if i, ok = s.IndexOf(next.id); !ok {
+				break
+			}
+		}
+		if a >= 0 && (a >= s.Len() || s.ID(a) != next.anchor) {
+			if a, ok = s.IndexOf(next.anchor); !ok {
+				break
+			}
+		}
+		if k%stride != 0 {
+			continue
+		}
+		pos := s.Position(i)
+		if a >= 0 {
+			anchor := s.Position(a)
+			pos = nbody.Vector2D{X: pos.X - anchor.X, Y: pos.Y - anchor.Y}
+		}
+		next.points = append(next.points, pos)
+	}
+
+	p.mu.Lock()
+	p.path, p.next = p.next, p.path
+	p.mu.Unlock()
+}
+
+// keepPerturbers removes from s every body but the i-th and the
+// predictPerturbers pulling hardest on it.
+func (p *predictor) keepPerturbers(s *nbody.Simulation, i int) {
+	target := p.bodies[i].Position
+	pull := func(j int) float64 {
+		q := p.bodies[j].Position
+		dx, dy := q.X-target.X, q.Y-target.Y
+		return p.bodies[j].Mass / (dx*dx + dy*dy)
+	}
+	p.keep = p.keep[:0]
+	for j := range p.bodies {
+		if j != i {
+			p.keep = append(p.keep, j)
+		}
+	}
+	slices.SortFunc(p.keep, func(a, b int) int { return cmp.Compare(pull(b), pull(a)) })
+	p.keep = append(p.keep[:predictPerturbers], i)
+	slices.Sort(p.keep)
+	s.KeepBodies(p.keep)
+}
+
+// drawPrediction draws the latest predicted path of the selected body in vp
+// as dots. center is the camera center.
+func (g *Game) drawPrediction(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	p := &g.predictor
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.path.spawn != g.spawner.dragging || !p.path.spawn && p.path.id != g.selected || len(p.path.points) == 0 {
+		return
+	}
+	var origin nbody.Vector2D
+	anchored := p.path.anchor == nbody.NoBody
+	c := color.Color(color.White)
+	for _, b := range g.drawBodies {
+		if b.ID == p.path.anchor {
+			origin, anchored = b.Position, true
+		}
+		if b.ID == p.path.id {
+			c = b.Color
+		}
+	}
+	if !anchored {
+		return
+	}
+	r, gr, b, a := c.RGBA()
+	dim := color.RGBA64{uint16(r * 3 / 4), uint16(gr * 3 / 4), uint16(b * 3 / 4), uint16(a * 3 / 4)}
+	for _, q := range p.path.points {
+		w := nbody.Vector2D{X: origin.X + q.X, Y: origin.Y + q.Y}
+		s := vp.toScreen(g.view(vp.Frame.apply(w)), center)
+		g.points.add(dst, s.X, s.Y, minPointRadius, dim)
+	}
+	g.points.flush(dst)
+}
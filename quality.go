@@ -0,0 +1,144 @@
+package main
+
+import (
+	"cmp"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// quality is a level of the governor, each giving up more than the last.
+// Visual levels come first so physics accuracy is the last thing lost.
+type quality int
+
+const (
+	qualityFull        quality = iota
+	qualityNoGlow              // no glows around luminous bodies
+	qualityShortTrails         // only the newest part of each trail drawn
+	qualityPoints              // draw every body as a point sprite
+	qualitySubsteps            // one physics substep per step
+	qualityFloat32             // single-precision direct gravity sum
+	qualityLowest      = qualityFloat32
+)
+
+var qualityNames = [...]string{
+	qualityFull:        "full",
+	qualityNoGlow:      "no glow",
+	qualityShortTrails: "short trails",
+	qualityPoints:      "point sprites",
+	qualitySubsteps:    "single substep",
+	qualityFloat32:     "float32 gravity",
+}
+
+func (q quality) String() string {
+	return qualityNames[q]
+}
+
+const (
+	// governorWindow is the number of frames averaged per decision.
+	governorWindow = 60
+	// governorCalm is the number of consecutive windows well under budget
+	// before a level is restored, so quality doesn't flap at the edge.
+	governorCalm = 5
+)
+
+// governor watches how long the game spends updating and drawing each
+// frame, and lowers quality a level at a time while frames run over
+// budget, restoring it once they have been comfortably under for a while.
+type governor struct {
+	auto    bool
+	level   quality
+	applied quality       // level the simulation's options were last set for
+	budget  time.Duration // work per frame to stay under
+
+	busy   time.Duration // work in the current frame
+	total  time.Duration // work in the current window
+	frames int
+	calm   int
+
+	// The simulation's own settings, saved when physics quality is first
+	// lowered and restored once it is regained.
+	substeps  int
+	precision nbody.Precision
+}
+
+// newGovernor returns a governor keeping each frame within a tick at tps
+// ticks per second, or Ebitengine's default rate if tps is zero.
+func newGovernor(auto bool, tps int) governor {
+	return governor{auto: auto, budget: time.Second / time.Duration(cmp.Or(tps, ebiten.DefaultTPS))}
+}
+
+// work adds d to the time spent on the current frame.
+func (q *governor) work(d time.Duration) {
+	q.busy += d
+}
+
+// frame ends the current frame, changing level at the end of a window.
+func (q *governor) frame() {
+	q.total += q.busy
+	q.busy = 0
+	q.frames++
+	if q.frames < governorWindow {
+		return
+	}
+	avg := q.total / time.Duration(q.frames)
+	q.total, q.frames = 0, 0
+	if !q.auto {
+		return
+	}
+	switch {
+	case avg > q.budget*9/10 && q.level < qualityLowest:
+		q.level++
+		q.calm = 0
+	case avg < q.budget*4/10 && q.level > qualityFull:
+		if q.calm++; q.calm >= governorCalm {
+			q.level--
+			q.calm = 0
+		}
+	default:
+		q.calm = 0
+	}
+}
+
+// toggle switches the governor on or off. Switched off, it keeps full
+// quality however slow frames get.
+func (q *governor) toggle() {
+	q.auto = !q.auto
+	q.level, q.calm = qualityFull, 0
+}
+
+// apply sets the physics options of sim for the current level when it has
+// changed, leaving them to others, such as a scenario loaded through the
+// HTTP API, while it stays the same.
+func (q *governor) apply(sim *nbody.Simulation) {
+	if q.level == q.applied {
+		return
+	}
+	if q.applied < qualitySubsteps {
+		if q.level < qualitySubsteps {
+			q.applied = q.level // the simulation's options are its own
+			return
+		}
+		q.substeps, q.precision = sim.Substeps, sim.Precision
+	}
+	q.applied = q.level
+	sim.Substeps, sim.Precision = q.substeps, q.precision
+	if q.level >= qualitySubsteps {
+		sim.Substeps = 1
+	}
+	if q.level >= qualityFloat32 {
+		sim.Precision = nbody.Float32
+	}
+}
+
+// draw shows the reduced level, if any, in the top right corner.
+func (q *governor) draw(screen *ebiten.Image) {
+	if q.level == qualityFull {
+		return
+	}
+	msg := "reduced quality: " + q.level.String() + " (G restores)"
+	x := screen.Bounds().Dx() - len(msg)*debugCharWidth - 4
+	drawText(screen, msg, x, 0)
+}
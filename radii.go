@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"n-body/nbody"
+)
+
+// radiusScale selects how big bodies are drawn.
+type radiusScale int
+
+const (
+	radiusTrue radiusScale = iota // the physical radius, to scale
+	radiusLog                     // by the log of the physical radius, within fixed bounds
+)
+
+func parseRadiusScale(s string) (radiusScale, error) {
+	switch s {
+	case "true":
+		return radiusTrue, nil
+	case "log":
+		return radiusLog, nil
+	}
+	return 0, fmt.Errorf("unknown radius scale %q", s)
+}
+
+// Bounds in pixels of the radii drawn by radiusLog, smallest body to
+// largest, whatever the zoom.
+const (
+	logRadiusMin = 2
+	logRadiusMax = 16
+)
+
+// sizer decides the radius bodies are drawn with on screen. Only the look
+// changes: collisions keep using the physical radius. Real radii span
+// orders of magnitude, so at true scale whole planetary systems show as
+// points, while radiusLog keeps every body visible with the larger ones
+// larger. A body that is bigger on screen at true scale is drawn so.
+type sizer struct {
+	scale  radiusScale
+	lo, hi float64 // range of log10 of the positive radii
+}
+
+// fit takes the range of radii to scale from bodies.
+func (s *sizer) fit(bodies []nbody.Body) {
+	if s.scale != radiusLog {
+		return
+	}
+	s.lo, s.hi = math.Inf(1), math.Inf(-1)
+	for _, b := range bodies {
+		if b.Radius > 0 {
+			r := math.Log10(b.Radius)
+			s.lo, s.hi = math.Min(s.lo, r), math.Max(s.hi, r)
+		}
+	}
+}
+
+// toggle switches between true and log radii.
+func (s *sizer) toggle() {
+	s.scale = 1 - s.scale
+}
+
+// radius returns the radius in pixels to draw b with at zoom.
+func (s *sizer) radius(b nbody.Body, zoom float64) float64 {
+	r := b.Radius * zoom
+	if s.scale != radiusLog || !(b.Radius > 0) {
+		return r
+	}
+	t := 0.5
+	if s.hi > s.lo {
+		t = (math.Log10(b.Radius) - s.lo) / (s.hi - s.lo)
+	}
+	return math.Max(r, uiScale*(logRadiusMin+math.Max(0, math.Min(t, 1))*(logRadiusMax-logRadiusMin)))
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/scenario"
+)
+
+// recorder writes the rendered frames of a session as a PNG sequence for
+// video encoding. Frames are placed on a constant-frame-rate grid of
+// simulated time: a drawn frame is kept only when it reaches a new slot of
+// the grid, and one that lands several slots later is held on screen for
+// all of them. The manifest, an ffconcat file, gives each image's duration
+// from timestamps rounded once from the slot numbers, so durations never
+// accumulate rounding error and the video stays in sync however the live
+// simulation was paced. Annotations are written alongside as SubRip and
+// WebVTT captions on the same timeline.
+//
+// Given a path ending in .gif, the recorder writes an animated GIF instead,
+// its frames shrunk to at most gifMaxWidth pixels across, dithered to a
+// fixed palette and timed the same way, to the nearest hundredth of a
+// second. The frames are held in memory until the recording is closed.
+type recorder struct {
+	dir         string // or the GIF file
+	fps         float64
+	every       int     // keep one drawn frame in every
+	length      float64 // simulated seconds to record, or 0 until closed
+	annotations []scenario.Annotation
+
+	drawn   int     // frames offered to capture
+	start   float64 // simulated time of slot 0
+	slot    int     // slot of the last recorded frame, or -1
+	entries []recordEntry
+	gif     *gif.GIF // nil for a PNG sequence
+
+	frames  chan *ebiten.Image // copies of the screen free for capture
+	queue   chan recordJob
+	done    chan error
+	dropped int // frames skipped while the writer was behind
+}
+
+// recordings starts and stops recordings, with F9 or after -record-for,
+// closing each in the background.
+type recordings struct {
+	dir         string // where F9 recordings go
+	gif         bool   // whether they are GIFs rather than PNG sequences
+	fps         float64
+	every       int
+	length      float64
+	annotations []scenario.Annotation
+
+	closing sync.WaitGroup
+}
+
+// start begins a recording into path, or into a new timestamped one in the
+// directory if path is empty.
+func (rs *recordings) start(path string) (*recorder, error) {
+	if path == "" {
+		path = filepath.Join(rs.dir, "recording-"+time.Now().Format("20060102-150405"))
+		if rs.gif {
+			path += ".gif"
+		}
+	}
+	r, err := newRecorder(path, rs.fps, rs.every, rs.length, rs.annotations)
+	if err == nil {
+		log.Printf("recording to %s", path)
+	}
+	return r, err
+}
+
+// stop finishes r in the background, logging the outcome.
+func (rs *recordings) stop(r *recorder) {
+	rs.closing.Add(1)
+	go func() {
+		defer rs.closing.Done()
+		if err := r.Close(); err != nil {
+			log.Printf("recording %s: %v", r.dir, err)
+			return
+		}
+		log.Printf("saved recording %s", r.dir)
+	}()
+}
+
+// recordEntry is one image of the sequence, shown from slot onwards.
+type recordEntry struct {
+	file string
+	slot int
+}
+
+type recordJob struct {
+	path  string
+	frame *ebiten.Image // returned to frames once read back
+}
+
+// recordQueue is how many frames a recording holds waiting to be read
+// back and encoded. Frames captured while it is full are dropped.
+const recordQueue = 8
+
+// gifMaxWidth is the widest a frame of a GIF recording is kept, since every
+// frame is held in memory until the recording is closed.
+const gifMaxWidth = 640
+
+// newRecorder starts a recording into path at fps frames per simulated
+// second, keeping one drawn frame in every and stopping after length
+// simulated seconds if that is not zero.
+func newRecorder(path string, fps float64, every int, length float64, annotations []scenario.Annotation) (*recorder, error) {
+	r := &recorder{
+		dir:         path,
+		fps:         fps,
+		every:       max(every, 1),
+		length:      length,
+		annotations: annotations,
+		slot:        -1,
+		frames:      make(chan *ebiten.Image, recordQueue),
+		queue:       make(chan recordJob, recordQueue),
+		done:        make(chan error, 1),
+	}
+	for range recordQueue {
+		r.frames <- nil // allocated at the first capture, to the screen's size
+	}
+	if strings.EqualFold(filepath.Ext(path), ".gif") {
+		r.gif = &gif.GIF{}
+	} else if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, err
+	}
+	go r.write()
+	return r, nil
+}
+
+// capture records screen if simulated time t has reached a new slot, and
+// reports whether the recording has reached its length. It only copies the
+// screen on the GPU, leaving the writer to read the copy back and encode
+// it; while the writer is behind, frames are dropped instead, and the
+// previous one is held on screen in their place.
+func (r *recorder) capture(screen *ebiten.Image, t float64) (finished bool) {
+	r.drawn++
+	if (r.drawn-1)%r.every != 0 {
+		return false
+	}
+	if r.slot < 0 {
+		r.start = t
+	}
+	if r.length > 0 && t-r.start >= r.length {
+		return true
+	}
+	slot := int(math.Floor((t - r.start) * r.fps))
+	if slot <= r.slot {
+		return false
+	}
+	r.slot = slot
+
+	var frame *ebiten.Image
+	select {
+	case frame = <-r.frames:
+	default:
+		r.dropped++
+		return false
+	}
+	if frame == nil || frame.Bounds().Size() != screen.Bounds().Size() {
+		if frame != nil {
+			frame.Deallocate()
+		}
+		frame = ebiten.NewImage(screen.Bounds().Dx(), screen.Bounds().Dy())
+	}
+	frame.DrawImage(screen, nil)
+	file := fmt.Sprintf("frame-%06d.png", len(r.entries))
+	r.entries = append(r.entries, recordEntry{file: file, slot: slot})
+	// Never blocks: there are no more frames than room in the queue.
+	r.queue <- recordJob{path: filepath.Join(r.dir, file), frame: frame}
+	return false
+}
+
+// write reads back and encodes queued frames until the queue is closed,
+// reporting the first error to done.
+func (r *recorder) write() {
+	var err error
+	for job := range r.queue {
+		if err != nil {
+			r.frames <- job.frame
+			continue
+		}
+		img := image.NewRGBA(job.frame.Bounds())
+		job.frame.ReadPixels(img.Pix)
+		r.frames <- job.frame
+		if r.gif != nil {
+			r.gif.Image = append(r.gif.Image, gifFrame(img))
+			continue
+		}
+		err = writePNG(job.path, img)
+	}
+	r.done <- err
+}
+
+// gifFrame shrinks img to at most gifMaxWidth across and dithers it to the
+// web-safe palette.
+func gifFrame(img *image.RGBA) *image.Paletted {
+	b := img.Bounds()
+	step := max(1, (b.Dx()+gifMaxWidth-1)/gifMaxWidth)
+	small := image.NewRGBA(image.Rect(0, 0, b.Dx()/step, b.Dy()/step))
+	for y := range small.Rect.Dy() {
+		for x := range small.Rect.Dx() {
+			small.SetRGBA(x, y, img.RGBAAt(b.Min.X+x*step, b.Min.Y+y*step))
+		}
+	}
+	frame := image.NewPaletted(small.Rect, palette.WebSafe)
+	draw.FloydSteinberg.Draw(frame, frame.Rect, small, image.Point{})
+	return frame
+}
+
+// Close waits for pending frames and writes the manifest and captions.
+func (r *recorder) Close() error {
+	close(r.queue)
+	err := <-r.done
+	for range recordQueue {
+		if frame := <-r.frames; frame != nil {
+			frame.Deallocate()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if r.dropped > 0 {
+		log.Printf("recording %s: dropped %d frames while writing fell behind", r.dir, r.dropped)
+	}
+	if len(r.entries) == 0 {
+		return nil
+	}
+	if r.gif != nil {
+		return r.writeGIF()
+	}
+	if cs := captions(r.annotations, r.start, time.Duration(r.timestamp(r.slot+1))*time.Microsecond); len(cs) > 0 {
+		if err := writeCaptions(filepath.Join(r.dir, "captions.srt"), cs, false); err != nil {
+			return err
+		}
+		if err := writeCaptions(filepath.Join(r.dir, "captions.vtt"), cs, true); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(filepath.Join(r.dir, "frames.ffconcat"))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "ffconcat version 1.0\n")
+	fmt.Fprintf(w, "# %g fps constant frame rate; encode with:\n", r.fps)
+	fmt.Fprintf(w, "#   ffmpeg -f concat -i frames.ffconcat -fps_mode cfr -r %g -pix_fmt yuv420p video.mp4\n", r.fps)
+	for i, e := range r.entries {
+		end := r.slot + 1
+		if i+1 < len(r.entries) {
+			end = r.entries[i+1].slot
+		}
+		fmt.Fprintf(w, "file '%s'\nduration %dus\n", e.file, r.timestamp(end)-r.timestamp(e.slot))
+	}
+	// The concat demuxer ignores the last entry's duration unless the
+	// file is listed once more.
+	fmt.Fprintf(w, "file '%s'\n", r.entries[len(r.entries)-1].file)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeGIF times the frames of a GIF recording and writes it.
+func (r *recorder) writeGIF() error {
+	r.gif.Delay = r.gif.Delay[:0]
+	for i, e := range r.entries {
+		end := r.slot + 1
+		if i+1 < len(r.entries) {
+			end = r.entries[i+1].slot
+		}
+		// Round from the timestamps in centiseconds, so delays don't
+		// accumulate rounding error either.
+		delay := (r.timestamp(end)+5000)/10000 - (r.timestamp(e.slot)+5000)/10000
+		r.gif.Delay = append(r.gif.Delay, int(max(delay, 1)))
+	}
+	f, err := os.Create(r.dir)
+	if err != nil {
+		return err
+	}
+	err = gif.EncodeAll(f, r.gif)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// timestamp returns the start of slot in whole microseconds.
+func (r *recorder) timestamp(slot int) int64 {
+	return int64(math.Round(float64(slot) * 1e6 / r.fps))
+}
@@ -0,0 +1,36 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Rasterize draws f onto dst as discs on a black background, fitting the
+// world to dst like Terminal does, for images of a simulation run without a
+// window. Bodies smaller than a pixel are drawn as one.
+func Rasterize(dst *image.RGBA, f *Frame) {
+	b := dst.Bounds()
+	for i := range dst.Pix {
+		dst.Pix[i] = 0
+		if i%4 == 3 {
+			dst.Pix[i] = 0xff
+		}
+	}
+	w, h := float64(b.Dx()), float64(b.Dy())
+	scale := math.Min(w/f.Width, h/f.Height) // pixels per world unit
+	for _, body := range f.Bodies {
+		x := float64(b.Min.X) + w/2 + (body.Position.X-f.Center.X)*scale
+		y := float64(b.Min.Y) + h/2 + (body.Position.Y-f.Center.Y)*scale
+		r := math.Max(body.Radius*scale, 0.5)
+		c := color.RGBAModel.Convert(body.Color).(color.RGBA)
+		c.A = 0xff
+		for py := int(math.Floor(y - r)); py <= int(math.Ceil(y+r)); py++ {
+			for px := int(math.Floor(x - r)); px <= int(math.Ceil(x+r)); px++ {
+				if math.Hypot(float64(px)+0.5-x, float64(py)+0.5-y) <= r || (px == int(math.Floor(x)) && py == int(math.Floor(y))) {
+					dst.SetRGBA(px, py, c)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"n-body/nbody"
+)
+
+func TestRasterize(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	black := color.RGBA{A: 0xff}
+	f := &Frame{
+		Width: 10, Height: 10,
+		Center: nbody.Vector2D{X: 5, Y: 5},
+		Bodies: []nbody.Body{
+			{Position: nbody.Vector2D{X: 2, Y: 2}, Radius: 1, Color: red},
+			{Position: nbody.Vector2D{X: 7.3, Y: 7.6}, Color: blue}, // smaller than a pixel
+		},
+	}
+	tests := []struct {
+		name   string
+		bounds image.Rectangle
+		offset image.Point // of the world's origin in the image
+	}{
+		{"square", image.Rect(0, 0, 10, 10), image.Point{}},
+		{"wide", image.Rect(0, 0, 20, 10), image.Pt(5, 0)},
+		{"offset", image.Rect(3, 4, 13, 14), image.Pt(3, 4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := image.NewRGBA(tt.bounds)
+			Rasterize(dst, f)
+			want := map[image.Point]color.RGBA{
+				{1, 1}: red, {2, 1}: red, {1, 2}: red, {2, 2}: red,
+				{0, 0}: black, {3, 2}: black, {2, 3}: black,
+				{7, 7}: blue, {8, 7}: black, {7, 8}: black,
+				{5, 5}: black,
+			}
+			for p, c := range want {
+				if got := dst.RGBAAt(p.X+tt.offset.X, p.Y+tt.offset.Y); got != c {
+					t.Errorf("pixel %v = %v, want %v", p, got, c)
+				}
+			}
+		})
+	}
+}
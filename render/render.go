@@ -0,0 +1,39 @@
+// Package render draws the simulation: a Renderer is handed a Frame at a
+// time and draws it wherever it draws, the game window or otherwise, so new
+// outputs can be added without touching the simulation or the code that
+// drives it.
+package render
+
+import "n-body/nbody"
+
+// Frame is the simulation at a moment of simulated time, as handed to a
+// Renderer. Its slices belong to the caller and are only valid for the
+// duration of Render.
+type Frame struct {
+	Title         string  // what is being simulated, such as the scenario name
+	Time          float64 // simulated seconds
+	Bodies        []nbody.Body
+	Width, Height float64        // of the world
+	Center        nbody.Vector2D // of the world, where an unmoved camera looks
+}
+
+// Renderer draws frames.
+type Renderer interface {
+	// Render draws f.
+	Render(f *Frame) error
+	// Close finishes drawing, releasing anything Render took hold of.
+	Close() error
+}
+
+// Null draws nothing, for running the simulation without output or
+// measuring it without the cost of drawing.
+type Null struct {
+	Frames int // rendered so far
+}
+
+func (n *Null) Render(*Frame) error {
+	n.Frames++
+	return nil
+}
+
+func (n *Null) Close() error { return nil }
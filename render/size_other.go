@@ -0,0 +1,9 @@
+//go:build !unix
+
+package render
+
+// terminalSize reports that the terminal's size is unknown, so -cols and
+// -rows or their defaults are used.
+func terminalSize() (int, int, bool) {
+	return 0, 0, false
+}
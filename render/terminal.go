@@ -0,0 +1,219 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+
+	"n-body/nbody"
+)
+
+// Charset is how a terminal cell is divided into dots.
+type Charset int
+
+const (
+	// Braille divides each cell into 2×4 dots with the Unicode braille
+	// patterns, the finest detail a terminal can show.
+	Braille Charset = iota
+	// Blocks divides each cell into 2 dots stacked with half blocks, for
+	// fonts without braille.
+	Blocks
+)
+
+// ParseCharset parses the name of a Charset: braille or blocks.
+func ParseCharset(s string) (Charset, error) {
+	switch s {
+	case "braille":
+		return Braille, nil
+	case "blocks":
+		return Blocks, nil
+	}
+	return 0, fmt.Errorf("unknown charset %q (want braille or blocks)", s)
+}
+
+// dots returns the number of dots across and down a cell.
+func (c Charset) dots() (int, int) {
+	if c == Blocks {
+		return 1, 2
+	}
+	return 2, 4
+}
+
+// brailleBits are the bits of the braille pattern for each dot of a cell,
+// by row and column.
+var brailleBits = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// canvas is a grid of dots over cols × rows terminal cells, each cell
+// taking the color of the last dot set in it.
+type canvas struct {
+	charset    Charset
+	cols, rows int
+	w, h       int    // in dots
+	dots       []bool // row by row
+	colors     []color.RGBA
+}
+
+// resize makes the canvas cols × rows cells and clears it.
+func (cv *canvas) resize(cols, rows int) {
+	dx, dy := cv.charset.dots()
+	cv.cols, cv.rows = cols, rows
+	cv.w, cv.h = cols*dx, rows*dy
+	cv.dots = make([]bool, cv.w*cv.h)
+	cv.colors = make([]color.RGBA, cols*rows)
+}
+
+// clear unsets every dot.
+func (cv *canvas) clear() {
+	clear(cv.dots)
+}
+
+// set sets the dot at (x, y), if it is on the canvas, in color c.
+func (cv *canvas) set(x, y int, c color.RGBA) {
+	if x < 0 || x >= cv.w || y < 0 || y >= cv.h {
+		return
+	}
+	cv.dots[y*cv.w+x] = true
+	dx, dy := cv.charset.dots()
+	cv.colors[y/dy*cv.cols+x/dx] = c
+}
+
+// disc sets the dots within r of (x, y), or the one dot at (x, y) if none
+// are.
+func (cv *canvas) disc(x, y, r float64, c color.RGBA) {
+	if r < 0.5 {
+		cv.set(int(math.Floor(x)), int(math.Floor(y)), c)
+		return
+	}
+	for j := int(math.Floor(y - r)); j <= int(math.Ceil(y+r)); j++ {
+		for i := int(math.Floor(x - r)); i <= int(math.Ceil(x+r)); i++ {
+			if math.Hypot(float64(i)+0.5-x, float64(j)+0.5-y) <= r {
+				cv.set(i, j, c)
+			}
+		}
+	}
+}
+
+// render writes the canvas to w from the top left of the terminal, in 24-bit
+// color if colored is set.
+func (cv *canvas) render(w *bufio.Writer, colored bool) {
+	dx, dy := cv.charset.dots()
+	var last color.RGBA
+	w.WriteString("\x1b[H")
+	for row := range cv.rows {
+		for col := range cv.cols {
+			var ch rune
+			for j := range dy {
+				for i := range dx {
+					if !cv.dots[(row*dy+j)*cv.w+col*dx+i] {
+						continue
+					}
+					if cv.charset == Blocks {
+						ch |= 1 << j
+					} else {
+						ch |= brailleBits[j][i]
+					}
+				}
+			}
+			if ch == 0 {
+				w.WriteByte(' ')
+				continue
+			}
+			if c := cv.colors[row*cv.cols+col]; colored && c != last {
+				fmt.Fprintf(w, "\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+				last = c
+			}
+			if cv.charset == Blocks {
+				w.WriteRune([...]rune{' ', '▀', '▄', '█'}[ch])
+			} else {
+				w.WriteRune(0x2800 + ch)
+			}
+		}
+		w.WriteString("\r\n")
+	}
+	if colored {
+		w.WriteString("\x1b[0m")
+	}
+}
+
+// Terminal draws frames in a terminal with braille or half-block
+// characters, for demos over SSH or on machines without a display or GPU.
+// The view fits the world to the terminal, resizing with it, and ends with
+// a status line.
+type Terminal struct {
+	Charset    Charset
+	Color      bool    // draw bodies in their colors with 24-bit color escapes
+	Zoom       float64 // magnification about the center; 1 fits the world
+	Follow     int     // ID of the body kept at the center, or nbody.NoBody
+	Cols, Rows int     // size of the terminal if it can't be read
+
+	out     *bufio.Writer
+	canvas  canvas
+	started bool
+}
+
+// NewTerminal returns a Terminal writing to out, in color, fitting the
+// world and following no body.
+func NewTerminal(out io.Writer) *Terminal {
+	return &Terminal{
+		Color:  true,
+		Zoom:   1,
+		Follow: nbody.NoBody,
+		Cols:   80,
+		Rows:   24,
+		out:    bufio.NewWriter(out),
+	}
+}
+
+func (t *Terminal) Render(f *Frame) error {
+	if !t.started {
+		t.out.WriteString("\x1b[?25l\x1b[2J") // hide the cursor, clear the screen
+		t.started = true
+	}
+	cols, rows, ok := terminalSize()
+	if !ok {
+		cols, rows = t.Cols, t.Rows
+	}
+	rows = max(rows-1, 1) // leave the last row for the status line
+	t.canvas.charset = t.Charset
+	if cols != t.canvas.cols || rows != t.canvas.rows {
+		t.canvas.resize(cols, rows)
+		t.out.WriteString("\x1b[2J")
+	}
+	t.canvas.clear()
+
+	center := f.Center
+	if b, ok := nbody.FindBody(f.Bodies, t.Follow); ok {
+		center = b.Position
+	}
+	// Dots per world unit, fitting the world to the canvas.
+	scale := math.Min(float64(t.canvas.w)/f.Width, float64(t.canvas.h)/f.Height) * t.Zoom
+	for _, b := range f.Bodies {
+		x := float64(t.canvas.w)/2 + (b.Position.X-center.X)*scale
+		y := float64(t.canvas.h)/2 + (b.Position.Y-center.Y)*scale
+		t.canvas.disc(x, y, b.Radius*scale, color.RGBAModel.Convert(b.Color).(color.RGBA))
+	}
+	t.canvas.render(t.out, t.Color)
+
+	status := fmt.Sprintf("%s  t = %.1fs  %d bodies  (Ctrl+C quits)", f.Title, f.Time, len(f.Bodies))
+	if len(status) > cols {
+		status = status[:cols]
+	}
+	t.out.WriteString("\x1b[2K" + status)
+	return t.out.Flush()
+}
+
+// Close restores the terminal's colors and cursor.
+func (t *Terminal) Close() error {
+	if !t.started {
+		return nil
+	}
+	t.out.WriteString("\x1b[0m\x1b[?25h\r\n")
+	return t.out.Flush()
+}
@@ -0,0 +1,111 @@
+package render
+
+import (
+	"bufio"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestParseCharset(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Charset
+		wantErr bool
+	}{
+		{"braille", Braille, false},
+		{"blocks", Blocks, false},
+		{"ascii", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCharset(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseCharset(%q) = %v, %v; want %v, error %t", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// countDots returns the number of dots set on cv.
+func countDots(cv *canvas) int {
+	n := 0
+	for _, d := range cv.dots {
+		if d {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCanvasDisc(t *testing.T) {
+	var cv canvas
+	cv.resize(4, 2)
+	c := color.RGBA{R: 0xff, A: 0xff}
+	cv.disc(2.2, 3.7, 0.3, c)
+	if n := countDots(&cv); n != 1 || !cv.dots[3*cv.w+2] {
+		t.Errorf("disc smaller than a dot set %d dots, want only (2, 3)", n)
+	}
+	cv.clear()
+	cv.disc(2, 2, 1, c)
+	if n := countDots(&cv); n != 4 {
+		t.Errorf("disc of radius 1 set %d dots, want 4", n)
+	}
+	cv.clear()
+	cv.disc(-5, -5, 1, c) // off the canvas
+	if n := countDots(&cv); n != 0 {
+		t.Errorf("disc off the canvas set %d dots, want 0", n)
+	}
+}
+
+func TestCanvasRender(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	green := color.RGBA{G: 0xff, A: 0xff}
+	tests := []struct {
+		name    string
+		charset Charset
+		dots    [][2]int // set red, then the last one green
+		colored bool
+		want    string
+	}{
+		{
+			name:    "braille",
+			charset: Braille,
+			dots:    [][2]int{{0, 0}, {3, 3}, {1, 4}},
+			want:    "\x1b[H⠁⢀ \r\n⠈  \r\n",
+		},
+		{
+			name:    "blocks",
+			charset: Blocks,
+			dots:    [][2]int{{0, 0}, {1, 0}, {1, 1}, {2, 3}},
+			want:    "\x1b[H▀█ \r\n  ▄\r\n",
+		},
+		{
+			name:    "colored",
+			charset: Blocks,
+			dots:    [][2]int{{0, 0}, {1, 1}, {2, 0}},
+			colored: true,
+			want:    "\x1b[H\x1b[38;2;255;0;0m▀▄\x1b[38;2;0;255;0m▀\r\n   \r\n\x1b[0m",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cv := canvas{charset: tt.charset}
+			cv.resize(3, 2)
+			for i, d := range tt.dots {
+				c := red
+				if i == len(tt.dots)-1 {
+					c = green
+				}
+				cv.set(d[0], d[1], c)
+			}
+			cv.set(-1, 0, red) // off the canvas
+			cv.set(0, cv.h, red)
+			var sb strings.Builder
+			w := bufio.NewWriter(&sb)
+			cv.render(w, tt.colored)
+			w.Flush()
+			if got := sb.String(); got != tt.want {
+				t.Errorf("render = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// timeline is a scrubber over the rewind buffer, shown along the bottom of
+// the screen while paused: clicking or dragging along it jumps back to the
+// snapshot under the cursor, and the simulation resumes from there when
+// unpaused. Snapshots are spaced evenly along it, oldest on the left.
+type timeline struct {
+	history  *nbody.History // nil without a rewind buffer
+	current  int            // index of the snapshot jumped to, or -1 for the present
+	dragging bool
+}
+
+// maxRewindSnapshots caps the rewind buffer however small the snapshots,
+// well beyond the resolution of the timeline.
+const maxRewindSnapshots = 1 << 14
+
+// newRewindHistory returns a rewind buffer of snapshots of sim, one every
+// every steps, that keeps within budget bytes as sim grows or shrinks, or
+// nil if fewer than two of its snapshots fit.
+func newRewindHistory(sim *nbody.Simulation, budget, every int) *nbody.History {
+	if budget/sim.SnapshotSize() < 2 {
+		return nil
+	}
+	h := nbody.NewHistory(maxRewindSnapshots, every)
+	h.Budget = budget
+	return h
+}
+
+// bounds returns the timeline's rectangle on a width × height screen.
+func (tl *timeline) bounds(width, height int) image.Rectangle {
+	margin, h := int(16*uiScale), int(8*uiScale)
+	return image.Rect(margin, height-margin-h, width-margin, height-margin)
+}
+
+// index returns the snapshot at screen x on a timeline in r.
+func (tl *timeline) index(r image.Rectangle, x int) int {
+	n := tl.history.Len()
+	i := int(float64(x-r.Min.X)/float64(max(r.Dx(), 1))*float64(n-1) + 0.5)
+	return min(max(i, 0), n-1)
+}
+
+// updateTimeline jumps to the snapshot under the cursor while the timeline
+// is clicked or dragged, and reports whether it took the mouse.
+func (g *Game) updateTimeline() bool {
+	tl := &g.timeline
+	if tl.history == nil || !g.paused || tl.history.Len() == 0 {
+		tl.dragging = false
+		return false
+	}
+	r := tl.bounds(g.width, g.height)
+	cursor := image.Pt(ebiten.CursorPosition())
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && cursor.In(r.Inset(-int(4*uiScale))) {
+		tl.dragging = true
+	}
+	if !tl.dragging {
+		return false
+	}
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		tl.dragging = false
+		return true
+	}
+	if i := tl.index(r, cursor.X); i != tl.current {
+		g.rewindTo(i)
+	}
+	return true
+}
+
+// rewindTo restores the i-th oldest snapshot of the rewind buffer. The
+// newer ones stay until the simulation resumes, so the scrubber can go
+// forward again.
+func (g *Game) rewindTo(i int) {
+	g.sim.Restore(g.timeline.history.At(i))
+	g.timeline.current = i
+	g.fault = nil
+	for i := range g.viewports {
+		g.viewports[i].trails.reset()
+	}
+	g.inset.trails.reset()
+	g.resync()
+}
+
+// resumeTimeline drops the snapshots after the one jumped to, once the
+// simulation carries on from it.
+func (g *Game) resumeTimeline() {
+	tl := &g.timeline
+	if tl.current < 0 {
+		return
+	}
+	tl.history.Rewind(g.sim, tl.current)
+	tl.current = -1
+	// The fault history may hold states from the discarded future.
+	g.history.Clear()
+	log.Printf("resumed from t = %s", formatDuration(g.sim.Time))
+}
+
+// drawTimeline draws the scrubber while paused.
+func (g *Game) drawTimeline(screen *ebiten.Image) {
+	tl := &g.timeline
+	if tl.history == nil || !g.paused || tl.history.Len() == 0 {
+		return
+	}
+	r := tl.bounds(g.width, g.height)
+	n := tl.history.Len()
+	vector.DrawFilledRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), theme.Panel, false)
+	vector.StrokeRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), 1, theme.Border, false)
+	i := tl.current
+	if i < 0 {
+		i = n - 1
+	}
+	x := float32(r.Min.X)
+	if n > 1 {
+		x += float32(r.Dx()) * float32(i) / float32(n-1)
+	}
+	vector.StrokeLine(screen, x, float32(r.Min.Y)-2*float32(uiScale), x, float32(r.Max.Y)+2*float32(uiScale), 2*float32(uiScale), theme.Selection, false)
+	oldest, newest := tl.history.At(0).Time(), tl.history.At(n-1).Time()
+	label := fmt.Sprintf("rewind: t = %s  (%s to %s, drag to scrub)", formatDuration(tl.history.At(i).Time()), formatDuration(oldest), formatDuration(newest))
+	drawText(screen, label, r.Min.X, r.Min.Y-debugLineHeight-2)
+}
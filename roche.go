@@ -0,0 +1,50 @@
+package main
+
+import (
+	"image/color"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// drawRocheLimits outlines the rigid and fluid Roche limits of the
+// selected body in vp, for satellites as dense on average as those bound
+// to it, or as dense as the body itself if none has a known density. It
+// draws nothing for a body whose physical radius is unknown. center is
+// the camera center.
+func (g *Game) drawRocheLimits(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	p := slices.IndexFunc(g.drawBodies, func(b nbody.Body) bool { return b.ID == g.selected })
+	if p < 0 {
+		return
+	}
+	primary := g.drawBodies[p]
+	density, n := 0.0, 0
+	for i, q := range g.subsystems.primaries {
+		if d := g.drawBodies[i].Density(); q == p && d > 0 {
+			density += d
+			n++
+		}
+	}
+	if n > 0 {
+		density /= float64(n)
+	} else {
+		density = primary.Density()
+	}
+	if !(density > 0) || !(primary.PhysicalRadius > 0) {
+		return
+	}
+	rigid, fluid := nbody.RocheLimits(primary, density)
+	s := vp.toScreen(g.view(g.framePositions[p]), center)
+	for _, limit := range []struct {
+		r     float64
+		c     color.Color
+		label string
+	}{{rigid, theme.RocheRigid, "rigid Roche"}, {fluid, theme.RocheFluid, "fluid Roche"}} {
+		r := limit.r * vp.Camera.Zoom
+		vector.StrokeCircle(dst, float32(s.X), float32(s.Y), float32(r), 1, limit.c, true)
+		drawText(dst, limit.label, int(s.X)-len(limit.label)*debugCharWidth/2, int(s.Y-r)-debugLineHeight)
+	}
+}
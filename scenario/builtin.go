@@ -0,0 +1,135 @@
+package scenario
+
+import (
+	"slices"
+
+	"n-body/nbody"
+)
+
+// builtins are the scenarios compiled into the program, by name.
+var builtins = map[string]func() *Scenario{
+	"solar-system": SolarSystem,
+}
+
+// Builtin returns a fresh copy of the named built-in scenario.
+func Builtin(name string) (*Scenario, bool) {
+	f, ok := builtins[name]
+	if !ok {
+		return nil, false
+	}
+	return f(), true
+}
+
+// BuiltinNames lists the built-in scenarios in alphabetical order.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SolarSystem is the Sun with Venus, Earth and its Moon, Mars and Jupiter,
+// at real masses and distances scaled by nbody.OrbitScale.
+func SolarSystem() *Scenario {
+	const (
+		width  = 1000
+		height = 800
+	)
+	sc := &Scenario{
+		Name:        "solar-system",
+		Description: "The Sun, Venus, Earth and the Moon, Mars and Jupiter.",
+		Width:       width,
+		Height:      height,
+	}
+
+	sun := BodySpec{
+		Name:           "Sun",
+		Position:       nbody.Vector2D{X: width / 2, Y: height / 2},
+		Velocity:       nbody.Vector2D{X: 0, Y: 0},
+		Mass:           1.989e30, // Mass of the Sun in kg
+		Radius:         20,
+		PhysicalRadius: 6.957e8, // 695,700 km
+		Color:          Color{255, 255, 0, 255},
+		Luminous:       true,
+	}
+	sc.Bodies = append(sc.Bodies, sun)
+
+	// Venus
+	venusOrbitRadius := 108.2e9 * nbody.OrbitScale               // 108.2 million km
+	venusSpeed := 35.02e3 * nbody.SpeedScale * nbody.ScaleFactor // 35.02 km/s
+	venus := BodySpec{
+		Name:           "Venus",
+		Position:       nbody.Vector2D{X: width/2 + venusOrbitRadius, Y: height / 2},
+		Velocity:       nbody.Vector2D{X: 0, Y: -venusSpeed},
+		Mass:           4.867e24, // Mass of Venus in kg
+		Radius:         4,
+		PhysicalRadius: 6.0518e6,                 // 6,051.8 km
+		Color:          Color{255, 198, 73, 255}, // Light orange
+		Tags:           []string{"planets"},
+	}
+	sc.Bodies = append(sc.Bodies, venus)
+
+	// Earth
+	earthOrbitRadius := 149.6e9 * nbody.OrbitScale               // 149.6 million km
+	earthSpeed := 29.78e3 * nbody.SpeedScale * nbody.ScaleFactor // 29.78 km/s
+	earth := BodySpec{
+		Name:           "Earth",
+		Position:       nbody.Vector2D{X: width/2 + earthOrbitRadius, Y: height / 2},
+		Velocity:       nbody.Vector2D{X: 0, Y: -earthSpeed},
+		Mass:           5.972e24, // Mass of the Earth in kg
+		Radius:         5,
+		PhysicalRadius: 6.371e6, // 6,371 km
+		Color:          Color{0, 0, 255, 255},
+		Tags:           []string{"planets"},
+	}
+	sc.Bodies = append(sc.Bodies, earth)
+
+	// Earth's Moon
+	moonOrbitRadius := 384400e3 * nbody.OrbitScale                                                                // 384,400 km
+	moonSpeed := (1.022e3 + earthSpeed/nbody.ScaleFactor/nbody.SpeedScale) * nbody.SpeedScale * nbody.ScaleFactor // 1.022 km/s + Earth's speed
+	moon := BodySpec{
+		Name:           "Moon",
+		Position:       nbody.Vector2D{X: earth.Position.X + moonOrbitRadius, Y: earth.Position.Y},
+		Velocity:       nbody.Vector2D{X: 0, Y: -moonSpeed},
+		Mass:           7.34767309e22, // Mass of the Moon in kg
+		Radius:         2,
+		PhysicalRadius: 1.7374e6,                  // 1,737.4 km
+		Color:          Color{200, 200, 200, 255}, // Light grey
+		Tags:           []string{"moons"},
+	}
+	sc.Bodies = append(sc.Bodies, moon)
+
+	// Mars
+	marsOrbitRadius := 227.9e9 * nbody.OrbitScale                // 227.9 million km
+	marsSpeed := 24.077e3 * nbody.SpeedScale * nbody.ScaleFactor // 24.077 km/s
+	mars := BodySpec{
+		Name:           "Mars",
+		Position:       nbody.Vector2D{X: width/2 + marsOrbitRadius, Y: height / 2},
+		Velocity:       nbody.Vector2D{X: 0, Y: -marsSpeed},
+		Mass:           6.39e23, // Mass of Mars in kg
+		Radius:         4,
+		PhysicalRadius: 3.3895e6, // 3,389.5 km
+		Color:          Color{255, 0, 0, 255},
+		Tags:           []string{"planets"},
+	}
+	sc.Bodies = append(sc.Bodies, mars)
+
+	// Jupiter
+	jupiterOrbitRadius := 778.5e9 * nbody.OrbitScale               // 778.5 million km
+	jupiterSpeed := 13.07e3 * nbody.SpeedScale * nbody.ScaleFactor // 13.07 km/s
+	jupiter := BodySpec{
+		Name:           "Jupiter",
+		Position:       nbody.Vector2D{X: width/2 + jupiterOrbitRadius, Y: height / 2},
+		Velocity:       nbody.Vector2D{X: 0, Y: -jupiterSpeed},
+		Mass:           1.898e27, // Mass of Jupiter in kg
+		Radius:         15,
+		PhysicalRadius: 6.9911e7,                // 69,911 km
+		Color:          Color{255, 140, 0, 255}, // Dark orange
+		Tags:           []string{"planets"},
+	}
+	sc.Bodies = append(sc.Bodies, jupiter)
+
+	return sc
+}
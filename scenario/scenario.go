@@ -0,0 +1,236 @@
+// Package scenario describes initial configurations for the simulation and
+// loads them from JSON files.
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"n-body/nbody"
+)
+
+// Scenario is a complete initial configuration: the world, its bodies and
+// the settings to simulate them with.
+type Scenario struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Epoch       string         `json:"epoch,omitempty"` // calendar time of the start; see ParseEpoch
+	Width       float64        `json:"width"`
+	Height      float64        `json:"height"`
+	Bodies      []BodySpec     `json:"bodies"`
+	Springs     []nbody.Spring `json:"springs,omitempty"` // A and B index Bodies
+	Tethers     []nbody.Tether `json:"tethers,omitempty"` // A and B index Bodies
+	Settings    Settings       `json:"settings"`
+	Annotations []Annotation   `json:"annotations,omitempty"`
+}
+
+// Annotation is a caption shown while the simulated time is in [Start, End).
+type Annotation struct {
+	Start float64 `json:"start"` // simulated seconds
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// BodySpec is the initial state of one body.
+type BodySpec struct {
+	Name           string         `json:"name,omitempty"`
+	Position       nbody.Vector2D `json:"position"`
+	Velocity       nbody.Vector2D `json:"velocity"`
+	Mass           float64        `json:"mass"`
+	Charge         float64        `json:"charge,omitempty"`
+	Radius         float64        `json:"radius"`
+	PhysicalRadius float64        `json:"physicalRadius,omitempty"` // true radius in meters, for densities; zero if unknown
+	Color          Color          `json:"color"`
+	Texture        string         `json:"texture,omitempty"` // image file, relative to the scenario file
+	Luminous       bool           `json:"luminous,omitempty"`
+	Comet          bool           `json:"comet,omitempty"` // drawn with a tail
+	Tags           []string       `json:"tags,omitempty"`  // groups that can be hidden together
+}
+
+// UnmarshalJSON decodes a body, which is opaque white if it has no color.
+func (b *BodySpec) UnmarshalJSON(data []byte) error {
+	type plain BodySpec // without this method
+	spec := plain{Color: Color{R: 255, G: 255, B: 255, A: 255}}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return err
+	}
+	*b = BodySpec(spec)
+	return nil
+}
+
+// Settings are the physics options a scenario is meant to run with.
+type Settings struct {
+	MOND       bool    `json:"mond,omitempty"`
+	MONDA0     float64 `json:"mondA0,omitempty"` // in m/s²; defaults to nbody.DefaultMONDA0
+	Collisions bool    `json:"collisions,omitempty"`
+	Dt         float64 `json:"dt,omitempty"` // seconds per step; defaults to nbody.TimeStep
+}
+
+// Color is an RGBA color written as "#rrggbb", which is opaque, or
+// "#rrggbbaa" in JSON. Bodies without one are opaque white.
+type Color color.RGBA
+
+// RGBA makes Color a color.Color.
+func (c Color) RGBA() (r, g, b, a uint32) {
+	return color.RGBA(c).RGBA()
+}
+
+func (c Color) MarshalJSON() ([]byte, error) {
+	if c.A == 255 {
+		return json.Marshal(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+	}
+	return json.Marshal(fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A))
+}
+
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	c.A = 255
+	var err error
+	switch len(s) {
+	case 7:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 9:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = fmt.Errorf("want #rrggbb or #rrggbbaa")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid color %q: %w", s, err)
+	}
+	return nil
+}
+
+// J2000 is the standard astronomical epoch, noon Terrestrial Time on 1
+// January 2000, in UTC.
+var J2000 = time.Date(2000, time.January, 1, 11, 58, 55, 816e6, time.UTC)
+
+// ParseEpoch parses the calendar time a scenario starts at: "J2000", an
+// RFC 3339 time such as "2024-03-20T03:06:00Z", or a date such as
+// "2024-03-20", taken as midnight UTC.
+func ParseEpoch(s string) (time.Time, error) {
+	if s == "J2000" || s == "J2000.0" {
+		return J2000, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid epoch %q: want J2000, an RFC 3339 time or a date", s)
+}
+
+// Load reads a scenario from a JSON file. Texture paths are resolved
+// relative to the file.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sc Scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("parsing scenario %s: %w", path, err)
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	for i, b := range sc.Bodies {
+		if b.Texture != "" && !filepath.IsAbs(b.Texture) {
+			sc.Bodies[i].Texture = filepath.Join(filepath.Dir(path), b.Texture)
+		}
+	}
+	return &sc, nil
+}
+
+// Validate checks that the scenario can be built.
+func (sc *Scenario) Validate() error {
+	if sc.Width <= 0 || sc.Height <= 0 {
+		return fmt.Errorf("world size %gx%g is not positive", sc.Width, sc.Height)
+	}
+	if sc.Epoch != "" {
+		if _, err := ParseEpoch(sc.Epoch); err != nil {
+			return err
+		}
+	}
+	if sc.Settings.Dt < 0 {
+		return fmt.Errorf("time step %g is negative", sc.Settings.Dt)
+	}
+	for i, b := range sc.Bodies {
+		if b.Mass <= 0 {
+			return fmt.Errorf("body %d has non-positive mass %g", i, b.Mass)
+		}
+		if b.PhysicalRadius < 0 {
+			return fmt.Errorf("body %d has negative physical radius %g", i, b.PhysicalRadius)
+		}
+		for _, tag := range b.Tags {
+			if tag == "" || strings.ContainsFunc(tag, unicode.IsSpace) {
+				return fmt.Errorf("body %d has tag %q, which is empty or contains spaces", i, tag)
+			}
+		}
+	}
+	n := len(sc.Bodies)
+	for _, s := range sc.Springs {
+		if s.A < 0 || s.A >= n || s.B < 0 || s.B >= n {
+			return fmt.Errorf("spring %d-%d references a missing body", s.A, s.B)
+		}
+	}
+	for _, t := range sc.Tethers {
+		if t.A < 0 || t.A >= n || t.B < 0 || t.B >= n {
+			return fmt.Errorf("tether %d-%d references a missing body", t.A, t.B)
+		}
+	}
+	for _, a := range sc.Annotations {
+		if !(a.End > a.Start) {
+			return fmt.Errorf("annotation %q ends at %g, not after its start %g", a.Text, a.End, a.Start)
+		}
+	}
+	return nil
+}
+
+// Build creates a simulation in the scenario's initial state. The i-th body
+// spec becomes the body with ID i.
+func (sc *Scenario) Build() *nbody.Simulation {
+	sim := nbody.NewSimulation(sc.Width, sc.Height)
+	ids := make([]int, len(sc.Bodies))
+	for i, b := range sc.Bodies {
+		ids[i] = sim.AddBody(nbody.Body{
+			Name:           b.Name,
+			Position:       b.Position,
+			Velocity:       b.Velocity,
+			Mass:           b.Mass,
+			Charge:         b.Charge,
+			Radius:         b.Radius,
+			PhysicalRadius: b.PhysicalRadius,
+			Color:          color.RGBA(b.Color),
+			Texture:        b.Texture,
+			Luminous:       b.Luminous,
+			Comet:          b.Comet,
+			Tags:           strings.Join(b.Tags, " "),
+		})
+	}
+	for _, s := range sc.Springs {
+		s.A, s.B = ids[s.A], ids[s.B]
+		sim.AddConstraint(s)
+	}
+	for _, t := range sc.Tethers {
+		t.A, t.B = ids[t.A], ids[t.B]
+		sim.AddConstraint(t)
+	}
+
+	sim.MOND = nbody.MOND{Enabled: sc.Settings.MOND, A0: sc.Settings.MONDA0}
+	if sim.MOND.A0 == 0 {
+		sim.MOND.A0 = nbody.DefaultMONDA0
+	}
+	sim.Collisions = sc.Settings.Collisions
+	sim.Dt = sc.Settings.Dt
+	return sim
+}
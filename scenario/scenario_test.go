@@ -0,0 +1,209 @@
+package scenario
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"n-body/nbody"
+)
+
+// twoBodies returns a small valid scenario using every field.
+func twoBodies() *Scenario {
+	return &Scenario{
+		Name:        "pair",
+		Description: "two bodies on a spring and a tether",
+		Epoch:       "2024-03-20",
+		Width:       1000,
+		Height:      800,
+		Bodies: []BodySpec{
+			{Name: "A", Position: nbody.Vector2D{X: 400, Y: 400}, Velocity: nbody.Vector2D{Y: 1}, Mass: 1e24, Charge: 2, Radius: 5,
+				Color: Color{R: 255, G: 128, B: 0, A: 255}, Luminous: true, Tags: []string{"stars"}},
+			{Name: "B", Position: nbody.Vector2D{X: 600, Y: 400}, Mass: 1e22, Radius: 2,
+				Color: Color{R: 10, G: 20, B: 30, A: 128}, Texture: "b.png", Comet: true, Tags: []string{"moons", "tracers"}},
+		},
+		Springs:     []nbody.Spring{{A: 0, B: 1, RestLength: 200, Stiffness: 3, Damping: 0.5}},
+		Tethers:     []nbody.Tether{{A: 1, B: 0, MaxLength: 250}},
+		Settings:    Settings{MOND: true, MONDA0: 1e-10, Collisions: true, Dt: 0.5},
+		Annotations: []Annotation{{Start: 0, End: 10, Text: "hello"}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Scenario)
+		err    string // substring of the error, or "" for none
+	}{
+		{"valid", func(*Scenario) {}, ""},
+		{"J2000 epoch", func(sc *Scenario) { sc.Epoch = "J2000" }, ""},
+		{"RFC 3339 epoch", func(sc *Scenario) { sc.Epoch = "2024-03-20T03:06:00Z" }, ""},
+		{"no bodies", func(sc *Scenario) { sc.Bodies, sc.Springs, sc.Tethers = nil, nil, nil }, ""},
+		{"zero width", func(sc *Scenario) { sc.Width = 0 }, "world size"},
+		{"negative height", func(sc *Scenario) { sc.Height = -1 }, "world size"},
+		{"bad epoch", func(sc *Scenario) { sc.Epoch = "yesterday" }, "invalid epoch"},
+		{"negative dt", func(sc *Scenario) { sc.Settings.Dt = -1 }, "time step"},
+		{"zero mass", func(sc *Scenario) { sc.Bodies[1].Mass = 0 }, "body 1 has non-positive mass"},
+		{"negative physical radius", func(sc *Scenario) { sc.Bodies[0].PhysicalRadius = -1 }, "body 0 has negative physical radius"},
+		{"empty tag", func(sc *Scenario) { sc.Bodies[0].Tags = []string{""} }, "tag"},
+		{"tag with space", func(sc *Scenario) { sc.Bodies[0].Tags = []string{"red dwarfs"} }, "tag"},
+		{"spring to missing body", func(sc *Scenario) { sc.Springs[0].B = 2 }, "spring 0-2"},
+		{"tether from negative body", func(sc *Scenario) { sc.Tethers[0].A = -1 }, "tether -1-0"},
+		{"annotation ending at its start", func(sc *Scenario) { sc.Annotations[0].End = 0 }, "annotation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := twoBodies()
+			tt.change(sc)
+			err := sc.Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tt.err != "" && err == nil:
+				t.Errorf("Validate() = nil, want an error containing %q", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	scenarios := []*Scenario{twoBodies()}
+	for _, name := range BuiltinNames() {
+		sc, _ := Builtin(name)
+		scenarios = append(scenarios, sc)
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.Name, func(t *testing.T) {
+			if err := sc.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(sc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got Scenario
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(&got, sc) {
+				t.Errorf("round trip through %s gave %+v, want %+v", data, got, *sc)
+			}
+		})
+	}
+}
+
+func TestJSONKeys(t *testing.T) {
+	data, err := json.Marshal(twoBodies())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{
+		`"springs":[{"a":0,"b":1,"restLength":200,"stiffness":3,"damping":0.5}]`,
+		`"tethers":[{"a":1,"b":0,"maxLength":250}]`,
+		`"color":"#ff8000"`,
+		`"color":"#0a141e80"`,
+	} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("%s does not contain %s", data, key)
+		}
+	}
+}
+
+func TestColorJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Color
+		ok   bool
+	}{
+		{`"#ff8000"`, Color{R: 255, G: 128, B: 0, A: 255}, true},
+		{`"#0a141e80"`, Color{R: 10, G: 20, B: 30, A: 128}, true},
+		{`"#fff"`, Color{}, false},
+		{`"ff8000"`, Color{}, false},
+		{`"#gg8000"`, Color{}, false},
+		{`255`, Color{}, false},
+	}
+	for _, tt := range tests {
+		var c Color
+		err := json.Unmarshal([]byte(tt.in), &c)
+		if (err == nil) != tt.ok {
+			t.Errorf("unmarshaling %s: error %v, want ok = %t", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && c != tt.want {
+			t.Errorf("unmarshaling %s = %+v, want %+v", tt.in, c, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	data, err := json.Marshal(twoBodies())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "pair.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "b.png"); sc.Bodies[1].Texture != want {
+		t.Errorf("texture = %q, want %q, relative to the file", sc.Bodies[1].Texture, want)
+	}
+
+	bad := twoBodies()
+	bad.Bodies[0].Mass = -1
+	data, _ = json.Marshal(bad)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("loaded a scenario with a negative mass")
+	}
+	uncolored := `{"name": "plain", "width": 10, "height": 10, "bodies": [{"name": "a", "mass": 1, "radius": 1}]}`
+	if err := os.WriteFile(path, []byte(uncolored), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if sc, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Color{R: 255, G: 255, B: 255, A: 255}); sc.Bodies[0].Color != want {
+		t.Errorf("body without a color = %+v, want opaque white %+v", sc.Bodies[0].Color, want)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("loaded a truncated file")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	sc := twoBodies()
+	sim := sc.Build()
+	if sim.Len() != len(sc.Bodies) {
+		t.Fatalf("built %d bodies, want %d", sim.Len(), len(sc.Bodies))
+	}
+	for i, spec := range sc.Bodies {
+		b := sim.Body(i)
+		if b.ID != i || b.Name != spec.Name || b.Mass != spec.Mass || b.Charge != spec.Charge || b.Tags != strings.Join(spec.Tags, " ") {
+			t.Errorf("body %d is %+v, built from %+v", i, b, spec)
+		}
+	}
+	if sim.Dt != sc.Settings.Dt || !sim.MOND.Enabled || sim.MOND.A0 != sc.Settings.MONDA0 || !sim.Collisions {
+		t.Errorf("settings %+v built into Dt %g, MOND %+v, collisions %t", sc.Settings, sim.Dt, sim.MOND, sim.Collisions)
+	}
+
+	sc.Settings.MONDA0 = 0
+	if a0 := sc.Build().MOND.A0; a0 != nbody.DefaultMONDA0 {
+		t.Errorf("a0 defaults to %g, want %g", a0, nbody.DefaultMONDA0)
+	}
+}
@@ -0,0 +1,181 @@
+// Package scenariotest runs scenarios headlessly from Go tests and asserts
+// on their final state, so a scenario can ship with regression checks:
+//
+//	func TestBinaryStaysBound(t *testing.T) {
+//		run := scenariotest.Load(t, "testdata/binary.json")
+//		run.Step(10000)
+//		run.AssertBodyCount(2)
+//		run.AssertFinite()
+//		run.AssertEnergyDrift(1e-3)
+//		run.AssertWithin(1, run.Body(0).Position, 50)
+//	}
+//
+// Assertions report through t.Errorf and keep going; lookups that cannot
+// continue, such as a missing body, stop the test with t.Fatalf.
+package scenariotest
+
+import (
+	"math"
+	"testing"
+
+	"n-body/nbody"
+	"n-body/scenario"
+)
+
+// Run is a scenario being simulated inside a test.
+type Run struct {
+	Scenario *scenario.Scenario
+	Sim      *nbody.Simulation
+	Initial  nbody.Diagnostics // diagnostics before the first step
+	Steps    int               // steps taken so far
+
+	t testing.TB
+}
+
+// Start builds sc and records its initial diagnostics.
+func Start(t testing.TB, sc *scenario.Scenario) *Run {
+	t.Helper()
+	if err := sc.Validate(); err != nil {
+		t.Fatalf("scenario %s: %v", sc.Name, err)
+	}
+	sim := sc.Build()
+	return &Run{Scenario: sc, Sim: sim, Initial: sim.Diagnostics(), t: t}
+}
+
+// Load reads a scenario file and starts it.
+func Load(t testing.TB, path string) *Run {
+	t.Helper()
+	sc, err := scenario.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Start(t, sc)
+}
+
+// Builtin starts the named built-in scenario.
+func Builtin(t testing.TB, name string) *Run {
+	t.Helper()
+	sc, ok := scenario.Builtin(name)
+	if !ok {
+		t.Fatalf("no built-in scenario %q", name)
+	}
+	return Start(t, sc)
+}
+
+// Step advances the simulation by n time steps.
+func (r *Run) Step(n int) *Run {
+	for range n {
+		r.Sim.Update()
+	}
+	r.Steps += n
+	return r
+}
+
+// For advances the simulation by at least d seconds of simulated time.
+func (r *Run) For(d float64) *Run {
+	return r.Step(int(math.Ceil(d / r.Sim.StepSize())))
+}
+
+// Body returns the body with the given ID. A freshly built scenario gives the
+// i-th body spec ID i; merged bodies keep the ID of the heavier one.
+func (r *Run) Body(id int) nbody.Body {
+	r.t.Helper()
+	return r.Sim.Body(r.index(id))
+}
+
+// index returns the index of body id, stopping the test if it is gone.
+func (r *Run) index(id int) int {
+	r.t.Helper()
+	i, ok := r.Sim.IndexOf(id)
+	if !ok {
+		r.t.Fatalf("after %d steps: no body with ID %d", r.Steps, id)
+	}
+	return i
+}
+
+// Diagnostics returns the simulation's current diagnostics.
+func (r *Run) Diagnostics() nbody.Diagnostics {
+	return r.Sim.Diagnostics()
+}
+
+// EnergyDrift returns the change in total energy since the start, relative
+// to the initial kinetic plus the magnitude of the initial potential energy.
+func (r *Run) EnergyDrift() float64 {
+	scale := r.Initial.Kinetic + math.Abs(r.Initial.Potential)
+	if scale == 0 {
+		return 0
+	}
+	return math.Abs(r.Diagnostics().Energy-r.Initial.Energy) / scale
+}
+
+// MomentumDrift returns the magnitude of the change in total momentum since
+// the start, relative to the initial sum of the bodies' momentum magnitudes.
+func (r *Run) MomentumDrift() float64 {
+	var scale float64
+	for _, b := range r.Scenario.Bodies {
+		scale += b.Mass * math.Hypot(b.Velocity.X, b.Velocity.Y)
+	}
+	if scale == 0 {
+		return 0
+	}
+	p := r.Diagnostics().Momentum
+	return math.Hypot(p.X-r.Initial.Momentum.X, p.Y-r.Initial.Momentum.Y) / scale
+}
+
+// AssertBodyCount checks that exactly n bodies remain.
+func (r *Run) AssertBodyCount(n int) {
+	r.t.Helper()
+	if got := r.Sim.Len(); got != n {
+		r.t.Errorf("after %d steps: %d bodies, want %d", r.Steps, got, n)
+	}
+}
+
+// AssertFinite checks that no position or velocity has become NaN or
+// infinite.
+func (r *Run) AssertFinite() {
+	r.t.Helper()
+	if err := r.Sim.CheckFinite(); err != nil {
+		r.t.Errorf("after %d steps: %v", r.Steps, err)
+	}
+}
+
+// AssertEnergyDrift checks that EnergyDrift is at most tol.
+func (r *Run) AssertEnergyDrift(tol float64) {
+	r.t.Helper()
+	if drift := r.EnergyDrift(); !(drift <= tol) {
+		r.t.Errorf("after %d steps: relative energy drift %.3g, want at most %.3g", r.Steps, drift, tol)
+	}
+}
+
+// AssertMomentumDrift checks that MomentumDrift is at most tol.
+func (r *Run) AssertMomentumDrift(tol float64) {
+	r.t.Helper()
+	if drift := r.MomentumDrift(); !(drift <= tol) {
+		r.t.Errorf("after %d steps: relative momentum drift %.3g, want at most %.3g", r.Steps, drift, tol)
+	}
+}
+
+// AssertWithin checks that body id lies within radius of center.
+func (r *Run) AssertWithin(id int, center nbody.Vector2D, radius float64) {
+	r.t.Helper()
+	p := r.Body(id).Position
+	if d := math.Hypot(p.X-center.X, p.Y-center.Y); !(d <= radius) {
+		r.t.Errorf("after %d steps: body %d at %v is %.3g from %v, want at most %.3g", r.Steps, id, p, d, center, radius)
+	}
+}
+
+// AssertBound checks that body id is gravitationally bound to the body that
+// dominates its motion.
+func (r *Run) AssertBound(id int) {
+	r.t.Helper()
+	i := r.index(id)
+	bodies := r.Sim.AppendBodies(nil)
+	primary, ok := nbody.DominantBody(bodies, i)
+	if !ok {
+		r.t.Errorf("after %d steps: body %d has no primary", r.Steps, id)
+		return
+	}
+	if el := nbody.OsculatingElements(bodies[i], bodies[primary]); !el.Bound {
+		r.t.Errorf("after %d steps: body %d is unbound from body %d (e = %.3g)", r.Steps, id, bodies[primary].ID, el.Eccentricity)
+	}
+}
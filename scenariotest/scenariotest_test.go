@@ -0,0 +1,95 @@
+package scenariotest
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"n-body/nbody"
+	"n-body/scenario"
+)
+
+func TestBinaryStaysBound(t *testing.T) {
+	run := Load(t, "testdata/binary.json")
+	run.Step(2500) // about one orbit
+	run.AssertBodyCount(2)
+	run.AssertFinite()
+	run.AssertEnergyDrift(1e-3)
+	run.AssertMomentumDrift(1e-6)
+	run.AssertBound(0)
+	run.AssertBound(1)
+	run.AssertWithin(0, nbody.Vector2D{X: 500, Y: 500}, 110)
+}
+
+func TestBuiltinsRun(t *testing.T) {
+	for _, name := range scenario.BuiltinNames() {
+		t.Run(name, func(t *testing.T) {
+			run := Builtin(t, name)
+			run.Step(600)
+			run.AssertBodyCount(len(run.Scenario.Bodies))
+			run.AssertFinite()
+			run.AssertEnergyDrift(1e-2)
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	run := Load(t, "testdata/binary.json")
+	if run.For(10.5); run.Steps != 11 {
+		t.Errorf("For(10.5) with steps of 1s took %d steps, want 11", run.Steps)
+	}
+}
+
+// recorder is a testing.TB that records failures instead of failing.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertionsFail(t *testing.T) {
+	tests := []struct {
+		name   string
+		assert func(*Run)
+	}{
+		{"body count", func(r *Run) { r.AssertBodyCount(3) }},
+		{"energy drift", func(r *Run) {
+			r.Sim.SetBody(0, withVelocity(r.Sim.Body(0), nbody.Vector2D{X: 5}))
+			r.AssertEnergyDrift(1e-3)
+		}},
+		{"momentum drift", func(r *Run) {
+			r.Sim.SetBody(0, withVelocity(r.Sim.Body(0), nbody.Vector2D{X: 5}))
+			r.AssertMomentumDrift(1e-3)
+		}},
+		{"finite", func(r *Run) {
+			r.Sim.SetBody(1, withVelocity(r.Sim.Body(1), nbody.Vector2D{X: math.NaN()}))
+			r.AssertFinite()
+		}},
+		{"within", func(r *Run) { r.AssertWithin(0, nbody.Vector2D{}, 10) }},
+		{"bound", func(r *Run) {
+			r.Sim.SetBody(0, withVelocity(r.Sim.Body(0), nbody.Vector2D{Y: -1e5}))
+			r.AssertBound(0)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{TB: t}
+			run := Load(t, "testdata/binary.json")
+			run.t = rec
+			tt.assert(run)
+			if len(rec.errors) != 1 {
+				t.Errorf("got errors %q, want one", rec.errors)
+			}
+		})
+	}
+}
+
+func withVelocity(b nbody.Body, v nbody.Vector2D) nbody.Body {
+	b.Velocity = v
+	return b
+}
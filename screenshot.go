@@ -0,0 +1,90 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/render"
+)
+
+// Size of the screenshots taken without a window.
+const (
+	headlessShotWidth  = 1000
+	headlessShotHeight = 800
+)
+
+// screenshots saves frames as PNGs named after the wall-clock time they
+// were taken, into dir: the next frame drawn after F12 is pressed, and one
+// every so much simulated time if every is set. Without a window, frames
+// are rasterized from the bodies alone.
+type screenshots struct {
+	dir     string
+	every   float64 // simulated seconds between screenshots, or 0
+	next    float64 // simulated time the next periodic screenshot is due
+	pending bool    // F12 was pressed
+}
+
+// due reports whether a screenshot should be taken of the frame at
+// simulated time t, and schedules the next periodic one.
+func (s *screenshots) due(t float64) bool {
+	shot := s.pending
+	s.pending = false
+	if s.every > 0 && t >= s.next {
+		shot = true
+		s.next = t + s.every
+	}
+	return shot
+}
+
+// capture saves screen, drawn at simulated time t, if a screenshot is due.
+func (s *screenshots) capture(screen *ebiten.Image, t float64) {
+	if !s.due(t) {
+		return
+	}
+	img := image.NewRGBA(screen.Bounds())
+	screen.ReadPixels(img.Pix)
+	go s.save(img)
+}
+
+// captureFrame saves f, rasterized, if a screenshot is due.
+func (s *screenshots) captureFrame(f *render.Frame) {
+	if !s.due(f.Time) {
+		return
+	}
+	img := image.NewRGBA(image.Rect(0, 0, headlessShotWidth, headlessShotHeight))
+	render.Rasterize(img, f)
+	s.save(img)
+}
+
+// save writes img into the directory, logging where or why not.
+func (s *screenshots) save(img *image.RGBA) {
+	path := filepath.Join(s.dir, "screenshot-"+time.Now().Format("20060102-150405.000")+".png")
+	err := os.MkdirAll(s.dir, 0o755)
+	if err == nil {
+		err = writePNG(path, img)
+	}
+	if err != nil {
+		log.Printf("screenshot: %v", err)
+		return
+	}
+	log.Printf("saved %s", path)
+}
+
+// writePNG encodes img as a PNG file at path.
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, img)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
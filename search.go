@@ -0,0 +1,133 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+const (
+	// searchResults bounds the matches listed.
+	searchResults = 10
+	// searchBodySize is the radius, in pixels before scaling, a body found
+	// is zoomed to.
+	searchBodySize = 24
+)
+
+// search finds bodies by name as it is typed, after /, listing those whose
+// names contain it, those starting with it first. Up and Down pick one and
+// Enter jumps to it: selects it and has the camera follow it, zoomed in.
+type search struct {
+	active  bool
+	query   []rune
+	matches []nbody.Body
+	current int // index in matches picked
+}
+
+// update handles typing into the search. It returns true when the search
+// consumed the keyboard this tick.
+func (s *search) update(g *Game) bool {
+	if !s.active {
+		return false
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		s.query = append(s.query, r)
+		s.current = 0
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(s.query) > 0:
+		s.query = s.query[:len(s.query)-1]
+		s.current = 0
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		s.current++
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		s.current--
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		s.close()
+		return true
+	}
+	s.find(g.bodies)
+	s.current = min(max(s.current, 0), max(len(s.matches)-1, 0))
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && len(s.matches) > 0 {
+		g.jumpTo(s.matches[s.current])
+		s.close()
+	}
+	return true
+}
+
+// open starts a new search.
+func (s *search) open() {
+	s.active, s.query, s.current = true, s.query[:0], 0
+}
+
+func (s *search) close() {
+	s.active = false
+	s.matches = s.matches[:0]
+}
+
+// find lists the bodies matching the query, case-insensitively.
+func (s *search) find(bodies []nbody.Body) {
+	q := strings.ToLower(string(s.query))
+	s.matches = s.matches[:0]
+	for _, b := range bodies {
+		if strings.Contains(strings.ToLower(bodyName(b)), q) {
+			s.matches = append(s.matches, b)
+		}
+	}
+	slices.SortStableFunc(s.matches, func(a, b nbody.Body) int {
+		ap := strings.HasPrefix(strings.ToLower(bodyName(a)), q)
+		bp := strings.HasPrefix(strings.ToLower(bodyName(b)), q)
+		if ap != bp {
+			if ap {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(bodyName(a), bodyName(b))
+	})
+}
+
+// jumpTo selects b and has the active viewport's camera follow it, zoomed
+// in on it.
+func (g *Game) jumpTo(b nbody.Body) {
+	g.selected = b.ID
+	vp := g.activeViewport()
+	vp.Camera.Follow = b.ID
+	if b.Radius > 0 {
+		vp.Camera.Zoom = searchBodySize * uiScale / b.Radius
+	}
+	vp.Camera.follow(g.bodies, &vp.Frame)
+}
+
+// draw shows the query and its matches at the top of the screen.
+func (s *search) draw(screen *ebiten.Image) {
+	if !s.active {
+		return
+	}
+	lines := []string{"find: " + string(s.query) + "_"}
+	first := max(s.current-searchResults+1, 0)
+	for i, b := range s.matches[first:min(first+searchResults, len(s.matches))] {
+		mark := "  "
+		if first+i == s.current {
+			mark = "> "
+		}
+		lines = append(lines, mark+bodyName(b))
+	}
+	if len(s.matches) == 0 {
+		lines = append(lines, "  no matches")
+	}
+	width := 40 * debugCharWidth
+	x, y := (screen.Bounds().Dx()-width)/2, 2*debugLineHeight
+	vector.DrawFilledRect(screen, float32(x-4), float32(y-4), float32(width+8), float32(len(lines)*debugLineHeight+8), theme.Panel, false)
+	vector.StrokeRect(screen, float32(x-4), float32(y-4), float32(width+8), float32(len(lines)*debugLineHeight+8), 1, theme.Border, false)
+	for _, line := range lines {
+		drawText(screen, line, x, y)
+		y += debugLineHeight
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Role is what a client is allowed to do.
+type Role int
+
+const (
+	RoleNone    Role = iota
+	RoleRead         // read and stream state
+	RoleControl      // also modify the simulation
+)
+
+// AddToken lets clients presenting token act with role. Tokens are passed
+// as "Authorization: Bearer <token>" or, for EventSource clients that can't
+// set headers, as a token query parameter.
+func (s *Server) AddToken(token string, role Role) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = append(s.tokens, tokenRole{token, role})
+}
+
+type tokenRole struct {
+	token string
+	role  Role
+}
+
+// role returns the role of the client making r and whether it presented
+// a token.
+func (s *Server) role(r *http.Request) (Role, bool) {
+	token := r.URL.Query().Get("token")
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimPrefix(h, "Bearer ")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token == "" {
+		return min(s.Anonymous, RoleRead), false
+	}
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t.token)) == 1 {
+			return t.role, true
+		}
+	}
+	return RoleNone, true
+}
+
+// require wraps h so it only runs for clients with at least role.
+func (s *Server) require(role Role, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch got, token := s.role(r); {
+		case got >= role:
+			h(w, r)
+		case got == RoleNone || !token:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="n-body"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+		default:
+			http.Error(w, "read-only access", http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"net/http"
+	"strconv"
+
+	"n-body/nbody"
+	"n-body/scenario"
+)
+
+// Command is a change to the simulation requested by a client. Commands
+// are queued by the HTTP handlers and run on the simulation loop by Apply.
+type Command func(sim *nbody.Simulation)
+
+// Apply runs the queued commands against sim and returns the simulation to
+// continue with. It is called from the simulation loop before each step.
+//
+// When a scenario was loaded since the last call, the returned simulation
+// is a fresh build of it, using sim's solver and precision and, if sim has
+// one, a new merger tree. Configure then applies the settings the program
+// was started with; without it, the build takes sim's substeps, and sim's
+// step size unless the scenario sets its own. Commands queued for the old
+// scenario are dropped.
+func (s *Server) Apply(sim *nbody.Simulation) *nbody.Simulation {
+	s.mu.Lock()
+	cmds, next := s.commands, s.next
+	s.commands, s.next = nil, nil
+	if next != nil {
+		s.current = next
+		s.epoch++
+	}
+	s.mu.Unlock()
+
+	if next != nil {
+		fresh := next.Build()
+		fresh.Solver, fresh.Precision = sim.Solver, sim.Precision
+		if sim.Mergers != nil {
+			fresh.Mergers = &nbody.MergerTree{}
+		}
+		if s.Configure != nil {
+			s.Configure(fresh)
+			return fresh
+		}
+		fresh.Substeps = sim.Substeps
+		if fresh.Dt == 0 {
+			fresh.Dt = sim.Dt
+		}
+		return fresh
+	}
+	for _, cmd := range cmds {
+		cmd(sim)
+	}
+	return sim
+}
+
+// SetScenario records the scenario the running simulation was built from,
+// without replacing it. Call it once before serving.
+func (s *Server) SetScenario(sc *scenario.Scenario) {
+	s.mu.Lock()
+	s.current = sc
+	s.mu.Unlock()
+}
+
+// Scenario returns the scenario the running simulation was built from, as
+// of the last Apply, or nil if none was set.
+func (s *Server) Scenario() *scenario.Scenario {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// LoadScenario replaces the running simulation with a fresh build of sc at
+// the next Apply. Connected clients then receive a full snapshot of it, and
+// stream clients a scenario event first.
+func (s *Server) LoadScenario(sc *scenario.Scenario) {
+	s.mu.Lock()
+	s.next = sc
+	s.mu.Unlock()
+}
+
+// scenarioInfo describes the running scenario to clients.
+type scenarioInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Epoch       uint64 `json:"epoch"`
+}
+
+func (s *Server) scenarioInfo() scenarioInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := scenarioInfo{Epoch: s.epoch}
+	if s.current != nil {
+		info.Name, info.Description = s.current.Name, s.current.Description
+	}
+	return info
+}
+
+func (s *Server) handleGetScenario(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.scenarioInfo())
+}
+
+// handleLoadScenario loads the built-in scenario named by the builtin query
+// parameter or, without it, the scenario in the JSON request body, which
+// may not use textures.
+func (s *Server) handleLoadScenario(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("builtin"); name != "" {
+		sc, ok := scenario.Builtin(name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("no built-in scenario %q", name), http.StatusNotFound)
+			return
+		}
+		s.LoadScenario(sc)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	var sc scenario.Scenario
+	if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := sc.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Texture paths name files on this machine, which a client has no
+	// business making the simulation open.
+	for i, b := range sc.Bodies {
+		if b.Texture != "" {
+			http.Error(w, fmt.Sprintf("body %d has a texture; scenarios loaded over HTTP cannot", i), http.StatusBadRequest)
+			return
+		}
+	}
+	s.LoadScenario(&sc)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) queue(cmd Command) {
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	s.mu.Unlock()
+}
+
+// handleAddBody adds the body in the request; its ID is ignored.
+func (s *Server) handleAddBody(w http.ResponseWriter, r *http.Request) {
+	var wb WireBody
+	if err := json.NewDecoder(r.Body).Decode(&wb); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b := nbody.Body{
+		Name:     wb.Name,
+		Position: nbody.Vector2D{X: wb.X, Y: wb.Y},
+		Velocity: nbody.Vector2D{X: wb.VX, Y: wb.VY},
+		Mass:     wb.Mass,
+		Radius:   wb.Radius,
+		Color:    color.White,
+	}
+	if !(b.Mass > 0) || b.Radius < 0 {
+		http.Error(w, "mass must be positive and radius non-negative", http.StatusBadRequest)
+		return
+	}
+	if wb.Color != "" {
+		var c color.RGBA
+		if _, err := fmt.Sscanf(wb.Color, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+			http.Error(w, fmt.Sprintf("invalid color %q", wb.Color), http.StatusBadRequest)
+			return
+		}
+		c.A = 255
+		b.Color = c
+	}
+	s.queue(func(sim *nbody.Simulation) { sim.AddBody(b) })
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleRemoveBody(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid body ID", http.StatusBadRequest)
+		return
+	}
+	s.queue(func(sim *nbody.Simulation) {
+		if i, ok := sim.IndexOf(id); ok {
+			sim.RemoveBody(i)
+		}
+	})
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"n-body/nbody"
+)
+
+// WireBody is a body as sent to clients.
+type WireBody struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name,omitempty"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	VX     float64 `json:"vx"`
+	VY     float64 `json:"vy"`
+	Mass   float64 `json:"mass"`
+	Radius float64 `json:"radius"`
+	Color  string  `json:"color"`
+}
+
+// NewWireBody returns b as sent to clients.
+func NewWireBody(b nbody.Body) WireBody {
+	w := WireBody{
+		ID:     b.ID,
+		Name:   b.Name,
+		X:      b.Position.X,
+		Y:      b.Position.Y,
+		VX:     b.Velocity.X,
+		VY:     b.Velocity.Y,
+		Mass:   b.Mass,
+		Radius: b.Radius,
+		Color:  "#ffffff",
+	}
+	if b.Color != nil {
+		r, g, bl, _ := b.Color.RGBA()
+		w.Color = fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, bl>>8)
+	}
+	return w
+}
+
+// Message is one state update. A full message replaces the client's state;
+// otherwise it is a delta from the state the client had at version Base:
+// Bodies holds only the bodies that are new or changed since and Removed
+// the IDs of bodies that are gone. Epoch changes, and a full message is
+// sent, whenever a new scenario is loaded.
+type Message struct {
+	Version  uint64     `json:"version"`
+	Base     uint64     `json:"base,omitempty"` // version a delta applies to
+	Epoch    uint64     `json:"epoch"`
+	Scenario string     `json:"scenario,omitempty"` // set on full messages
+	Time     float64    `json:"time"`
+	Full     bool       `json:"full"`
+	Bodies   []WireBody `json:"bodies"`
+	Removed  []int      `json:"removed,omitempty"`
+}
+
+// maxPending bounds the messages a delta encoder keeps waiting for an
+// acknowledgement.
+const maxPending = 8
+
+// deltaEncoder turns snapshots into messages for one client. It encodes
+// every message as a delta from the state the client last acknowledged
+// having, so a lost message costs nothing but its own update: a body is
+// only resent once it has moved more than threshold from the position the
+// client acknowledged, and the client's error never exceeds threshold.
+type deltaEncoder struct {
+	threshold   float64
+	base        map[int]WireBody // state acknowledged, or nil for none
+	baseVersion uint64
+	epoch       uint64    // of base
+	pending     []Message // sent and not yet acknowledged, oldest first
+	version     uint64    // of the last snapshot encoded
+}
+
+func newDeltaEncoder(threshold float64) *deltaEncoder {
+	return &deltaEncoder{threshold: threshold}
+}
+
+// ack records that the client has applied the message with version v, or
+// with v zero that it has nothing, so the next message is full. A version
+// the encoder doesn't know also gets a full message next.
+func (e *deltaEncoder) ack(v uint64) {
+	if e.base != nil && v == e.baseVersion {
+		return
+	}
+	i := slices.IndexFunc(e.pending, func(m Message) bool { return m.Version == v })
+	if v == 0 || i < 0 || !e.pending[i].Full && (e.base == nil || e.pending[i].Base != e.baseVersion) {
+		e.base, e.pending = nil, nil
+		return
+	}
+	m := e.pending[i]
+	if m.Full {
+		e.base = make(map[int]WireBody, len(m.Bodies))
+	}
+	for _, w := range m.Bodies {
+		e.base[w.ID] = w
+	}
+	for _, id := range m.Removed {
+		delete(e.base, id)
+	}
+	e.baseVersion, e.epoch = m.Version, m.Epoch
+	e.pending = e.pending[:0]
+}
+
+// encode returns the message bringing a client with the acknowledged state
+// up to date with snap.
+func (e *deltaEncoder) encode(snap *Snapshot) Message {
+	m := Message{Version: snap.Version, Epoch: snap.Epoch, Time: snap.Time, Bodies: []WireBody{}}
+	e.version = snap.Version
+	if e.base == nil || snap.Epoch != e.epoch {
+		m.Full, m.Scenario = true, snap.Scenario
+		for _, b := range snap.Bodies {
+			m.Bodies = append(m.Bodies, NewWireBody(b))
+		}
+	} else {
+		m.Base = e.baseVersion
+		seen := make(map[int]bool, len(snap.Bodies))
+		for _, b := range snap.Bodies {
+			seen[b.ID] = true
+			w := NewWireBody(b)
+			if old, ok := e.base[b.ID]; ok && !e.changed(old, w) {
+				continue
+			}
+			m.Bodies = append(m.Bodies, w)
+		}
+		for id := range e.base {
+			if !seen[id] {
+				m.Removed = append(m.Removed, id)
+			}
+		}
+		slices.Sort(m.Removed)
+	}
+	if len(e.pending) == maxPending {
+		e.pending = slices.Delete(e.pending, 0, 1)
+	}
+	e.pending = append(e.pending, m)
+	return m
+}
+
+// changed reports whether a client holding old should be sent cur.
+func (e *deltaEncoder) changed(old, cur WireBody) bool {
+	return math.Hypot(cur.X-old.X, cur.Y-old.Y) > e.threshold ||
+		cur.Mass != old.Mass || cur.Radius != old.Radius || cur.Color != old.Color
+}
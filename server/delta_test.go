@@ -0,0 +1,119 @@
+package server
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"n-body/nbody"
+)
+
+// viewer is a polling client's copy of the state, as a web viewer keeps it.
+type viewer struct {
+	version uint64
+	bodies  map[int]WireBody
+}
+
+// apply applies m as a client would, reporting whether it could: a delta
+// from a state the viewer doesn't have is dropped.
+func (v *viewer) apply(m Message) bool {
+	switch {
+	case m.Full:
+		v.bodies = make(map[int]WireBody)
+	case v.bodies == nil || m.Base != v.version:
+		return false
+	}
+	for _, w := range m.Bodies {
+		v.bodies[w.ID] = w
+	}
+	for _, id := range m.Removed {
+		delete(v.bodies, id)
+	}
+	v.version = m.Version
+	return true
+}
+
+func snapshot(version uint64, positions ...float64) *Snapshot {
+	snap := &Snapshot{Version: version}
+	for id, x := range positions {
+		snap.Bodies = append(snap.Bodies, nbody.Body{ID: id, Position: nbody.Vector2D{X: x}, Mass: 1})
+	}
+	return snap
+}
+
+func ids(ws []WireBody) []int {
+	var ids []int
+	for _, w := range ws {
+		ids = append(ids, w.ID)
+	}
+	return ids
+}
+
+func TestDeltaEncoding(t *testing.T) {
+	e := newDeltaEncoder(1)
+	m := e.encode(snapshot(1, 0, 10, 20))
+	if !m.Full || len(m.Bodies) != 3 {
+		t.Fatalf("first message: full %t with %d bodies, want a full one with 3", m.Full, len(m.Bodies))
+	}
+	e.ack(1)
+
+	m = e.encode(snapshot(2, 0.5, 12, 20))
+	if m.Full || m.Base != 1 || !slices.Equal(ids(m.Bodies), []int{1}) {
+		t.Errorf("after one body moved past the threshold: full %t, base %d, bodies %v; want a delta from 1 with body 1", m.Full, m.Base, ids(m.Bodies))
+	}
+	// Message 2 is lost: 3 is still a delta from 1, so it resends body 1.
+	m = e.encode(snapshot(3, 0.8, 12, 25))
+	if m.Base != 1 || !slices.Equal(ids(m.Bodies), []int{1, 2}) {
+		t.Errorf("after losing a message: base %d, bodies %v; want a delta from 1 with bodies 1 and 2", m.Base, ids(m.Bodies))
+	}
+	e.ack(3)
+
+	// Body 0 crept past the threshold in steps under it; body 2 is removed
+	// and body 3 added.
+	snap := snapshot(4, 1.2, 12)
+	snap.Bodies = append(snap.Bodies, nbody.Body{ID: 3, Mass: 1})
+	m = e.encode(snap)
+	if m.Base != 3 || !slices.Equal(ids(m.Bodies), []int{0, 3}) || !slices.Equal(m.Removed, []int{2}) {
+		t.Errorf("base %d, bodies %v, removed %v; want a delta from 3 with bodies 0 and 3, removing 2", m.Base, ids(m.Bodies), m.Removed)
+	}
+
+	e.ack(99)
+	if m = e.encode(snapshot(5, 0)); !m.Full {
+		t.Error("after acknowledging an unknown version, the message is not full")
+	}
+	e.ack(5)
+	if m = e.encode(&Snapshot{Version: 6, Epoch: 1}); !m.Full {
+		t.Error("after a scenario load, the message is not full")
+	}
+}
+
+// TestDeltaErrorBound checks that a client that loses messages at random
+// and acknowledges the ones it applies is never further than the threshold
+// from the true state.
+func TestDeltaErrorBound(t *testing.T) {
+	const threshold = 0.5
+	r := rand.New(rand.NewSource(1))
+	e := newDeltaEncoder(threshold)
+	positions := make([]float64, 20)
+	var v viewer
+	for version := uint64(1); version <= 2000; version++ {
+		for i := range positions {
+			positions[i] += r.NormFloat64() * 0.2
+		}
+		snap := snapshot(version, positions...)
+		e.ack(v.version)
+		m := e.encode(snap)
+		if r.Float64() < 0.3 {
+			continue // lost
+		}
+		if !v.apply(m) {
+			t.Fatalf("version %d: a delta from %d reached a client at %d", version, m.Base, v.version)
+		}
+		for id, x := range positions {
+			if d := math.Abs(v.bodies[id].X - x); d > threshold {
+				t.Fatalf("version %d: client has body %d %.3g from where it is", version, id, d)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// metrics are the counters exposed at /metrics in the Prometheus text
+// format.
+type metrics struct {
+	steps     atomic.Uint64
+	stepNanos atomic.Uint64 // total time spent stepping
+	streams   atomic.Int64  // open /stream connections
+	requests  atomic.Uint64
+}
+
+// ObserveStep records that the simulation took d to advance one step.
+func (s *Server) ObserveStep(d time.Duration) {
+	s.metrics.steps.Add(1)
+	s.metrics.stepNanos.Add(uint64(d))
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	snap := s.freshSnapshot(r.Context())
+	m := &s.metrics
+	s.mu.Lock()
+	pollers := len(s.clients)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# HELP nbody_steps_total Simulation steps taken.\n# TYPE nbody_steps_total counter\nnbody_steps_total %d\n", m.steps.Load())
+	fmt.Fprintf(w, "# HELP nbody_step_seconds_total Time spent stepping the simulation.\n# TYPE nbody_step_seconds_total counter\nnbody_step_seconds_total %g\n", time.Duration(m.stepNanos.Load()).Seconds())
+	fmt.Fprintf(w, "# HELP nbody_bodies Bodies in the latest snapshot.\n# TYPE nbody_bodies gauge\nnbody_bodies %d\n", len(snap.Bodies))
+	fmt.Fprintf(w, "# HELP nbody_sim_time_seconds Simulated time of the latest snapshot.\n# TYPE nbody_sim_time_seconds gauge\nnbody_sim_time_seconds %g\n", snap.Time)
+	fmt.Fprintf(w, "# HELP nbody_stream_clients Open /stream connections.\n# TYPE nbody_stream_clients gauge\nnbody_stream_clients %d\n", m.streams.Load())
+	fmt.Fprintf(w, "# HELP nbody_poll_clients Polling clients seen in the last minute.\n# TYPE nbody_poll_clients gauge\nnbody_poll_clients %d\n", pollers)
+	fmt.Fprintf(w, "# HELP nbody_http_requests_total HTTP API requests served.\n# TYPE nbody_http_requests_total counter\nnbody_http_requests_total %d\n", m.requests.Load())
+}
@@ -0,0 +1,339 @@
+// Package server publishes the simulation's state over HTTP for web viewers.
+//
+// GET /state returns the latest state as JSON. Clients that pass a client
+// ID get delta-encoded responses: each holds only the bodies that moved
+// more than the client's threshold since the state the client last
+// acknowledged, by passing the version of the response it applied as ack.
+// Without an acknowledgement, or one the server no longer knows, the
+// response is full. A delta's base is the version it applies to; a client
+// holding another should drop it and ask again with ack=0. GET /stream sends
+// the same messages as server-sent events at the rate the client asks for,
+// each a delta from the one before; a client that reconnects starts over
+// with a full message. Both endpoints take these query parameters:
+//
+//	client     ID identifying a polling client (/state only)
+//	ack        version of the last response applied (/state only)
+//	hz         updates per second, capped at Server.MaxRate
+//	threshold  distance in world units a body must move to be resent
+//
+// State is only copied for publication while clients are streaming or
+// polling; a request arriving after steps went unpublished waits for the
+// next one, up to a second.
+//
+// Clients presenting a control token may also change the simulation:
+//
+//	POST   /bodies       add the body in the JSON request body
+//	DELETE /bodies/{id}  remove a body
+//	POST   /scenario     replace the simulation with the scenario in the
+//	                     JSON request body, or ?builtin=name
+//
+// GET /scenario describes the running scenario. When it is replaced, stream
+// clients receive a "scenario" event followed by a full snapshot, and
+// polling clients a full snapshot with a new epoch.
+//
+// GET /metrics reports counters in the Prometheus text format and GET
+// /healthz answers "ok"; neither requires a token. See AddToken for how
+// clients authenticate.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"n-body/nbody"
+	"n-body/scenario"
+)
+
+const (
+	defaultRate = 10 // updates per second when the client doesn't say
+	// clientTimeout is how long a polling client's state is kept after its
+	// last request.
+	clientTimeout = time.Minute
+	// maxWait is how long a request waits for a fresh snapshot.
+	maxWait = time.Second
+)
+
+// Snapshot is the published state of the simulation at one step. It is
+// never modified after publication.
+type Snapshot struct {
+	Version  uint64
+	Epoch    uint64 // incremented by every scenario load
+	Scenario string
+	Time     float64
+	Bodies   []nbody.Body
+}
+
+// Server holds the latest snapshot and serves it to clients.
+type Server struct {
+	// MaxRate is the highest update rate, in Hz, a client may request.
+	// Polling faster is answered with 429 Too Many Requests.
+	MaxRate float64
+	// Anonymous is the role of clients that present no token: RoleRead
+	// for a new server, so anyone who can reach it can watch, or RoleNone
+	// to require a token for that too. Changing the simulation always
+	// takes a control token; anonymous clients never get RoleControl.
+	Anonymous Role
+	// Configure, if set, is called by Apply on every simulation built for
+	// a scenario loaded by a client, to apply the settings that overrode
+	// the first scenario's at startup, such as a step size given on the
+	// command line, and check the step as at startup.
+	Configure func(sim *nbody.Simulation)
+
+	mu        sync.Mutex
+	snap      *Snapshot
+	stale     bool          // steps were taken since snap with no one to publish them to
+	published chan struct{} // closed by the next Publish, for requests waiting on it
+	clients   map[string]*pollClient
+	tokens    []tokenRole
+	commands  []Command
+	metrics   metrics
+
+	current *scenario.Scenario // running scenario
+	next    *scenario.Scenario // scenario to load at the next Apply
+	epoch   uint64
+}
+
+// pollClient is the server-side state of a client polling /state.
+type pollClient struct {
+	enc      *deltaEncoder
+	interval time.Duration
+	last     time.Time
+}
+
+func New() *Server {
+	return &Server{
+		MaxRate:   60,
+		Anonymous: RoleRead,
+		snap:      &Snapshot{},
+		stale:     true,
+		clients:   make(map[string]*pollClient),
+	}
+}
+
+// Publish makes the bodies appendBodies appends, such as
+// Simulation.AppendBodies, at time t the latest state. It is called from
+// the simulation loop every step, but only copies the bodies while clients
+// are connected or waiting for them, and otherwise notes that the latest
+// state is stale.
+func (s *Server) Publish(t float64, appendBodies func([]nbody.Body) []nbody.Body) {
+	s.mu.Lock()
+	s.expireClients(time.Now())
+	wanted := s.metrics.streams.Load() > 0 || len(s.clients) > 0 || s.published != nil
+	if !wanted {
+		s.stale = true
+	}
+	s.mu.Unlock()
+	if !wanted {
+		return
+	}
+
+	snap := &Snapshot{Time: t, Bodies: appendBodies(nil)}
+	s.mu.Lock()
+	snap.Version = s.snap.Version + 1
+	snap.Epoch = s.epoch
+	if s.current != nil {
+		snap.Scenario = s.current.Name
+	}
+	s.snap, s.stale = snap, false
+	if s.published != nil {
+		close(s.published)
+		s.published = nil
+	}
+	s.mu.Unlock()
+}
+
+// Stale reports whether the simulation moved on since the latest snapshot,
+// with no one to publish it to. A paused simulation loop should keep
+// publishing while it is, for clients that arrive.
+func (s *Server) Stale() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stale
+}
+
+// expireClients forgets polling clients that made no request for
+// clientTimeout. s.mu must be held.
+func (s *Server) expireClients(now time.Time) {
+	for id, c := range s.clients {
+		if now.Sub(c.last) > clientTimeout {
+			delete(s.clients, id)
+		}
+	}
+}
+
+// Latest returns the most recently published snapshot.
+func (s *Server) Latest() *Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snap
+}
+
+// freshSnapshot returns the latest snapshot once it is up to date, waiting
+// up to maxWait for the next Publish if it is stale.
+func (s *Server) freshSnapshot(ctx context.Context) *Snapshot {
+	s.mu.Lock()
+	if !s.stale {
+		defer s.mu.Unlock()
+		return s.snap
+	}
+	if s.published == nil {
+		s.published = make(chan struct{})
+	}
+	published := s.published
+	s.mu.Unlock()
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	select {
+	case <-published:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return s.Latest()
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /state", s.require(RoleRead, s.handleState))
+	mux.HandleFunc("GET /stream", s.require(RoleRead, s.handleStream))
+	mux.HandleFunc("POST /bodies", s.require(RoleControl, s.handleAddBody))
+	mux.HandleFunc("DELETE /bodies/{id}", s.require(RoleControl, s.handleRemoveBody))
+	mux.HandleFunc("GET /scenario", s.require(RoleRead, s.handleGetScenario))
+	mux.HandleFunc("POST /scenario", s.require(RoleControl, s.handleLoadScenario))
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.metrics.requests.Add(1)
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// params are the options a client passes in the query string.
+type params struct {
+	client    string
+	ack       uint64
+	interval  time.Duration
+	threshold float64
+}
+
+func (s *Server) parseParams(r *http.Request) (params, error) {
+	q := r.URL.Query()
+	p := params{client: q.Get("client")}
+	if v := q.Get("ack"); v != "" {
+		var err error
+		if p.ack, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return p, fmt.Errorf("invalid ack %q", v)
+		}
+	}
+	hz := float64(defaultRate)
+	if v := q.Get("hz"); v != "" {
+		var err error
+		if hz, err = strconv.ParseFloat(v, 64); err != nil || !(hz > 0) {
+			return p, fmt.Errorf("invalid hz %q", v)
+		}
+	}
+	hz = min(hz, s.MaxRate)
+	p.interval = time.Duration(float64(time.Second) / hz)
+	if v := q.Get("threshold"); v != "" {
+		var err error
+		if p.threshold, err = strconv.ParseFloat(v, 64); err != nil || p.threshold < 0 {
+			return p, fmt.Errorf("invalid threshold %q", v)
+		}
+	}
+	return p, nil
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	p, err := s.parseParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	snap := s.freshSnapshot(r.Context())
+	if p.client == "" {
+		writeJSON(w, newDeltaEncoder(0).encode(snap))
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	s.expireClients(now)
+	c, ok := s.clients[p.client]
+	if !ok {
+		c = &pollClient{enc: newDeltaEncoder(p.threshold)}
+		s.clients[p.client] = c
+	}
+	if wait := c.last.Add(c.interval).Sub(now); ok && wait > 0 {
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		http.Error(w, "polling faster than the requested rate", http.StatusTooManyRequests)
+		return
+	}
+	c.interval, c.enc.threshold, c.last = p.interval, p.threshold, now
+	c.enc.ack(p.ack)
+	msg := c.enc.encode(snap)
+	s.mu.Unlock()
+	writeJSON(w, msg)
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	p, err := s.parseParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	s.metrics.streams.Add(1)
+	defer s.metrics.streams.Add(-1)
+
+	enc := newDeltaEncoder(p.threshold)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	snap, sent := s.freshSnapshot(r.Context()), false
+	for {
+		if !sent || snap.Version != enc.version {
+			if sent && snap.Epoch != enc.epoch {
+				info, _ := json.Marshal(scenarioInfo{Name: snap.Scenario, Epoch: snap.Epoch})
+				if _, err := fmt.Fprintf(w, "event: scenario\ndata: %s\n\n", info); err != nil {
+					return
+				}
+			}
+			msg := enc.encode(snap)
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+			// The connection delivers messages in order, so each was
+			// received if the next can be.
+			enc.ack(msg.Version)
+			sent = true
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		snap = s.Latest()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
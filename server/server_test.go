@@ -0,0 +1,322 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"n-body/nbody"
+	"n-body/scenario"
+)
+
+// serve publishes sim from a background loop, as the simulation loop does,
+// until the test ends, and returns a test server for s.
+func serve(t *testing.T, s *Server, sim *nbody.Simulation) *httptest.Server {
+	t.Helper()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.Publish(sim.Time, sim.AppendBodies)
+			}
+		}
+	}()
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		close(done)
+		<-stopped
+	})
+	return ts
+}
+
+func solarSystem(t *testing.T) (*scenario.Scenario, *nbody.Simulation) {
+	t.Helper()
+	sc, ok := scenario.Builtin("solar-system")
+	if !ok {
+		t.Fatal("no solar-system scenario")
+	}
+	return sc, sc.Build()
+}
+
+func do(t *testing.T, method, url, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+const newBody = `{"x": 1, "y": 2, "mass": 1e20, "radius": 1, "color": "#ff0000"}`
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		anonymous Role
+		method    string
+		path      string
+		token     string
+		want      int
+	}{
+		{"anonymous read", RoleRead, "GET", "/state", "", http.StatusOK},
+		{"anonymous add", RoleRead, "POST", "/bodies", "", http.StatusUnauthorized},
+		{"anonymous remove", RoleRead, "DELETE", "/bodies/1", "", http.StatusUnauthorized},
+		{"anonymous scenario load", RoleRead, "POST", "/scenario?builtin=solar-system", "", http.StatusUnauthorized},
+		{"anonymous control is capped", RoleControl, "POST", "/bodies", "", http.StatusUnauthorized},
+		{"anonymous read when closed", RoleNone, "GET", "/state", "", http.StatusUnauthorized},
+		{"read token reads when closed", RoleNone, "GET", "/state", "r", http.StatusOK},
+		{"read token in the query", RoleNone, "GET", "/state?token=r", "", http.StatusOK},
+		{"read token adds", RoleRead, "POST", "/bodies", "r", http.StatusForbidden},
+		{"control token adds", RoleRead, "POST", "/bodies", "c", http.StatusAccepted},
+		{"control token in the query", RoleRead, "POST", "/bodies?token=c", "", http.StatusAccepted},
+		{"control token removes", RoleRead, "DELETE", "/bodies/1", "c", http.StatusAccepted},
+		{"control token reads", RoleNone, "GET", "/scenario", "c", http.StatusOK},
+		{"wrong token", RoleRead, "GET", "/state", "x", http.StatusUnauthorized},
+		{"health without a token", RoleNone, "GET", "/healthz", "", http.StatusOK},
+		{"metrics without a token", RoleNone, "GET", "/metrics", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Anonymous = tt.anonymous
+			s.AddToken("r", RoleRead)
+			s.AddToken("c", RoleControl)
+			_, sim := solarSystem(t)
+			ts := serve(t, s, sim)
+			body := ""
+			if tt.method == "POST" && strings.HasPrefix(tt.path, "/bodies") {
+				body = newBody
+			}
+			resp := do(t, tt.method, ts.URL+tt.path, tt.token, body)
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+			}
+			if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestNewServerIsReadOnly(t *testing.T) {
+	s := New()
+	_, sim := solarSystem(t)
+	ts := serve(t, s, sim)
+	if resp := do(t, "POST", ts.URL+"/bodies", "", newBody); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("adding a body to a new server without a token: status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if resp := do(t, "GET", ts.URL+"/state", "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("reading a new server without a token: status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	s := New()
+	s.AddToken("c", RoleControl)
+	_, sim := solarSystem(t)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	n := sim.Len()
+	do(t, "POST", ts.URL+"/bodies", "c", newBody)
+	do(t, "DELETE", ts.URL+"/bodies/0", "c", "")
+	if sim.Len() != n {
+		t.Fatal("commands ran before Apply")
+	}
+	if got := s.Apply(sim); got != sim {
+		t.Fatal("Apply replaced the simulation without a scenario load")
+	}
+	if sim.Len() != n {
+		t.Errorf("%d bodies after adding one and removing one of %d", sim.Len(), n)
+	}
+	if _, ok := sim.IndexOf(0); ok {
+		t.Error("body 0 was not removed")
+	}
+	if b := sim.Body(sim.Len() - 1); b.Mass != 1e20 || b.Position != (nbody.Vector2D{X: 1, Y: 2}) {
+		t.Errorf("added %+v", b)
+	}
+
+	for _, body := range []string{`{"mass": 0}`, `{"mass": 1, "color": "red"}`, `{`} {
+		if resp := do(t, "POST", ts.URL+"/bodies", "c", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("adding %s: status %d, want %d", body, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestScenarioSwap(t *testing.T) {
+	s := New()
+	s.AddToken("c", RoleControl)
+	sc, sim := solarSystem(t)
+	s.SetScenario(sc)
+	sim.Substeps = 3
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	// A polling client holding the old scenario.
+	s.mu.Lock()
+	s.clients["viewer"] = &pollClient{enc: newDeltaEncoder(0), last: time.Now()}
+	s.mu.Unlock()
+	s.Publish(sim.Time, sim.AppendBodies)
+	enc := s.clients["viewer"].enc
+	enc.ack(enc.encode(s.Latest()).Version)
+
+	for _, tt := range []struct {
+		path, body string
+		want       int
+	}{
+		{"/scenario?builtin=nowhere", "", http.StatusNotFound},
+		{"/scenario", "{", http.StatusBadRequest},
+		{"/scenario", `{"name": "flat", "width": 0, "height": 10}`, http.StatusBadRequest},
+		{"/scenario", `{"name": "textured", "width": 10, "height": 10, "bodies": [{"mass": 1, "texture": "/etc/passwd"}]}`, http.StatusBadRequest},
+	} {
+		if resp := do(t, "POST", ts.URL+tt.path, "c", tt.body); resp.StatusCode != tt.want {
+			t.Errorf("loading %s %s: status %d, want %d", tt.path, tt.body, resp.StatusCode, tt.want)
+		}
+	}
+	if got := s.Apply(sim); got != sim {
+		t.Fatal("a rejected scenario replaced the simulation")
+	}
+
+	do(t, "POST", ts.URL+"/bodies", "c", newBody)
+	swap := `{"name": "pair", "width": 100, "height": 100, "bodies": [` +
+		`{"position": {"X": 10, "Y": 10}, "velocity": {"X": 0, "Y": 0}, "mass": 1e20, "radius": 1, "color": "#ffffff"},` +
+		`{"position": {"X": 20, "Y": 10}, "velocity": {"X": 0, "Y": 0}, "mass": 1e20, "radius": 1, "color": "#ffffff"}]}`
+	if resp := do(t, "POST", ts.URL+"/scenario", "c", swap); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("loading a scenario: status %d", resp.StatusCode)
+	}
+	fresh := s.Apply(sim)
+	if fresh == sim {
+		t.Fatal("Apply kept the old simulation after a scenario load")
+	}
+	if fresh.Len() != 2 {
+		t.Errorf("the new simulation has %d bodies, want the scenario's 2 and no queued ones", fresh.Len())
+	}
+	if fresh.Substeps != 3 {
+		t.Errorf("the new simulation takes %d substeps, want the old one's 3", fresh.Substeps)
+	}
+	if fresh.Dt != sim.Dt {
+		t.Errorf("the new simulation steps %g, want the old one's %g", fresh.Dt, sim.Dt)
+	}
+
+	var info scenarioInfo
+	resp, err := http.Get(ts.URL + "/scenario")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(resp.Body).Decode(&info)
+	resp.Body.Close()
+	if info.Name != "pair" || info.Epoch != 1 {
+		t.Errorf("GET /scenario = %+v, want pair at epoch 1", info)
+	}
+	if sc := s.Scenario(); sc == nil || sc.Name != "pair" {
+		t.Errorf("Scenario() = %v after the swap, want pair", sc)
+	}
+
+	s.Publish(fresh.Time, fresh.AppendBodies)
+	snap := s.Latest()
+	if snap.Epoch != 1 || snap.Scenario != "pair" || len(snap.Bodies) != 2 {
+		t.Errorf("snapshot after the swap: epoch %d, scenario %q, %d bodies", snap.Epoch, snap.Scenario, len(snap.Bodies))
+	}
+	enc.ack(enc.version)
+	if m := enc.encode(snap); !m.Full || m.Scenario != "pair" {
+		t.Errorf("a client of the old scenario got full %t, scenario %q; want a full message of pair", m.Full, m.Scenario)
+	}
+}
+
+func TestScenarioSwapConfigure(t *testing.T) {
+	s := New()
+	sc, sim := solarSystem(t)
+	s.SetScenario(sc)
+	sim.Solver, sim.Precision = nbody.NewPM(32), nbody.Float32
+	sim.Mergers = &nbody.MergerTree{}
+	configured := 0
+	s.Configure = func(sim *nbody.Simulation) {
+		configured++
+		sim.MOND.Enabled, sim.Collisions = true, true
+		sim.Dt, sim.Substeps = 0.5, 2
+	}
+
+	if s.Apply(sim); configured != 0 {
+		t.Errorf("Configure ran %d times without a scenario load", configured)
+	}
+	s.LoadScenario(sc)
+	fresh := s.Apply(sim)
+	if configured != 1 {
+		t.Errorf("Configure ran %d times for a scenario load, want once", configured)
+	}
+	if !fresh.MOND.Enabled || !fresh.Collisions || fresh.Dt != 0.5 || fresh.Substeps != 2 {
+		t.Errorf("the new simulation has MOND %t, collisions %t, step %g and %d substeps; want Configure's",
+			fresh.MOND.Enabled, fresh.Collisions, fresh.Dt, fresh.Substeps)
+	}
+	if fresh.Solver != sim.Solver || fresh.Precision != sim.Precision {
+		t.Error("the new simulation does not keep the old one's solver and precision")
+	}
+	if fresh.Mergers == nil || fresh.Mergers == sim.Mergers {
+		t.Error("the new simulation does not have a merger tree of its own")
+	}
+}
+
+func TestPublishOnlyWithClients(t *testing.T) {
+	s := New()
+	_, sim := solarSystem(t)
+	calls := 0
+	appendBodies := func(b []nbody.Body) []nbody.Body {
+		calls++
+		return sim.AppendBodies(b)
+	}
+	s.Publish(sim.Time, appendBodies)
+	if calls != 0 || !s.Stale() {
+		t.Fatalf("published %d times with no clients, stale %t", calls, s.Stale())
+	}
+
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	go func() {
+		// Publish once the request below is waiting.
+		for {
+			s.mu.Lock()
+			waiting := s.published != nil
+			s.mu.Unlock()
+			if waiting {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		s.Publish(sim.Time, appendBodies)
+	}()
+	var m Message
+	resp, err := http.Get(ts.URL + "/state?client=a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(resp.Body).Decode(&m)
+	resp.Body.Close()
+	if !m.Full || len(m.Bodies) != sim.Len() || m.Version != 1 {
+		t.Errorf("first poll: full %t, %d bodies, version %d; want the full state at version 1", m.Full, len(m.Bodies), m.Version)
+	}
+	if s.Stale() {
+		t.Error("stale after publishing")
+	}
+	s.Publish(sim.Time, appendBodies)
+	if calls != 2 {
+		t.Errorf("published %d times with a polling client, want 2", calls)
+	}
+}
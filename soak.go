@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+)
+
+// soak runs the game headlessly, without rendering, for d (forever when d is
+// zero) or until interrupted, logging memory and goroutine statistics every
+// interval. Heap figures are taken right after a forced collection, so a
+// live heap that keeps growing across reports points at a leak rather than
+// at garbage waiting to be collected.
+func (g *Game) soak(d, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	r := newSoakReporter()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.report(g)
+			return nil
+		case <-ticker.C:
+			r.report(g)
+		default:
+		}
+		if err := g.step(); err != nil {
+			return err
+		}
+		if g.fault != nil {
+			return g.fault
+		}
+		r.steps++
+	}
+}
+
+// soakReporter logs the change in runtime statistics between reports.
+type soakReporter struct {
+	start     time.Time
+	last      time.Time
+	steps     int
+	lastSteps int
+	first     runtime.MemStats // at the first report, as a baseline for growth
+	prev      runtime.MemStats
+}
+
+func newSoakReporter() *soakReporter {
+	r := &soakReporter{start: time.Now()}
+	r.last = r.start
+	runtime.GC()
+	runtime.ReadMemStats(&r.prev)
+	r.first = r.prev
+	return r
+}
+
+func (r *soakReporter) report(g *Game) {
+	now := time.Now()
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	secs := now.Sub(r.last).Seconds()
+	log.Printf("soak %s: %d steps (%.0f/s), %d bodies, heap %.1f MiB (%+.1f since start) in %d objects, alloc %.1f MiB/s in %.0f objects/s, %d goroutines, %d GCs",
+		now.Sub(r.start).Round(time.Second),
+		r.steps, float64(r.steps-r.lastSteps)/secs,
+		g.sim.Len(),
+		mib(m.HeapAlloc), mib(int64(m.HeapAlloc)-int64(r.first.HeapAlloc)), m.HeapObjects,
+		mib(float64(m.TotalAlloc-r.prev.TotalAlloc)/secs), float64(m.Mallocs-r.prev.Mallocs)/secs,
+		runtime.NumGoroutine(), m.NumGC)
+
+	r.last, r.lastSteps, r.prev = now, r.steps, m
+}
+
+// mib converts a byte count to mebibytes.
+func mib[T uint64 | int64 | float64](n T) float64 {
+	return float64(n) / (1 << 20)
+}
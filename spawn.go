@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+const (
+	// spawnDragTime is how long, in simulated seconds, a spawned body takes
+	// to cover the drag that launched it: the arrow drawn while dragging
+	// shows where it would get to in that time if nothing pulled on it.
+	spawnDragTime = 5
+	// Mass and radius of spawned bodies by default, those of a small moon.
+	spawnMass   = 1e22
+	spawnRadius = 2
+)
+
+// spawnPreset is a kind of body to spawn, with a plausible mass and a
+// radius on the scale of the built-in scenarios.
+type spawnPreset struct {
+	name     string
+	mass     float64 // kg
+	radius   float64
+	color    color.RGBA
+	luminous bool
+}
+
+// spawnPresets are the kinds of body offered while spawning, the default
+// first.
+var spawnPresets = []spawnPreset{
+	{name: "Moon", mass: spawnMass, radius: spawnRadius, color: color.RGBA{200, 200, 200, 255}},
+	{name: "Rocky planet", mass: 5.972e24, radius: 5, color: color.RGBA{70, 130, 220, 255}},                    // the Earth
+	{name: "Gas giant", mass: 1.898e27, radius: 15, color: color.RGBA{230, 160, 80, 255}},                      // Jupiter
+	{name: "Red dwarf", mass: 0.2 * 1.989e30, radius: 12, color: color.RGBA{255, 90, 60, 255}, luminous: true}, // a fifth of the Sun
+	{name: "Asteroid", mass: 1e19, radius: 1, color: color.RGBA{150, 140, 130, 255}},
+}
+
+// spawner adds bodies with the mouse while on: pressing places a body,
+// dragging sets its velocity, with its predicted path shown, and releasing
+// adds it to the simulation. Positions and velocities are taken in the
+// frame of the viewport dragged in. What kind of body is spawned is picked
+// from a palette at the top of the screen first.
+type spawner struct {
+	on       bool
+	dragging bool
+	vp       int            // index of the viewport dragged in
+	from, to nbody.Vector2D // frame positions pressed at and dragged to
+	count    int            // bodies spawned, for naming them
+	preset   int            // index in spawnPresets of the kind spawned
+	palette  image.Rectangle
+}
+
+// updateSpawner places, aims and adds a body while spawning, and reports
+// whether it took the mouse.
+func (g *Game) updateSpawner() bool {
+	sp := &g.spawner
+	if !sp.on {
+		sp.dragging = false
+		return false
+	}
+	if !sp.dragging {
+		w := newWidgets(nil, g.paletteOrigin(), new(string))
+		g.spawnPalette(w)
+		sp.palette = w.bounds
+		if w.took || w.pressed && w.cursor.In(w.bounds.Inset(-2)) {
+			return true
+		}
+	}
+	cursor := image.Pt(ebiten.CursorPosition())
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if g.powerZoomOn {
+			log.Print("turn power zoom off (P) to spawn bodies")
+			return true
+		}
+		sp.vp = g.viewportAt(cursor)
+		vp := &g.viewports[sp.vp]
+		sp.from = vp.toFrame(cursor, vp.Camera.Center)
+		sp.dragging = true
+	}
+	if !sp.dragging {
+		return false
+	}
+	if sp.vp >= len(g.viewports) {
+		sp.dragging = false
+		return true
+	}
+	vp := &g.viewports[sp.vp]
+	sp.to = vp.toFrame(cursor, vp.Camera.Center)
+	b := g.spawnBody()
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		sp.dragging = false
+		sp.count++
+		b.Name = fmt.Sprintf("%s %d", spawnPresets[sp.preset].name, sp.count)
+		g.sim.AddBody(b)
+		i := g.sim.Len() - 1
+		g.edits.add(edit{op: "spawn", time: g.sim.Time, index: i, after: g.sim.Body(i)})
+		g.resync()
+		return true
+	}
+	g.predictor.startSpawn(g.sim, b)
+	return true
+}
+
+// spawnBody returns the body the drag under way would spawn.
+func (g *Game) spawnBody() nbody.Body {
+	sp := &g.spawner
+	f := &g.viewports[sp.vp].Frame
+	v := nbody.Vector2D{X: (sp.to.X - sp.from.X) / spawnDragTime, Y: (sp.to.Y - sp.from.Y) / spawnDragTime}
+	p := spawnPresets[sp.preset]
+	return nbody.Body{
+		Position: f.invert(sp.from),
+		Velocity: f.worldVelocity(sp.from, v),
+		Mass:     p.mass,
+		Radius:   p.radius,
+		Color:    p.color,
+		Luminous: p.luminous,
+	}
+}
+
+// spawnPalette describes the palette of kinds of body to w, picking the
+// one clicked.
+func (g *Game) spawnPalette(w *widgets) {
+	w.label("spawn")
+	for i, p := range spawnPresets {
+		if w.button(p.name, g.spawner.preset == i) {
+			g.spawner.preset = i
+		}
+	}
+}
+
+// paletteOrigin is where the palette starts: centered below the status
+// line.
+func (g *Game) paletteOrigin() image.Point {
+	return image.Pt((g.width-g.spawner.palette.Dx())/2, debugLineHeight+8)
+}
+
+// drawSpawnPalette draws the palette while spawning.
+func (g *Game) drawSpawnPalette(screen *ebiten.Image) {
+	if !g.spawner.on {
+		return
+	}
+	r := g.spawner.palette.Inset(-2)
+	vector.DrawFilledRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), theme.Panel, false)
+	g.spawnPalette(newWidgets(screen, g.paletteOrigin(), new(string)))
+}
+
+// drawSpawn draws the body being spawned in vp and the arrow setting its
+// velocity. center is the camera center.
+func (g *Game) drawSpawn(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D) {
+	sp := &g.spawner
+	if !sp.dragging || vp != &g.viewports[sp.vp] {
+		return
+	}
+	from, to := vp.toScreen(sp.from, center), vp.toScreen(sp.to, center)
+	r := max(spawnPresets[sp.preset].radius*vp.Camera.Zoom, minPointRadius*uiScale)
+	vector.DrawFilledCircle(dst, float32(from.X), float32(from.Y), float32(r), theme.Selection, true)
+	vector.StrokeLine(dst, float32(from.X), float32(from.Y), float32(to.X), float32(to.Y), float32(uiScale), theme.Selection, true)
+}
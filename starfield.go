@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// starTile is the side in pixels of the square of stars each layer
+	// repeats, at a zoom of 1.
+	starTile = 256
+	// starsPerTile is the number of stars in each layer's tile.
+	starsPerTile = 40
+)
+
+// starLayers are the depths of the starfield's layers: how far each moves,
+// as a fraction of the distance the world moves, when the camera pans, and
+// the power of the camera's zoom it scales by. Nearer layers are brighter,
+// as a fraction of the way from the theme's background to its stars.
+var starLayers = [...]struct {
+	parallax   float64
+	brightness float64
+}{
+	{0.02, 0.35},
+	{0.05, 0.55},
+	{0.1, 0.8},
+}
+
+type star struct {
+	x, y       float64 // position in the tile
+	brightness float64
+}
+
+// starfield is a seeded background of stars in a few layers that drift
+// slightly as the camera moves, so panning and zooming show even where
+// there are no bodies.
+type starfield struct {
+	layers [len(starLayers)][]star
+}
+
+func newStarfield(seed int64) *starfield {
+	rng := rand.New(rand.NewSource(seed))
+	f := &starfield{}
+	for l, layer := range starLayers {
+		stars := make([]star, starsPerTile)
+		for i := range stars {
+			stars[i] = star{
+				x:          rng.Float64() * starTile,
+				y:          rng.Float64() * starTile,
+				brightness: layer.brightness * (0.4 + 0.6*rng.Float64()),
+			}
+		}
+		f.layers[l] = stars
+	}
+	return f
+}
+
+// draw queues the stars over vp on points.
+func (f *starfield) draw(dst *ebiten.Image, points *pointBatch, vp *Viewport) {
+	b := vp.Bounds
+	mid := b.Min.Add(b.Max).Div(2)
+	for l, layer := range starLayers {
+		scale := uiScale * math.Max(0.5, math.Min(math.Pow(vp.Camera.Zoom/uiScale, layer.parallax), 4))
+		tile := starTile * scale
+		// Screen position of a tile corner, then the tiles covering vp.
+		ox := float64(mid.X) - vp.Camera.Center.X*vp.Camera.Zoom*layer.parallax
+		oy := float64(mid.Y) - vp.Camera.Center.Y*vp.Camera.Zoom*layer.parallax
+		x0 := ox + tile*math.Floor((float64(b.Min.X)-ox)/tile)
+		y0 := oy + tile*math.Floor((float64(b.Min.Y)-oy)/tile)
+		for ty := y0; ty < float64(b.Max.Y); ty += tile {
+			for tx := x0; tx < float64(b.Max.X); tx += tile {
+				for _, s := range f.layers[l] {
+					points.add(dst, tx+s.x*scale, ty+s.y*scale, minPointRadius, mixColor(theme.Background, theme.Stars, s.brightness))
+				}
+			}
+		}
+	}
+	points.flush(dst)
+}
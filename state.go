@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync/atomic"
+
+	"n-body/nbody"
+)
+
+// renderState is what Draw needs of the simulation: the bodies after the
+// last two steps, to interpolate between, and the world they live in.
+type renderState struct {
+	prev, cur     []nbody.Body
+	accel         []nbody.Vector2D // of cur's bodies in the last step, or empty if unknown
+	time          float64          // simulated time of cur
+	dt            float64          // simulated time between prev and cur
+	width, height float64
+}
+
+func (st *renderState) center() nbody.Vector2D {
+	return nbody.Vector2D{X: st.width / 2, Y: st.height / 2}
+}
+
+// advance makes bodies, the simulation's after a step, the current state,
+// and the current one the previous.
+func (st *renderState) advance(sim *nbody.Simulation, bodies []nbody.Body) {
+	st.prev, st.cur = st.cur, st.prev
+	st.cur = append(st.cur[:0], bodies...)
+	st.accel = sim.AppendAccelerations(st.accel[:0])
+	st.time, st.dt = sim.Time, sim.StepSize()
+	st.width, st.height = sim.Width, sim.Height
+}
+
+// reset makes bodies both the previous and the current state, so that Draw
+// shows them as they are instead of interpolating from an earlier state.
+func (st *renderState) reset(sim *nbody.Simulation, bodies []nbody.Body) {
+	st.advance(sim, bodies)
+	st.prev = append(st.prev[:0], bodies...)
+}
+
+// copyFrom makes st a copy of src, reusing st's slices.
+func (st *renderState) copyFrom(src *renderState) {
+	prev, cur, accel := st.prev, st.cur, st.accel
+	*st = *src
+	st.prev = append(prev[:0], src.prev...)
+	st.cur = append(cur[:0], src.cur...)
+	st.accel = append(accel[:0], src.accel...)
+}
+
+// stateBuffer hands render states from the physics side to the renderer
+// without either waiting for the other, so the two can run on different
+// goroutines. It is double buffering with a spare slot: the writer fills
+// its back slot and swaps it with the spare, and the reader swaps its
+// front slot with the spare whenever that holds something newer. The slot
+// in use by each side is never touched by the other.
+type stateBuffer struct {
+	slots [3]renderState
+	back  int           // written by the physics side
+	front int           // read by the renderer
+	spare atomic.Uint32 // index of the spare slot, plus stateFresh
+}
+
+// stateFresh marks a spare slot published since the renderer last took one.
+const stateFresh = 1 << 2
+
+func newStateBuffer() *stateBuffer {
+	b := &stateBuffer{back: 0, front: 1}
+	b.spare.Store(2)
+	return b
+}
+
+// publish makes a copy of st the newest state.
+func (b *stateBuffer) publish(st *renderState) {
+	b.slots[b.back].copyFrom(st)
+	old := b.spare.Swap(uint32(b.back) | stateFresh)
+	b.back = int(old &^ stateFresh)
+}
+
+// read returns the newest published state. It stays valid, and unchanged,
+// until the next call.
+func (b *stateBuffer) read() *renderState {
+	if b.spare.Load()&stateFresh != 0 {
+		old := b.spare.Swap(uint32(b.front))
+		b.front = int(old &^ stateFresh)
+	}
+	return &b.slots[b.front]
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"n-body/nbody"
+)
+
+func TestStateBuffer(t *testing.T) {
+	b := newStateBuffer()
+	var st renderState
+	if got := b.read(); len(got.cur) != 0 {
+		t.Fatalf("read before publishing = %d bodies, want none", len(got.cur))
+	}
+	st.cur = []nbody.Body{{ID: 1}}
+	st.time = 1
+	b.publish(&st)
+	st.cur[0].ID = 2 // the published copy must not change
+	st.time = 2
+	got := b.read()
+	if got.time != 1 || got.cur[0].ID != 1 {
+		t.Errorf("read = time %g, body %d; want the copy published at time 1", got.time, got.cur[0].ID)
+	}
+	if again := b.read(); again != got {
+		t.Error("read with nothing newer published returned another slot")
+	}
+	b.publish(&st)
+	b.publish(&st)
+	if got := b.read(); got.time != 2 || got.cur[0].ID != 2 {
+		t.Errorf("read = time %g, body %d; want the newest, at time 2", got.time, got.cur[0].ID)
+	}
+}
+
+// TestStateBufferConcurrent publishes and reads from two goroutines, for
+// the race detector, checking that reads never see a state half written.
+func TestStateBufferConcurrent(t *testing.T) {
+	b := newStateBuffer()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var st renderState
+		for i := range 10000 {
+			st.time = float64(i)
+			st.cur = st.cur[:0]
+			for range 8 {
+				st.cur = append(st.cur, nbody.Body{ID: i})
+			}
+			b.publish(&st)
+		}
+	}()
+	last := -1.0
+	for range 10000 {
+		st := b.read()
+		if st.time < last {
+			t.Fatalf("read time %g after %g", st.time, last)
+		}
+		last = st.time
+		for _, body := range st.cur {
+			if float64(body.ID) != st.time {
+				t.Fatalf("state at time %g holds a body from step %d", st.time, body.ID)
+			}
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// textures loads body textures on first use, on a goroutine of their own
+// so a large file doesn't stall a frame, and keeps them for the rest of the
+// run. Bodies are drawn as plain discs until their texture has loaded, or
+// for good if it fails to, which is logged once.
+type textures struct {
+	images map[string]*ebiten.Image // nil for textures loading or failed
+
+	mu      sync.Mutex
+	decoded map[string]image.Image // loaded since the last get, or nil if failed
+}
+
+// get returns the texture at path, or nil if it is still loading or cannot
+// be loaded.
+func (t *textures) get(path string) *ebiten.Image {
+	if img, ok := t.images[path]; ok {
+		if img == nil {
+			t.collect()
+			img = t.images[path]
+		}
+		return img
+	}
+	if t.images == nil {
+		t.images = make(map[string]*ebiten.Image)
+	}
+	t.images[path] = nil
+	go func() {
+		src, err := decodeTexture(path)
+		if err != nil {
+			log.Printf("loading texture: %v", err)
+		}
+		t.mu.Lock()
+		if t.decoded == nil {
+			t.decoded = make(map[string]image.Image)
+		}
+		t.decoded[path] = src
+		t.mu.Unlock()
+	}()
+	return nil
+}
+
+// collect turns the textures decoded since the last call into images.
+func (t *textures) collect() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for path, src := range t.decoded {
+		if src != nil {
+			t.images[path] = ebiten.NewImageFromImage(src)
+		}
+		delete(t.decoded, path)
+	}
+}
+
+func decodeTexture(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return src, nil
+}
+
+// drawSprite draws img centered at pos, scaled so its longer side spans the
+// body's diameter.
+func drawSprite(dst, img *ebiten.Image, pos nbody.Vector2D, radius float64) {
+	size := img.Bounds().Size()
+	scale := 2 * radius / float64(max(size.X, size.Y))
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Translate(-float64(size.X)/2, -float64(size.Y)/2)
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(pos.X, pos.Y)
+	dst.DrawImage(img, op)
+}
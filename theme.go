@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"n-body/scenario"
+)
+
+// Theme is the palette everything other than the bodies is drawn in.
+// Colors are written as in scenarios, "#rrggbb" or "#rrggbbaa", with the
+// color channels premultiplied by alpha as in image/color.
+type Theme struct {
+	Background scenario.Color `json:"background"`
+	Text       scenario.Color `json:"text"`
+	Panel      scenario.Color `json:"panel"`  // behind the HUD, minimap and worksheet
+	Border     scenario.Color `json:"border"` // around viewports and the minimap
+	Selection  scenario.Color `json:"selection"`
+	Stars      scenario.Color `json:"stars"` // the brightest stars of the starfield
+	Grid       scenario.Color `json:"grid"`
+	ScaleBar   scenario.Color `json:"scaleBar"`
+	ScaleRings scenario.Color `json:"scaleRings"` // power zoom's distance rings
+	Trail      scenario.Color `json:"trail"`      // fully transparent to use each body's color
+	Field      scenario.Color `json:"field"`      // gravity arrows
+	Contour    scenario.Color `json:"contour"`
+	Kepler     scenario.Color `json:"kepler"`
+	Hill       scenario.Color `json:"hill"`
+	RocheRigid scenario.Color `json:"rocheRigid"`
+	RocheFluid scenario.Color `json:"rocheFluid"`
+	Lagrange   scenario.Color `json:"lagrange"`
+	Barycenter scenario.Color `json:"barycenter"`
+	Subsystem  scenario.Color `json:"subsystem"` // barycenters of subsystems
+	Periapsis  scenario.Color `json:"periapsis"`
+	Apoapsis   scenario.Color `json:"apoapsis"`
+	Eclipse    scenario.Color `json:"eclipse"` // lines through eclipses and transits
+}
+
+// themes are the built-in themes, by name.
+var themes = map[string]Theme{
+	"dark": {
+		Background: scenario.Color{R: 0, G: 0, B: 0, A: 255},
+		Text:       scenario.Color{R: 255, G: 255, B: 255, A: 255},
+		Panel:      scenario.Color{R: 0, G: 0, B: 0, A: 200},
+		Border:     scenario.Color{R: 64, G: 64, B: 64, A: 255},
+		Selection:  scenario.Color{R: 255, G: 255, B: 255, A: 255},
+		Stars:      scenario.Color{R: 255, G: 255, B: 255, A: 255},
+		Grid:       scenario.Color{R: 40, G: 40, B: 40, A: 255},
+		ScaleBar:   scenario.Color{R: 200, G: 200, B: 200, A: 255},
+		ScaleRings: scenario.Color{R: 80, G: 80, B: 80, A: 255},
+		Field:      scenario.Color{R: 60, G: 110, B: 160, A: 200},
+		Contour:    scenario.Color{R: 120, G: 120, B: 120, A: 120},
+		Kepler:     scenario.Color{R: 110, G: 110, B: 150, A: 150},
+		Hill:       scenario.Color{R: 50, G: 90, B: 110, A: 160},
+		RocheRigid: scenario.Color{R: 200, G: 130, B: 40, A: 255},
+		RocheFluid: scenario.Color{R: 200, G: 60, B: 40, A: 255},
+		Lagrange:   scenario.Color{R: 120, G: 200, B: 120, A: 255},
+		Barycenter: scenario.Color{R: 255, G: 255, B: 255, A: 255},
+		Subsystem:  scenario.Color{R: 150, G: 150, B: 150, A: 255},
+		Periapsis:  scenario.Color{R: 230, G: 120, B: 80, A: 255},
+		Apoapsis:   scenario.Color{R: 80, G: 150, B: 230, A: 255},
+		Eclipse:    scenario.Color{R: 255, G: 220, B: 120, A: 200},
+	},
+	"light": {
+		Background: scenario.Color{R: 245, G: 245, B: 240, A: 255},
+		Text:       scenario.Color{R: 20, G: 20, B: 20, A: 255},
+		Panel:      scenario.Color{R: 230, G: 230, B: 225, A: 220},
+		Border:     scenario.Color{R: 170, G: 170, B: 170, A: 255},
+		Selection:  scenario.Color{R: 0, G: 0, B: 0, A: 255},
+		Stars:      scenario.Color{R: 150, G: 150, B: 160, A: 255},
+		Grid:       scenario.Color{R: 220, G: 220, B: 215, A: 255},
+		ScaleBar:   scenario.Color{R: 60, G: 60, B: 60, A: 255},
+		ScaleRings: scenario.Color{R: 180, G: 180, B: 180, A: 255},
+		Field:      scenario.Color{R: 30, G: 80, B: 140, A: 200},
+		Contour:    scenario.Color{R: 90, G: 90, B: 90, A: 120},
+		Kepler:     scenario.Color{R: 60, G: 60, B: 120, A: 160},
+		Hill:       scenario.Color{R: 30, G: 90, B: 110, A: 180},
+		RocheRigid: scenario.Color{R: 190, G: 110, B: 0, A: 255},
+		RocheFluid: scenario.Color{R: 190, G: 30, B: 20, A: 255},
+		Lagrange:   scenario.Color{R: 20, G: 130, B: 40, A: 255},
+		Barycenter: scenario.Color{R: 0, G: 0, B: 0, A: 255},
+		Subsystem:  scenario.Color{R: 110, G: 110, B: 110, A: 255},
+		Periapsis:  scenario.Color{R: 200, G: 80, B: 30, A: 255},
+		Apoapsis:   scenario.Color{R: 30, G: 90, B: 200, A: 255},
+		Eclipse:    scenario.Color{R: 170, G: 120, B: 0, A: 220},
+	},
+	"high-contrast": {
+		Background: scenario.Color{R: 0, G: 0, B: 0, A: 255},
+		Text:       scenario.Color{R: 255, G: 255, B: 0, A: 255},
+		Panel:      scenario.Color{R: 0, G: 0, B: 0, A: 255},
+		Border:     scenario.Color{R: 255, G: 255, B: 255, A: 255},
+		Selection:  scenario.Color{R: 0, G: 255, B: 255, A: 255},
+		Stars:      scenario.Color{R: 110, G: 110, B: 110, A: 255},
+		Grid:       scenario.Color{R: 90, G: 90, B: 90, A: 255},
+		ScaleBar:   scenario.Color{R: 255, G: 255, B: 255, A: 255},
+		ScaleRings: scenario.Color{R: 200, G: 200, B: 200, A: 255},
+		Field:      scenario.Color{R: 0, G: 200, B: 255, A: 255},
+		Contour:    scenario.Color{R: 200, G: 200, B: 200, A: 255},
+		Kepler:     scenario.Color{R: 255, G: 255, B: 255, A: 255},
+		Hill:       scenario.Color{R: 0, G: 255, B: 255, A: 255},
+		RocheRigid: scenario.Color{R: 255, G: 170, B: 0, A: 255},
+		RocheFluid: scenario.Color{R: 255, G: 0, B: 0, A: 255},
+		Lagrange:   scenario.Color{R: 0, G: 255, B: 0, A: 255},
+		Barycenter: scenario.Color{R: 255, G: 255, B: 255, A: 255},
+		Subsystem:  scenario.Color{R: 255, G: 0, B: 255, A: 255},
+		Periapsis:  scenario.Color{R: 255, G: 120, B: 0, A: 255},
+		Apoapsis:   scenario.Color{R: 0, G: 160, B: 255, A: 255},
+		Eclipse:    scenario.Color{R: 255, G: 255, B: 0, A: 255},
+	},
+}
+
+// theme is the theme in use, set once at startup.
+var theme = themes["dark"]
+
+// loadTheme returns the built-in theme called name, or else reads one from
+// the JSON file at that path. Colors the file leaves out are the dark
+// theme's.
+func loadTheme(name string) (Theme, error) {
+	if t, ok := themes[name]; ok {
+		return t, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return Theme{}, fmt.Errorf("theme %q is neither built in (dark, light, high-contrast) nor readable: %w", name, err)
+	}
+	t := themes["dark"]
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Theme{}, fmt.Errorf("parsing theme %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// mixColor returns the color t of the way from a to b.
+func mixColor(a, b scenario.Color, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 { return uint8(float64(x) + t*(float64(y)-float64(x))) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// textScratch is where drawText renders text before tinting it.
+var textScratch *ebiten.Image
+
+// drawText draws str at (x, y) like ebitenutil.DebugPrintAt, in the
+// theme's text color and scaled by textScale. The debug font only comes in
+// white and at one size, so otherwise it is drawn through a scratch image.
+func drawText(dst *ebiten.Image, str string, x, y int) {
+	if color.RGBA(theme.Text) == (color.RGBA{255, 255, 255, 255}) && textScale == 1 {
+		ebitenutil.DebugPrintAt(dst, str, x, y)
+		return
+	}
+	lines := strings.Split(str, "\n")
+	w := 0
+	for _, line := range lines {
+		w = max(w, len(line)*debugFontWidth+1)
+	}
+	h := len(lines) * debugFontHeight
+	if textScratch == nil || textScratch.Bounds().Dx() < w || textScratch.Bounds().Dy() < h {
+		if textScratch != nil {
+			w, h = max(w, textScratch.Bounds().Dx()), max(h, textScratch.Bounds().Dy())
+		}
+		textScratch = ebiten.NewImage(w, h)
+	}
+	src := textScratch.SubImage(image.Rect(0, 0, w, h)).(*ebiten.Image)
+	src.Clear()
+	ebitenutil.DebugPrintAt(src, str, 0, 0)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(textScale), float64(textScale))
+	op.GeoM.Translate(float64(x), float64(y))
+	op.ColorScale.ScaleWithColor(theme.Text)
+	dst.DrawImage(src, op)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+// tooltipLines describes bodies[i] for a tooltip: its name, mass and speed
+// and, if ref is another body, how far it is from that.
+func tooltipLines(bodies []nbody.Body, i, ref int) []string {
+	b := bodies[i]
+	lines := []string{
+		bodyName(b),
+		fmt.Sprintf("mass  %.3g kg", b.Mass),
+		fmt.Sprintf("speed %s/s", formatDistance(math.Hypot(b.Velocity.X, b.Velocity.Y))),
+	}
+	j := slices.IndexFunc(bodies, func(b nbody.Body) bool { return b.ID == ref })
+	if j >= 0 && j != i {
+		d := math.Hypot(bodies[j].Position.X-b.Position.X, bodies[j].Position.Y-b.Position.Y)
+		lines = append(lines, fmt.Sprintf("%s from %s", formatDistance(d), bodyName(bodies[j])))
+	}
+	return lines
+}
+
+// drawTooltip describes the body under the cursor beside it, unless the
+// mouse is busy with something else.
+func (g *Game) drawTooltip(screen *ebiten.Image) {
+	if g.dragging >= 0 || g.spawner.dragging || g.moving.id != nbody.NoBody || g.helpOn {
+		return
+	}
+	cursor := image.Pt(ebiten.CursorPosition())
+	if !cursor.In(screen.Bounds()) || cursor.In(g.controls.bounds) {
+		return
+	}
+	id := g.bodyAt(&g.viewports[g.viewportAt(cursor)], cursor)
+	i := slices.IndexFunc(g.drawBodies, func(b nbody.Body) bool { return b.ID == id })
+	if i < 0 {
+		return
+	}
+	lines := tooltipLines(g.drawBodies, i, g.selected)
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line)*debugCharWidth)
+	}
+	height := len(lines) * debugLineHeight
+	// Below and to the right of the cursor, unless that runs off screen.
+	x, y := cursor.X+int(12*uiScale), cursor.Y+int(12*uiScale)
+	if x+width+4 > screen.Bounds().Dx() {
+		x = cursor.X - width - int(12*uiScale)
+	}
+	if y+height+4 > screen.Bounds().Dy() {
+		y = cursor.Y - height - int(12*uiScale)
+	}
+	vector.DrawFilledRect(screen, float32(x-2), float32(y-2), float32(width+4), float32(height+4), theme.Panel, false)
+	vector.StrokeRect(screen, float32(x-2), float32(y-2), float32(width+4), float32(height+4), 1, theme.Border, false)
+	for _, line := range lines {
+		drawText(screen, line, x, y)
+		y += debugLineHeight
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"n-body/nbody"
+)
+
+// tapSlop is how far, in pixels before scaling, a finger may move and still
+// tap rather than drag.
+const tapSlop = 10
+
+// touchGesture follows the fingers on a touch screen: a tap selects the
+// body under it, dragging pans the viewport touched first, with one finger
+// or two, and pinching zooms it about the point between the fingers.
+type touchGesture struct {
+	ids   []ebiten.TouchID // fingers down
+	on    bool             // a gesture is under way
+	vp    int              // index of the viewport it is in
+	start image.Point      // where its first finger went down
+	moved bool             // whether it went further than a tap
+}
+
+// updateTouches handles the fingers on the screen, if any, and reports
+// whether they took the place of the mouse this tick.
+func (g *Game) updateTouches() bool {
+	t := &g.touch
+	t.ids = ebiten.AppendTouchIDs(t.ids[:0])
+	if len(t.ids) == 0 {
+		if !t.on {
+			return false
+		}
+		t.on = false
+		released := inpututil.AppendJustReleasedTouchIDs(nil)
+		if !t.moved && len(released) == 1 && t.vp < len(g.viewports) {
+			p := image.Pt(inpututil.TouchPositionInPreviousTick(released[0]))
+			g.selected = g.bodyAt(&g.viewports[t.vp], p)
+		}
+		return true
+	}
+	if !t.on {
+		p := image.Pt(ebiten.TouchPosition(t.ids[0]))
+		t.on, t.vp, t.start, t.moved = true, g.viewportAt(p), p, false
+	}
+	if len(t.ids) > 1 {
+		t.moved = true
+	}
+	if t.vp >= len(g.viewports) {
+		return true
+	}
+
+	// Fingers that just went down have nowhere they moved from, so only
+	// those down since the last tick move the camera.
+	var now, before []nbody.Vector2D
+	for _, id := range t.ids {
+		if inpututil.TouchPressDuration(id) <= 1 {
+			continue
+		}
+		x, y := ebiten.TouchPosition(id)
+		px, py := inpututil.TouchPositionInPreviousTick(id)
+		now = append(now, nbody.Vector2D{X: float64(x), Y: float64(y)})
+		before = append(before, nbody.Vector2D{X: float64(px), Y: float64(py)})
+	}
+	if len(now) == 0 {
+		return true
+	}
+	if !t.moved {
+		if math.Hypot(now[0].X-float64(t.start.X), now[0].Y-float64(t.start.Y)) <= tapSlop*uiScale {
+			return true
+		}
+		t.moved = true
+	}
+
+	vp := &g.viewports[t.vp]
+	mid, prevMid := touchMidpoint(now), touchMidpoint(before)
+	if len(now) > 1 {
+		spread := math.Hypot(now[1].X-now[0].X, now[1].Y-now[0].Y)
+		prevSpread := math.Hypot(before[1].X-before[0].X, before[1].Y-before[0].Y)
+		if spread > 0 && prevSpread > 0 && spread != prevSpread {
+			vp.zoomAt(spread/prevSpread, image.Pt(int(mid.X), int(mid.Y)))
+		}
+	}
+	if mid != prevMid {
+		c := &vp.Camera
+		c.Follow = nbody.NoBody
+		c.Center.X -= (mid.X - prevMid.X) / c.Zoom
+		c.Center.Y -= (mid.Y - prevMid.Y) / c.Zoom
+	}
+	return true
+}
+
+// touchMidpoint returns the point halfway between the first two of ps, or
+// the only one.
+func touchMidpoint(ps []nbody.Vector2D) nbody.Vector2D {
+	if len(ps) == 1 {
+		return ps[0]
+	}
+	return nbody.Vector2D{X: (ps[0].X + ps[1].X) / 2, Y: (ps[0].Y + ps[1].Y) / 2}
+}
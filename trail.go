@@ -0,0 +1,126 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/nbody"
+)
+
+// trail is the recent path of one body, in the frame of the viewport
+// showing it, as a ring of positions.
+type trail struct {
+	points []nbody.Vector2D
+	next   int // slot of the next position
+	count  int // positions ever recorded
+	marks  []apsisMark
+	seen   bool
+}
+
+// trails are the paths of every body in one viewport.
+type trails struct {
+	byID map[int]*trail
+}
+
+// record adds the current position of every body in frame, keeping the
+// last length of each, and forgets bodies that no longer exist.
+func (t *trails) record(bodies []nbody.Body, frame *Frame, length int) {
+	if t.byID == nil {
+		t.byID = make(map[int]*trail)
+	}
+	for _, b := range bodies {
+		tr := t.byID[b.ID]
+		if tr == nil {
+			tr = &trail{}
+			t.byID[b.ID] = tr
+		}
+		p := frame.apply(b.Position)
+		if len(tr.points) < length {
+			tr.points = append(tr.points, p)
+		} else {
+			tr.points[tr.next] = p
+			tr.next = (tr.next + 1) % len(tr.points)
+		}
+		tr.count++
+		// Marks go when the part of the trail they are on does.
+		n := 0
+		for n < len(tr.marks) && tr.count-tr.marks[n].at > len(tr.points) {
+			n++
+		}
+		tr.marks = append(tr.marks[:0], tr.marks[n:]...)
+		tr.seen = true
+	}
+	for id, tr := range t.byID {
+		if !tr.seen {
+			delete(t.byID, id)
+		}
+		tr.seen = false
+	}
+}
+
+// reset forgets every trail, as when the frame changes under them.
+func (t *trails) reset() {
+	clear(t.byID)
+}
+
+// shortTrailFraction is the part of each trail drawn while the quality
+// governor shortens them.
+const shortTrailFraction = 4
+
+// drawTrails draws the trails in vp and their apsis marks, only the newest
+// part of each while the quality governor shortens them. center is the
+// camera center.
+func (g *Game) drawTrails(dst *ebiten.Image, vp *Viewport, center nbody.Vector2D, st *renderState) {
+	toScreen := func(p nbody.Vector2D) nbody.Vector2D { return vp.toScreen(g.view(p), center) }
+	length := g.trailLength
+	if g.quality.level >= qualityShortTrails {
+		length = max(length/shortTrailFraction, 2)
+	}
+	vp.trails.draw(dst, &g.lines, g.drawBodies, toScreen, st.width, st.height, length)
+	g.lines.flush(dst)
+	if m, at := vp.trails.drawMarks(dst, toScreen, image.Pt(ebiten.CursorPosition())); m != nil {
+		drawApsisLabel(dst, m, at)
+	}
+}
+
+// draw queues the newest length positions of every trail on lines, fading
+// from transparent at the oldest to the body's color at the newest.
+// Segments longer than half the world are where a body wrapped around its
+// edge and are skipped.
+func (t *trails) draw(dst *ebiten.Image, lines *lineBatch, bodies []nbody.Body, toScreen func(nbody.Vector2D) nbody.Vector2D, width, height float64, length int) {
+	for _, b := range bodies {
+		tr := t.byID[b.ID]
+		if tr == nil {
+			continue
+		}
+		var c color.Color = b.Color
+		if theme.Trail.A > 0 {
+			c = theme.Trail
+		}
+		n := len(tr.points)
+		skip := max(n-length, 0) // older positions left out
+		prev := tr.points[(tr.next+skip)%n]
+		for k := skip + 1; k < n; k++ {
+			p := tr.points[(tr.next+k)%n]
+			if math.Abs(p.X-prev.X) <= width/2 && math.Abs(p.Y-prev.Y) <= height/2 {
+				fade := float64(k-skip) / float64(n-skip)
+				lines.add(dst, toScreen(prev), toScreen(p), fadeColor(c, fade))
+			}
+			prev = p
+		}
+	}
+}
+
+// fadeColor scales c's opacity by alpha.
+func fadeColor(c color.Color, alpha float64) color.Color {
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{
+		R: uint16(float64(r) * alpha),
+		G: uint16(float64(g) * alpha),
+		B: uint16(float64(b) * alpha),
+		A: uint16(float64(a) * alpha),
+	}
+}
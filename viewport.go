@@ -0,0 +1,252 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+const (
+	zoomStep    = 1.25
+	maxViewport = 4
+	// closeUpZoom is the zoom, relative to the first viewport's, of a
+	// viewport opened while a body is selected.
+	closeUpZoom = 8
+)
+
+// Camera decides which part of the world a viewport shows.
+type Camera struct {
+	Center nbody.Vector2D // frame position shown at the middle of the viewport
+	Zoom   float64        // screen pixels per world unit
+	Follow int            // ID of the body kept centered, or nbody.NoBody
+}
+
+func defaultCamera(center nbody.Vector2D) Camera {
+	return Camera{
+		Center: center,
+		Zoom:   uiScale,
+		Follow: nbody.NoBody,
+	}
+}
+
+// Viewport is a rectangle of the screen rendering the simulation through its
+// own camera and reference frame.
+type Viewport struct {
+	Bounds image.Rectangle
+	Camera Camera
+	Frame  Frame
+
+	trails trails   // recent body paths in Frame
+	heat   heatmap  // potential sampled over the viewport
+	visits exposure // where bodies have been in Frame, for the long exposure
+	inset  bool     // a picture-in-picture view, too small for a minimap
+}
+
+// toScreen maps a point to screen coordinates, where center is the camera
+// center expressed in the same space as p.
+func (vp *Viewport) toScreen(p, center nbody.Vector2D) nbody.Vector2D {
+	mid := vp.Bounds.Min.Add(vp.Bounds.Max).Div(2)
+	return nbody.Vector2D{
+		X: float64(mid.X) + (p.X-center.X)*vp.Camera.Zoom,
+		Y: float64(mid.Y) + (p.Y-center.Y)*vp.Camera.Zoom,
+	}
+}
+
+// toFrame maps the screen point p back to a frame position, given the
+// camera center.
+func (vp *Viewport) toFrame(p image.Point, center nbody.Vector2D) nbody.Vector2D {
+	mid := vp.Bounds.Min.Add(vp.Bounds.Max).Div(2)
+	return nbody.Vector2D{
+		X: center.X + float64(p.X-mid.X)/vp.Camera.Zoom,
+		Y: center.Y + float64(p.Y-mid.Y)/vp.Camera.Zoom,
+	}
+}
+
+// follow recenters the camera on its followed body, if that body still
+// exists.
+func (c *Camera) follow(bodies []nbody.Body, frame *Frame) {
+	if c.Follow == nbody.NoBody {
+		return
+	}
+	if b, ok := nbody.FindBody(bodies, c.Follow); ok {
+		c.Center = frame.apply(b.Position)
+		return
+	}
+	c.Follow = nbody.NoBody
+}
+
+// cycleFrame switches the viewport to the next reference frame. Body-centered
+// and co-rotating frames use the followed body, or the heaviest body when the
+// camera is free.
+func (vp *Viewport) cycleFrame(bodies []nbody.Body) {
+	vp.Frame.Kind = (vp.Frame.Kind + 1) % frameKinds
+	vp.Frame.Body = vp.Camera.Follow
+	if vp.Frame.Body == nbody.NoBody {
+		vp.Frame.Body = nbody.HeaviestBody(bodies)
+	}
+	vp.trails.reset()
+	vp.visits.reset()
+}
+
+// splitScreen divides a width×height screen into n viewport rectangles: one
+// full screen, two side by side, three as a left half plus two stacked right
+// quarters, or four as a 2×2 grid.
+func splitScreen(n, width, height int) []image.Rectangle {
+	w, h := width/2, height/2
+	switch n {
+	case 2:
+		return []image.Rectangle{
+			image.Rect(0, 0, w, height),
+			image.Rect(w, 0, width, height),
+		}
+	case 3:
+		return []image.Rectangle{
+			image.Rect(0, 0, w, height),
+			image.Rect(w, 0, width, h),
+			image.Rect(w, h, width, height),
+		}
+	case 4:
+		return []image.Rectangle{
+			image.Rect(0, 0, w, h),
+			image.Rect(w, 0, width, h),
+			image.Rect(0, h, w, height),
+			image.Rect(w, h, width, height),
+		}
+	default:
+		return []image.Rectangle{image.Rect(0, 0, width, height)}
+	}
+}
+
+// setViewportCount re-splits the screen into n viewports, keeping the cameras
+// of the viewports that remain. New viewports close in on the selected body,
+// if there is one, so that splitting the screen sets a close-up beside the
+// wider view; otherwise they show the whole world.
+func (g *Game) setViewportCount(n int) {
+	rects := splitScreen(n, g.width, g.height)
+	viewports := make([]Viewport, len(rects))
+	for i, r := range rects {
+		viewports[i] = Viewport{Bounds: r, Camera: defaultCamera(g.sim.Center())}
+		switch {
+		case i < len(g.viewports):
+			viewports[i].Camera = g.viewports[i].Camera
+			viewports[i].Frame = g.viewports[i].Frame
+			viewports[i].trails = g.viewports[i].trails
+		case g.selected != nbody.NoBody && i > 0:
+			viewports[i].Camera.Zoom = viewports[0].Camera.Zoom * closeUpZoom
+			viewports[i].lockOn(g.selected)
+		}
+	}
+	g.viewports = viewports
+}
+
+// activeViewport returns the viewport under the mouse cursor.
+func (g *Game) activeViewport() *Viewport {
+	return &g.viewports[g.viewportAt(image.Pt(ebiten.CursorPosition()))]
+}
+
+// viewportAt returns the index of the viewport containing p, or of the
+// first one if none does.
+func (g *Game) viewportAt(p image.Point) int {
+	for i := range g.viewports {
+		if p.In(g.viewports[i].Bounds) {
+			return i
+		}
+	}
+	return 0
+}
+
+// mouseCamera pans a viewport while the left button drags across it,
+// letting go of any followed body, and zooms the viewport under the cursor
+// with the wheel, keeping the point under the cursor in place.
+func (g *Game) mouseCamera() {
+	cursor := image.Pt(ebiten.CursorPosition())
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		g.dragging, g.dragFrom, g.dragged = g.viewportAt(cursor), cursor, false
+	}
+	if g.dragging >= 0 {
+		if g.dragging >= len(g.viewports) {
+			g.dragging = -1
+		} else if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+			if !g.dragged {
+				// A click rather than a drag: select the body under it,
+				// or with shift, pair it with the selected body.
+				id := g.bodyAt(&g.viewports[g.dragging], cursor)
+				if ebiten.IsKeyPressed(ebiten.KeyShift) {
+					g.partner = id
+				} else {
+					g.selected = id
+				}
+			}
+			g.dragging = -1
+		} else if d := cursor.Sub(g.dragFrom); d != (image.Point{}) {
+			c := &g.viewports[g.dragging].Camera
+			c.Follow = nbody.NoBody
+			c.Center.X -= float64(d.X) / c.Zoom
+			c.Center.Y -= float64(d.Y) / c.Zoom
+			g.dragFrom, g.dragged = cursor, true
+		}
+	}
+	if _, wy := ebiten.Wheel(); wy != 0 {
+		g.viewports[g.viewportAt(cursor)].zoomAt(math.Pow(zoomStep, wy), cursor)
+	}
+}
+
+// pickRadius is how far, in pixels, a click may land outside a body and
+// still select it.
+const pickRadius = 6
+
+// bodyAt returns the ID of the body drawn nearest to the screen point p in
+// vp, if p is on it or within pickRadius of it, and nbody.NoBody otherwise.
+func (g *Game) bodyAt(vp *Viewport, p image.Point) int {
+	center := g.view(vp.Camera.Center)
+	best, bestDist := nbody.NoBody, math.Inf(1)
+	for _, b := range g.bodies {
+		if !g.groups.visible(b) {
+			continue
+		}
+		s := vp.toScreen(g.view(vp.Frame.apply(b.Position)), center)
+		d := math.Hypot(s.X-float64(p.X), s.Y-float64(p.Y)) - g.sizes.radius(b, vp.Camera.Zoom)
+		if d <= pickRadius*uiScale && d < bestDist {
+			best, bestDist = b.ID, d
+		}
+	}
+	return best
+}
+
+// lockOn makes the camera follow body id in a frame centered on it, so
+// everything moving around the body is shown relative to it. Locking onto
+// the body already locked onto releases it.
+func (vp *Viewport) lockOn(id int) {
+	if vp.Camera.Follow == id && vp.Frame.Kind == FrameBodyCentered && vp.Frame.Body == id {
+		vp.Camera.Follow = nbody.NoBody
+		vp.Frame.Kind = FrameInertial
+	} else {
+		vp.Camera.Follow = id
+		vp.Frame.Kind, vp.Frame.Body = FrameBodyCentered, id
+	}
+	vp.trails.reset()
+	vp.visits.reset()
+}
+
+// zoomAt multiplies the camera's zoom by factor about the screen point p.
+// A camera following a body stays centered on it.
+func (vp *Viewport) zoomAt(factor float64, p image.Point) {
+	c := &vp.Camera
+	if c.Follow == nbody.NoBody {
+		mid := vp.Bounds.Min.Add(vp.Bounds.Max).Div(2)
+		dx, dy := float64(p.X-mid.X), float64(p.Y-mid.Y)
+		c.Center.X += dx/c.Zoom - dx/(c.Zoom*factor)
+		c.Center.Y += dy/c.Zoom - dy/(c.Zoom*factor)
+	}
+	c.Zoom *= factor
+}
+
+func drawViewportBorder(screen *ebiten.Image, vp *Viewport) {
+	r := vp.Bounds
+	vector.StrokeRect(screen, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), 1, theme.Border, false)
+}
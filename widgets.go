@@ -0,0 +1,152 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// sliderWidth is the length of a slider's track, before scaling.
+const sliderWidth = 120
+
+// slider is a horizontal control setting a value between min and max, on a
+// logarithmic scale if log is set, in which case both must be positive.
+type slider struct {
+	min, max float64
+	log      bool
+}
+
+// fraction returns how far along the slider v is, from 0 to 1.
+func (s slider) fraction(v float64) float64 {
+	var f float64
+	if s.log {
+		f = math.Log(v/s.min) / math.Log(s.max/s.min)
+	} else {
+		f = (v - s.min) / (s.max - s.min)
+	}
+	if math.IsNaN(f) {
+		return 0
+	}
+	return min(max(f, 0), 1)
+}
+
+// at returns the value a fraction f along the slider.
+func (s slider) at(f float64) float64 {
+	f = min(max(f, 0), 1)
+	if s.log {
+		return s.min * math.Pow(s.max/s.min, f)
+	}
+	return s.min + f*(s.max-s.min)
+}
+
+// sliderFraction returns how far along a slider track in r screen x is.
+func sliderFraction(r image.Rectangle, x int) float64 {
+	return float64(x-r.Min.X) / float64(max(r.Dx(), 1))
+}
+
+// drawSlider draws a slider track in r with its knob a fraction f along,
+// highlighted while active.
+func drawSlider(dst *ebiten.Image, r image.Rectangle, f float64, active bool) {
+	mid := float32(r.Min.Y+r.Max.Y) / 2
+	vector.StrokeLine(dst, float32(r.Min.X), mid, float32(r.Max.X), mid, 2*float32(uiScale), theme.Border, false)
+	x := float32(r.Min.X) + float32(f)*float32(r.Dx())
+	clr := theme.Text
+	if active {
+		clr = theme.Selection
+	}
+	vector.DrawFilledRect(dst, x-2*float32(uiScale), float32(r.Min.Y), 4*float32(uiScale), float32(r.Dy()), clr, false)
+}
+
+// widgets lays out buttons and sliders in rows, left to right, and either
+// handles the mouse for them or draws them. A panel is described once, by
+// a function run from Update with no image to draw on and again from Draw
+// with one, so what is drawn is always what is clicked.
+type widgets struct {
+	dst      *ebiten.Image // nil while handling the mouse
+	left     int           // where rows start
+	x, y     int           // where the next widget goes
+	bounds   image.Rectangle
+	cursor   image.Point
+	pressed  bool    // the left button was pressed this tick
+	dragging *string // ID of the slider being dragged, or ""
+	took     bool    // a widget took the mouse
+}
+
+// widgetHeight is the height of a row of widgets.
+func widgetHeight() int { return debugLineHeight + 4 }
+
+// newWidgets starts laying out widgets at p, handling the mouse if dst is
+// nil. dragging holds which slider is being dragged between ticks.
+func newWidgets(dst *ebiten.Image, p image.Point, dragging *string) *widgets {
+	w := &widgets{dst: dst, left: p.X, x: p.X, y: p.Y, bounds: image.Rectangle{Min: p, Max: p}, dragging: dragging}
+	if dst == nil {
+		w.cursor = image.Pt(ebiten.CursorPosition())
+		w.pressed = inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+		if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+			*dragging = ""
+		}
+	}
+	return w
+}
+
+// place reserves a widget width pixels wide in the current row.
+func (w *widgets) place(width int) image.Rectangle {
+	r := image.Rect(w.x, w.y, w.x+width, w.y+widgetHeight())
+	w.x += width + 4
+	w.bounds = w.bounds.Union(r)
+	return r
+}
+
+// newline starts a new row.
+func (w *widgets) newline() {
+	w.x, w.y = w.left, w.y+widgetHeight()+4
+}
+
+// label shows str.
+func (w *widgets) label(str string) {
+	r := w.place(len(str) * debugCharWidth)
+	if w.dst != nil {
+		drawText(w.dst, str, r.Min.X, r.Min.Y+2)
+	}
+}
+
+// button shows a button reading str, filled in if on, and reports whether
+// it was clicked.
+func (w *widgets) button(str string, on bool) bool {
+	r := w.place(len(str)*debugCharWidth + 8)
+	if w.dst == nil {
+		if w.pressed && w.cursor.In(r) {
+			w.took = true
+			return true
+		}
+		return false
+	}
+	if on {
+		vector.DrawFilledRect(w.dst, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), theme.Border, false)
+	}
+	vector.StrokeRect(w.dst, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), 1, theme.Border, false)
+	drawText(w.dst, str, r.Min.X+4, r.Min.Y+2)
+	return false
+}
+
+// slider shows s set to v and returns the value it was dragged to and
+// whether it was. id tells it from the panel's other sliders.
+func (w *widgets) slider(id string, s slider, v float64) (float64, bool) {
+	r := w.place(int(sliderWidth * uiScale))
+	r.Min.Y, r.Max.Y = r.Min.Y+2, r.Max.Y-2
+	if w.dst != nil {
+		drawSlider(w.dst, r, s.fraction(v), *w.dragging == id)
+		return v, false
+	}
+	if w.pressed && w.cursor.In(r.Inset(-int(2*uiScale))) {
+		*w.dragging = id
+	}
+	if *w.dragging != id {
+		return v, false
+	}
+	w.took = true
+	return s.at(sliderFraction(r, w.cursor.X)), true
+}
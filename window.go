@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"n-body/render"
+)
+
+// windowRenderer is the default Renderer: it draws frames, with the game's
+// viewports, overlays and HUD, onto the Ebitengine screen being drawn.
+type windowRenderer struct {
+	g      *Game
+	screen *ebiten.Image // set by Draw for the duration of Render
+	state  *renderState  // the frame was interpolated from, likewise
+}
+
+func (r *windowRenderer) Render(f *render.Frame) error {
+	r.g.drawFrame(r.screen, r.state, f)
+	return nil
+}
+
+func (r *windowRenderer) Close() error { return nil }
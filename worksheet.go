@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"n-body/nbody"
+)
+
+const defaultTolerance = 0.05
+
+// Task is one worksheet exercise. Its expected answer is either fixed
+// (Answer) or measured from the live simulation when the student submits
+// (Measure on Body).
+type Task struct {
+	Prompt    string   `json:"prompt"`
+	Answer    *float64 `json:"answer,omitempty"`
+	Measure   string   `json:"measure,omitempty"` // period, distance, speed, eccentricity or mass
+	Body      int      `json:"body,omitempty"`
+	Tolerance float64  `json:"tolerance,omitempty"` // relative; defaults to 5%
+	Unit      string   `json:"unit,omitempty"`
+}
+
+// Worksheet is a list of tasks a student works through while the
+// simulation runs.
+type Worksheet struct {
+	Title string `json:"title"`
+	Tasks []Task `json:"tasks"`
+}
+
+// loadWorksheet reads a worksheet from a JSON file.
+func loadWorksheet(path string) (*Worksheet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ws Worksheet
+	if err := json.Unmarshal(data, &ws); err != nil {
+		return nil, fmt.Errorf("parsing worksheet %s: %w", path, err)
+	}
+	for i, t := range ws.Tasks {
+		if t.Answer == nil && t.Measure == "" {
+			return nil, fmt.Errorf("worksheet %s: task %d has neither an answer nor a measurement", path, i+1)
+		}
+	}
+	return &ws, nil
+}
+
+// expected returns the task's correct answer for the current state.
+func (t Task) expected(bodies []nbody.Body) (float64, error) {
+	if t.Answer != nil {
+		return *t.Answer, nil
+	}
+	i := -1
+	for k, b := range bodies {
+		if b.ID == t.Body {
+			i = k
+		}
+	}
+	if i < 0 {
+		return 0, fmt.Errorf("body %d no longer exists", t.Body)
+	}
+	b := bodies[i]
+	switch t.Measure {
+	case "mass":
+		return b.Mass, nil
+	case "speed":
+		return math.Hypot(b.Velocity.X, b.Velocity.Y), nil
+	}
+	p, ok := nbody.DominantBody(bodies, i)
+	if !ok {
+		return 0, fmt.Errorf("body %d has no primary", t.Body)
+	}
+	el := nbody.OsculatingElements(b, bodies[p])
+	switch t.Measure {
+	case "period":
+		if !el.Bound {
+			return 0, fmt.Errorf("body %d is not in a bound orbit", t.Body)
+		}
+		return el.Period, nil
+	case "distance":
+		return el.Distance, nil
+	case "eccentricity":
+		return el.Eccentricity, nil
+	}
+	return 0, fmt.Errorf("unknown measurement %q", t.Measure)
+}
+
+// check reports whether answer is within the task's tolerance.
+func (t Task) check(answer float64, bodies []nbody.Body) (bool, error) {
+	want, err := t.expected(bodies)
+	if err != nil {
+		return false, err
+	}
+	tol := t.Tolerance
+	if tol <= 0 {
+		tol = defaultTolerance
+	}
+	return math.Abs(answer-want) <= tol*math.Abs(want), nil
+}
+
+type taskStatus int
+
+const (
+	taskOpen taskStatus = iota
+	taskCorrect
+	taskWrong
+)
+
+// worksheetMode is the interactive state of a worksheet: which task is
+// current, what the student is typing and how each task went.
+type worksheetMode struct {
+	sheet   *Worksheet
+	active  bool // the overlay is shown and captures typed characters
+	current int
+	input   []rune
+	status  []taskStatus
+	message string
+}
+
+func newWorksheetMode(ws *Worksheet) *worksheetMode {
+	return &worksheetMode{sheet: ws, status: make([]taskStatus, len(ws.Tasks))}
+}
+
+// update handles worksheet input. It returns true when the worksheet
+// consumed the keyboard this tick.
+func (w *worksheetMode) update(bodies []nbody.Body) bool {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF2) {
+		w.active = !w.active
+		return true
+	}
+	if !w.active || len(w.sheet.Tasks) == 0 {
+		return false
+	}
+
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if strings.ContainsRune("0123456789.-+eE", r) {
+			w.input = append(w.input, r)
+		}
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(w.input) > 0:
+		w.input = w.input[:len(w.input)-1]
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		w.submit(bodies)
+	case inpututil.IsKeyJustPressed(ebiten.KeyPageDown):
+		w.current = (w.current + 1) % len(w.sheet.Tasks)
+		w.input, w.message = w.input[:0], ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyPageUp):
+		w.current = (w.current + len(w.sheet.Tasks) - 1) % len(w.sheet.Tasks)
+		w.input, w.message = w.input[:0], ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		w.active = false
+	}
+	return true
+}
+
+func (w *worksheetMode) submit(bodies []nbody.Body) {
+	answer, err := strconv.ParseFloat(string(w.input), 64)
+	if err != nil {
+		w.message = "not a number"
+		return
+	}
+	ok, err := w.sheet.Tasks[w.current].check(answer, bodies)
+	switch {
+	case err != nil:
+		w.message = "cannot check: " + err.Error()
+	case ok:
+		w.status[w.current] = taskCorrect
+		w.message = "correct!"
+		w.input = w.input[:0]
+	default:
+		w.status[w.current] = taskWrong
+		w.message = "not quite, try again"
+	}
+}
+
+func (w *worksheetMode) draw(screen *ebiten.Image) {
+	if !w.active {
+		return
+	}
+	const lineHeight = 16
+	width, height := screen.Bounds().Dx(), screen.Bounds().Dy()
+	x, y := 8, height-lineHeight*(len(w.sheet.Tasks)+5)
+	vector.DrawFilledRect(screen, float32(x-4), float32(y-4), float32(width-2*(x-4)), float32(height-y), theme.Panel, false)
+
+	drawText(screen, "WORKSHEET: "+w.sheet.Title, x, y)
+	y += lineHeight
+	for i, t := range w.sheet.Tasks {
+		mark := [...]string{"[ ]", "[x]", "[!]"}[w.status[i]]
+		cursor := " "
+		if i == w.current {
+			cursor = ">"
+		}
+		drawText(screen, fmt.Sprintf("%s %s %d. %s", cursor, mark, i+1, t.Prompt), x, y)
+		y += lineHeight
+	}
+	y += lineHeight
+	drawText(screen, fmt.Sprintf("Answer: %s_ %s", string(w.input), w.sheet.Tasks[w.current].Unit), x, y)
+	y += lineHeight
+	drawText(screen, w.message+"  (Enter: check, PgUp/PgDn: task, Esc: hide)", x, y)
+}